@@ -77,12 +77,36 @@ func (server *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
+	// 删除后无法再查询账单名称和成员, 提前查询用于通知
+	var account db.Account
+	account, err = server.db.GetAccount(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var rules []db.AccountAccessRule
+	rules, err = server.db.GetAccountAccessRulesByAccountId(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	err = server.db.DeleteAccount(ctx, req.ID)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	memberIds := make([]int64, len(rules))
+	for i, rule := range rules {
+		memberIds[i] = rule.UserID
+	}
+	server.notify(ctx, notificationAccountDeleted, memberIds, notificationData{
+		AccountId:   account.ID,
+		AccountName: account.Name,
+	})
+
 	ctx.JSON(http.StatusOK, nil)
 }
 
@@ -148,7 +172,9 @@ func (server *Server) getAccountsCount(ctx *gin.Context) {
 type getAccountsRequest struct {
 	Role     util.AccountRole `json:"role" binding:"required,min=1,max=2"`
 	PageSize int64            `json:"page_size" binding:"required,min=5,max=20"`
-	PageId   int64            `json:"page_id" binding:"required,min=1"`
+	Cursor   string           `json:"cursor"`
+	// Deprecated: 使用cursor分页
+	PageId int64 `json:"page_id" binding:"omitempty,min=1"`
 }
 
 func (server *Server) getAccounts(ctx *gin.Context) {
@@ -162,20 +188,47 @@ func (server *Server) getAccounts(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	var accounts []db.Account
-	offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
-	accounts, err = server.db.GetAccountsByUserIdAndRole(
+	if req.PageId != 0 {
+		setDeprecatedPagination(ctx)
+		offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
+		accounts, err = server.db.GetAccountsByUserIdAndRole(
+			ctx,
+			authPayload.UserId,
+			req.Role,
+			offset,
+			limit,
+		)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, accounts)
+		return
+	}
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorAccounts)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	accounts, err = server.db.GetAccountsByUserIdAndRoleAfterCursor(
 		ctx,
 		authPayload.UserId,
 		req.Role,
-		offset,
-		limit,
+		cursor.ID,
+		req.PageSize+1,
 	)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	ctx.JSON(http.StatusOK, newPageResponse(accounts, req.PageSize, cursorAccounts, func(account db.Account) pageCursor {
+		return pageCursor{ID: account.ID}
+	}))
 }
 
 type updateAccountNameRequest struct {
@@ -203,7 +256,17 @@ func (server *Server) updateAccountName(ctx *gin.Context) {
 		return
 	}
 
-	err = server.db.UpdateAccountName(ctx, req.ID, req.Name)
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		err := store.UpdateAccountName(ctx, req.ID, req.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		return []accountEvent{newAccountEvent(ctx, eventAccountRenamed, req.ID, accountEventData{
+			ID:   req.ID,
+			Name: req.Name,
+		})}, nil
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -241,12 +304,25 @@ func (server *Server) addAccountManager(ctx *gin.Context) {
 		return
 	}
 
-	_, err = server.db.CreateAccountAccessRule(ctx, req.UserId, req.AccountId, util.AccountRoleManager)
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		_, err := store.CreateAccountAccessRule(ctx, req.UserId, req.AccountId, util.AccountRoleManager)
+		if err != nil {
+			return nil, err
+		}
+
+		return []accountEvent{newAccountEvent(ctx, eventMemberAdded, req.AccountId, memberEventData{
+			UserId: req.UserId,
+			Role:   util.AccountRoleManager,
+		})}, nil
+	})
 	if err != nil {
 		// TODO: 判定是UserId,AccountId不存在产生的错误还是内部错误
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
+	server.notifyMembers(ctx, notificationMemberAdded, req.AccountId, []int64{req.UserId}, util.AccountRoleManager)
+
 	ctx.JSON(http.StatusOK, nil)
 }
 
@@ -283,16 +359,31 @@ func (server *Server) deleteAccountManager(ctx *gin.Context) {
 		} else {
 			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		}
+		return
 	}
 
 	if rule.Role != util.AccountRoleManager {
-		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccessDenied))
+		return
 	}
 
-	err = server.db.DeleteAccountAccessRule(ctx, rule.ID)
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		err := store.DeleteAccountAccessRule(ctx, rule.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return []accountEvent{newAccountEvent(ctx, eventMemberRemoved, req.AccountId, memberEventData{
+			UserId: req.UserId,
+			Role:   rule.Role,
+		})}, nil
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
+	server.notifyMembers(ctx, notificationMemberRemoved, req.AccountId, []int64{req.UserId}, rule.Role)
+
 	ctx.JSON(http.StatusOK, nil)
 }
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/timelyrain/star-account/blob"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+const (
+	maxAttachmentSize       = 10 << 20
+	maxAttachmentsPerRecord = 10
+	// 每个账单中所有附件的总大小
+	attachmentQuota       = 500 << 20
+	maxAttachmentFilename = 255
+	// multipart表单中除文件内容以外的部分
+	maxAttachmentFormOverhead = 64 << 10
+)
+
+/**
+ * 允许上传的附件类型, 按文件内容识别, 不信任客户端声明的类型
+ */
+var attachmentContentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var (
+	errAttachmentTooLarge        = fmt.Errorf("attachment is larger than %d bytes", maxAttachmentSize)
+	errUnsupportedAttachmentType = errors.New("unsupported attachment type")
+	errEmptyAttachment           = errors.New("empty attachment")
+	errAttachmentRecordNotFound  = errors.New("record not found")
+	errAttachmentContentNotFound = errors.New("attachment content not found")
+)
+
+type uploadAttachmentRequest struct {
+	RecordId int64                 `form:"record_id" binding:"required,min=1"`
+	File     *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+/**
+ * 1. 读取multipart表单中的record_id和file字段
+ * 2. 校验记录的访问权限, 文件大小和按内容识别的文件类型
+ * 3. 先把内容写入BlobStore, 再在事务中检查配额并写入附件信息
+ *    写入附件信息失败时登记已上传的内容, 由后台任务删除
+ */
+func (server *Server) uploadAttachment(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxAttachmentSize+maxAttachmentFormOverhead)
+
+	var req uploadAttachmentRequest
+	err := ctx.ShouldBindWith(&req, binding.FormMultipart)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(errAttachmentTooLarge))
+		} else {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		}
+		return
+	}
+
+	if req.File.Size > maxAttachmentSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(errAttachmentTooLarge))
+		return
+	}
+
+	var data []byte
+	data, err = readFormFile(req.File)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if len(data) == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errEmptyAttachment))
+		return
+	}
+
+	contentType := sniffAttachmentType(data)
+	if contentType == "" {
+		ctx.JSON(http.StatusUnsupportedMediaType, errorResponse(errUnsupportedAttachmentType))
+		return
+	}
+
+	var record db.Record
+	record, err = server.db.GetRecord(ctx, req.RecordId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, record.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	// 提前检查配额, 避免上传注定写入失败的内容, 最终以事务中的检查为准
+	var used int64
+	used, err = server.db.GetAttachmentsSizeSumByAccountId(ctx, record.AccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if used+int64(len(data)) > attachmentQuota {
+		ctx.JSON(http.StatusConflict, errorResponse(db.ErrAttachmentQuotaExceeded))
+		return
+	}
+
+	var key string
+	key, err = newAttachmentKey(record, contentType)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.blobStore.Put(ctx, key, data, contentType)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var attachment db.Attachment
+	attachment, err = server.db.CreateAttachment(
+		ctx,
+		record.ID,
+		key,
+		attachmentFilename(req.File.Filename, contentType),
+		contentType,
+		int64(len(data)),
+		authPayload.UserId,
+		maxAttachmentsPerRecord,
+		attachmentQuota,
+	)
+	if err != nil {
+		if deleteErr := server.db.CreateBlobDeletion(ctx, key); deleteErr != nil {
+			_ = ctx.Error(deleteErr)
+		}
+
+		switch err {
+		case sql.ErrNoRows:
+			ctx.JSON(http.StatusNotFound, errorResponse(errAttachmentRecordNotFound))
+		case db.ErrAttachmentQuotaExceeded, db.ErrTooManyAttachments:
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachment)
+}
+
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, maxAttachmentSize))
+}
+
+/**
+ * 按文件头识别类型, 不在允许列表中时返回空字符串
+ */
+func sniffAttachmentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	contentType, _, _ = strings.Cut(contentType, ";")
+	if _, ok := attachmentContentTypes[contentType]; !ok {
+		return ""
+	}
+
+	return contentType
+}
+
+/**
+ * 内容的key按账单分组, 文件名使用随机值, 不包含用户输入
+ */
+func newAttachmentKey(record db.Record, contentType string) (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return "accounts/" + strconv.FormatInt(record.AccountID, 10) +
+		"/records/" + strconv.FormatInt(record.ID, 10) +
+		"/" + hex.EncodeToString(buf) + attachmentContentTypes[contentType], nil
+}
+
+/**
+ * 只保留客户端文件名的最后一段, 扩展名按识别出的类型修正
+ */
+func attachmentFilename(filename string, contentType string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, `\`, "/"))
+	filename = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, filename)
+
+	ext := attachmentContentTypes[contentType]
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+
+	for len(name)+len(ext) > maxAttachmentFilename {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return name + ext
+}
+
+type getAttachmentsByRecordIdRequest struct {
+	RecordId int64 `json:"record_id" binding:"required,min=1"`
+}
+
+func (server *Server) getAttachmentsByRecordId(ctx *gin.Context) {
+	var req getAttachmentsByRecordIdRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var record db.Record
+	record, err = server.db.GetRecord(ctx, req.RecordId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, record.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var attachments []db.Attachment
+	attachments, err = server.db.GetAttachmentsByRecordId(ctx, req.RecordId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachments)
+}
+
+type attachmentRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+/**
+ * 查询附件并检查当前用户对附件所在账单的权限, 失败时写入响应
+ */
+func (server *Server) getAccessibleAttachment(ctx *gin.Context) (db.Attachment, bool) {
+	var req attachmentRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.Attachment{}, false
+	}
+
+	var attachment db.Attachment
+	attachment, err = server.db.GetAttachment(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return db.Attachment{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, attachment.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return db.Attachment{}, false
+	}
+
+	return attachment, true
+}
+
+/**
+ * 以保存时识别出的类型返回附件内容, 禁止浏览器再次猜测类型
+ */
+func (server *Server) downloadAttachment(ctx *gin.Context) {
+	attachment, ok := server.getAccessibleAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	content, err := server.blobStore.Get(ctx, attachment.BlobKey)
+	if err != nil {
+		if err == blob.ErrNotFound {
+			ctx.JSON(http.StatusNotFound, errorResponse(errAttachmentContentNotFound))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+	defer content.Close()
+
+	setAttachmentHeaders(ctx, attachment.ContentType, attachment.Filename)
+	ctx.Header("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Status(http.StatusOK)
+
+	_, err = io.Copy(ctx.Writer, content)
+	if err != nil {
+		_ = ctx.Error(err)
+	}
+}
+
+/**
+ * 附件信息立即删除, 内容由后台任务从BlobStore中删除
+ */
+func (server *Server) deleteAttachment(ctx *gin.Context) {
+	attachment, ok := server.getAccessibleAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	err := server.db.DeleteAttachment(ctx, attachment.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
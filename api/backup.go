@@ -0,0 +1,331 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 备份文件格式的版本, 格式发生不兼容的变化时递增
+ */
+const backupArchiveVersion = 1
+
+/**
+ * 恢复请求体的大小上限, 备份中的所有数据在一个事务中写入
+ */
+const maxRestoreBodySize = 32 << 20
+
+var (
+	errUnsupportedArchiveVersion = errors.New("unsupported archive version")
+	errBackupTooLarge            = fmt.Errorf("backup is larger than %d bytes", maxRestoreBodySize)
+)
+
+/**
+ * 账单的备份文件, 用户以邮箱表示, 以便在其他部署中恢复
+ */
+type backupArchive struct {
+	Version       int                  `json:"version" binding:"required"`
+	ExportedAt    time.Time            `json:"exported_at"`
+	Account       backupAccount        `json:"account" binding:"required"`
+	Members       []backupMember       `json:"members" binding:"max=1000,dive"`
+	Records       []backupRecord       `json:"records" binding:"max=100000,dive"`
+	Budgets       []backupBudget       `json:"budgets" binding:"max=1000,dive"`
+	BudgetAlerts  []backupBudgetAlert  `json:"budget_alerts" binding:"max=10000,dive"`
+	ApprovalRules []backupApprovalRule `json:"approval_rules" binding:"max=100,dive"`
+}
+
+type backupAccount struct {
+	Name       string    `json:"name" binding:"required,max=15"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+type backupMember struct {
+	Email string           `json:"email" binding:"required,email"`
+	Role  util.AccountRole `json:"role" binding:"required,min=1,max=2"`
+}
+
+type backupRecord struct {
+	Name              string          `json:"name" binding:"required,max=15"`
+	RecordType        util.RecordType `json:"record_type" binding:"required,min=1,max=7"`
+	Date              string          `json:"date" binding:"required,datetime=2006-01-02"`
+	Amount            string          `json:"amount" binding:"required,numeric"`
+	CreateUserEmail   string          `json:"create_user_email"`
+	LastModifiedEmail string          `json:"last_modified_user_email"`
+	CreateTime        time.Time       `json:"create_time"`
+	ExternalId        string          `json:"external_id,omitempty"`
+	// 为空表示已通过
+	ApprovalStatus util.ApprovalStatus `json:"approval_status" binding:"omitempty,min=1,max=3"`
+}
+
+/**
+ * Ref是预算在备份中的编号, 预算提醒通过BudgetRef引用
+ */
+type backupBudget struct {
+	Ref        int64             `json:"ref" binding:"required"`
+	RecordType util.RecordType   `json:"record_type" binding:"omitempty,min=1,max=7"`
+	UserEmail  string            `json:"user_email" binding:"omitempty,email"`
+	Period     util.BudgetPeriod `json:"period" binding:"required,min=1,max=3"`
+	Amount     string            `json:"amount" binding:"required,numeric"`
+	Rollover   bool              `json:"rollover"`
+	Thresholds []int32           `json:"thresholds" binding:"max=5,dive,min=1,max=1000"`
+}
+
+type backupBudgetAlert struct {
+	BudgetRef   int64  `json:"budget_ref" binding:"required"`
+	PeriodStart string `json:"period_start" binding:"required,datetime=2006-01-02"`
+	Threshold   int32  `json:"threshold" binding:"required"`
+	Spent       string `json:"spent" binding:"required,numeric"`
+	LimitAmount string `json:"limit_amount" binding:"required,numeric"`
+}
+
+type backupApprovalRule struct {
+	MinAmount string  `json:"min_amount" binding:"required,numeric"`
+	Types     []int32 `json:"types" binding:"max=7,dive,min=1,max=7"`
+}
+
+func newBackupArchive(backup db.AccountBackup) backupArchive {
+	emails := map[int64]string{}
+	for _, user := range backup.Users {
+		emails[user.ID] = user.Email
+	}
+
+	archive := backupArchive{
+		Version:       backupArchiveVersion,
+		ExportedAt:    time.Now().UTC(),
+		Account:       backupAccount{Name: backup.Account.Name, CreateTime: backup.Account.CreateTime},
+		Members:       []backupMember{},
+		Records:       make([]backupRecord, len(backup.Records)),
+		Budgets:       make([]backupBudget, len(backup.Budgets)),
+		BudgetAlerts:  make([]backupBudgetAlert, len(backup.BudgetAlerts)),
+		ApprovalRules: make([]backupApprovalRule, len(backup.ApprovalRules)),
+	}
+
+	for _, rule := range backup.AccessRules {
+		if email, ok := emails[rule.UserID]; ok {
+			archive.Members = append(archive.Members, backupMember{Email: email, Role: rule.Role})
+		}
+	}
+
+	for i, record := range backup.Records {
+		archive.Records[i] = backupRecord{
+			Name:              record.Name,
+			RecordType:        record.Type,
+			Date:              record.Date.Format(util.DateFormat),
+			Amount:            record.Amount,
+			CreateUserEmail:   emails[record.CreateUserID],
+			LastModifiedEmail: emails[record.LastModifiedUserID],
+			CreateTime:        record.CreateTime,
+			ExternalId:        record.ExternalID,
+			ApprovalStatus:    record.ApprovalStatus,
+		}
+	}
+
+	for i, budget := range backup.Budgets {
+		archive.Budgets[i] = backupBudget{
+			Ref:        budget.ID,
+			RecordType: budget.Type.Int32,
+			UserEmail:  emails[budget.UserID.Int64],
+			Period:     budget.Period,
+			Amount:     budget.Amount,
+			Rollover:   budget.Rollover,
+			Thresholds: budget.Thresholds,
+		}
+	}
+
+	for i, alert := range backup.BudgetAlerts {
+		archive.BudgetAlerts[i] = backupBudgetAlert{
+			BudgetRef:   alert.BudgetID,
+			PeriodStart: alert.PeriodStart.Format(util.DateFormat),
+			Threshold:   alert.Threshold,
+			Spent:       alert.Spent,
+			LimitAmount: alert.LimitAmount,
+		}
+	}
+
+	for i, rule := range backup.ApprovalRules {
+		archive.ApprovalRules[i] = backupApprovalRule{
+			MinAmount: rule.MinAmount,
+			Types:     rule.Types,
+		}
+	}
+
+	return archive
+}
+
+type exportAccountBackupRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+}
+
+func (server *Server) exportAccountBackup(ctx *gin.Context) {
+	var req exportAccountBackupRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var backup db.AccountBackup
+	backup, err = server.db.GetAccountBackup(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("account-%d-backup.json", req.AccountId)))
+	ctx.JSON(http.StatusOK, newBackupArchive(backup))
+}
+
+/**
+ * 备份文件可以由任何人编写, 其中的成员不会被加入恢复的账单
+ * SkippedMembers是恢复账单的用户以外的成员邮箱, 需要由拥有者重新邀请
+ * 所有记录归属于恢复账单的用户, 属于其他成员的预算不会恢复
+ */
+type restoreAccountResponse struct {
+	Account         db.Account `json:"account"`
+	RestoredRecords int        `json:"restored_records"`
+	RestoredBudgets int        `json:"restored_budgets"`
+	SkippedMembers  []string   `json:"skipped_members"`
+	SkippedBudgets  int        `json:"skipped_budgets"`
+}
+
+/**
+ * 请求体是exportAccountBackup导出的备份文件
+ * 1. 检查备份文件的大小, 版本和内容
+ * 2. 恢复账单的用户成为唯一的成员和拥有者, 记录的创建者和修改者都是该用户
+ * 3. 在一个事务中重新创建账单, 所有数据使用新的id
+ */
+func (server *Server) restoreAccount(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxRestoreBodySize)
+
+	var archive backupArchive
+	err := ctx.ShouldBindJSON(&archive)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(errBackupTooLarge))
+		} else {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		}
+		return
+	}
+
+	if archive.Version != backupArchiveVersion {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errUnsupportedArchiveVersion))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var user db.User
+	user, err = server.db.GetUser(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := restoreAccountResponse{SkippedMembers: []string{}}
+	restore := db.AccountRestore{
+		Name:    archive.Account.Name,
+		OwnerId: user.ID,
+	}
+
+	for _, member := range archive.Members {
+		if member.Email != user.Email {
+			resp.SkippedMembers = append(resp.SkippedMembers, member.Email)
+		}
+	}
+
+	for _, item := range archive.Records {
+		record := db.RestoredRecord{
+			NewRecord: db.NewRecord{
+				Name:       item.Name,
+				RecordType: item.RecordType,
+				Amount:     item.Amount,
+				ExternalId: item.ExternalId,
+			},
+			CreateUserId:       user.ID,
+			LastModifiedUserId: user.ID,
+			CreateTime:         item.CreateTime,
+			ApprovalStatus:     item.ApprovalStatus,
+		}
+		record.Date, _ = time.Parse(util.DateFormat, item.Date)
+		if record.CreateTime.IsZero() {
+			record.CreateTime = time.Now()
+		}
+
+		restore.Records = append(restore.Records, record)
+	}
+
+	for _, item := range archive.Budgets {
+		var userId int64
+		if item.UserEmail != "" {
+			if item.UserEmail != user.Email {
+				resp.SkippedBudgets++
+				continue
+			}
+			userId = user.ID
+		}
+
+		thresholds := item.Thresholds
+		if len(thresholds) == 0 {
+			thresholds = defaultBudgetThresholds
+		}
+
+		restore.Budgets = append(restore.Budgets, db.RestoredBudget{
+			Ref:        item.Ref,
+			RecordType: item.RecordType,
+			UserId:     userId,
+			Period:     item.Period,
+			Amount:     item.Amount,
+			Rollover:   item.Rollover,
+			Thresholds: thresholds,
+		})
+	}
+
+	for _, item := range archive.BudgetAlerts {
+		alert := db.RestoredBudgetAlert{
+			BudgetRef:   item.BudgetRef,
+			Threshold:   item.Threshold,
+			Spent:       item.Spent,
+			LimitAmount: item.LimitAmount,
+		}
+		alert.PeriodStart, _ = time.Parse(util.DateFormat, item.PeriodStart)
+		restore.BudgetAlerts = append(restore.BudgetAlerts, alert)
+	}
+
+	for _, item := range archive.ApprovalRules {
+		restore.ApprovalRules = append(restore.ApprovalRules, db.RestoredApprovalRule{
+			MinAmount: item.MinAmount,
+			Types:     uniqueTypes(item.Types),
+		})
+	}
+
+	resp.Account, err = server.db.RestoreAccount(ctx, restore)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	resp.RestoredRecords = len(restore.Records)
+	resp.RestoredBudgets = len(restore.Budgets)
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,466 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+var (
+	errBudgetUserNotMember  = errors.New("budget user is not a member of the account")
+	errInvalidBudgetAmount  = errors.New("budget amount must be positive")
+	defaultBudgetThresholds = []int32{80, 100}
+)
+
+func checkBudgetAmount(amount string) error {
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok || r.Sign() <= 0 {
+		return errInvalidBudgetAmount
+	}
+	return nil
+}
+
+/**
+ * RecordType为0表示覆盖所有类型, UserId为0表示覆盖所有成员
+ */
+type budgetResponse struct {
+	ID         int64             `json:"id"`
+	AccountId  int64             `json:"account_id"`
+	RecordType util.RecordType   `json:"record_type"`
+	UserId     int64             `json:"user_id"`
+	Period     util.BudgetPeriod `json:"period"`
+	Amount     string            `json:"amount"`
+	Rollover   bool              `json:"rollover"`
+	Thresholds []int32           `json:"thresholds"`
+	CreateTime time.Time         `json:"create_time"`
+}
+
+func newBudgetResponse(budget db.Budget) budgetResponse {
+	return budgetResponse{
+		ID:         budget.ID,
+		AccountId:  budget.AccountID,
+		RecordType: budget.Type.Int32,
+		UserId:     budget.UserID.Int64,
+		Period:     budget.Period,
+		Amount:     budget.Amount,
+		Rollover:   budget.Rollover,
+		Thresholds: budget.Thresholds,
+		CreateTime: budget.CreateTime,
+	}
+}
+
+/**
+ * 检查记录所在周期内的预算是否达到提醒阈值, 新产生的提醒以通知发送
+ * 记录已经写入, 检查失败不影响请求的结果
+ */
+func (server *Server) checkBudgetAlerts(
+	ctx *gin.Context,
+	accountId int64,
+	recordType util.RecordType,
+	userId int64,
+	date time.Time,
+) {
+	alerts, err := server.db.CheckBudgetAlerts(ctx, accountId, recordType, userId, date)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	if len(alerts) > 0 {
+		server.notifyBudgetAlerts(ctx, accountId, alerts)
+	}
+}
+
+/**
+ * 预算提醒通知账单的拥有者, 个人预算同时通知预算所属的成员
+ */
+func (server *Server) notifyBudgetAlerts(ctx *gin.Context, accountId int64, alerts []db.BudgetAlert) {
+	account, err := server.db.GetAccount(ctx, accountId)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	var rules []db.AccountAccessRule
+	rules, err = server.db.GetAccountAccessRulesByAccountId(ctx, accountId)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	members := map[int64]bool{}
+	owners := []int64{}
+	for _, rule := range rules {
+		members[rule.UserID] = true
+		if rule.Role == util.AccountRoleOwner {
+			owners = append(owners, rule.UserID)
+		}
+	}
+
+	for _, alert := range alerts {
+		var budget db.Budget
+		budget, err = server.db.GetBudget(ctx, alert.BudgetID)
+		if err != nil {
+			_ = ctx.Error(err)
+			continue
+		}
+
+		recipients := owners
+		if budget.UserID.Valid && members[budget.UserID.Int64] {
+			recipients = uniqueIds(append([]int64{budget.UserID.Int64}, owners...))
+		}
+
+		server.sendNotifications(ctx, notificationBudgetAlert, recipients, notificationData{
+			AccountId:   account.ID,
+			AccountName: account.Name,
+			BudgetId:    alert.BudgetID,
+			Threshold:   alert.Threshold,
+			Spent:       alert.Spent,
+			Limit:       alert.LimitAmount,
+		})
+	}
+}
+
+/**
+ * 批量写入记录后检查预算提醒, 账单, 类型, 创建者和日期都相同的记录只检查一次
+ */
+func (server *Server) checkRecordsBudgetAlerts(ctx *gin.Context, records []db.Record) {
+	type budgetAlertKey struct {
+		accountId  int64
+		recordType util.RecordType
+		userId     int64
+		date       time.Time
+	}
+
+	checked := map[budgetAlertKey]bool{}
+	for _, record := range records {
+		key := budgetAlertKey{record.AccountID, record.Type, record.CreateUserID, record.Date}
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+
+		server.checkBudgetAlerts(ctx, key.accountId, key.recordType, key.userId, key.date)
+	}
+}
+
+/**
+ * Rollover为true时上一个周期未用完的金额计入本周期的额度
+ * 只结转上一个周期, 更早周期的结余不会累计, 超支也不会减少本周期的额度
+ */
+type createBudgetRequest struct {
+	AccountId  int64             `json:"account_id" binding:"required,min=1"`
+	RecordType util.RecordType   `json:"record_type" binding:"omitempty,min=1,max=7"`
+	UserId     int64             `json:"user_id" binding:"omitempty,min=1"`
+	Period     util.BudgetPeriod `json:"period" binding:"required,min=1,max=3"`
+	Amount     string            `json:"amount" binding:"required,numeric"`
+	Rollover   bool              `json:"rollover"`
+	Thresholds []int32           `json:"thresholds" binding:"max=5,dive,min=1,max=1000"`
+}
+
+func (server *Server) createBudget(ctx *gin.Context) {
+	var req createBudgetRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = checkBudgetAmount(req.Amount)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	if req.UserId != 0 {
+		err = server.checkAccountAccessRule(ctx, req.UserId, req.AccountId, util.AccountRoleManager)
+		if err != nil {
+			if err == errAccessDenied {
+				ctx.JSON(http.StatusBadRequest, errorResponse(errBudgetUserNotMember))
+			} else {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			}
+			return
+		}
+	}
+
+	thresholds := req.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = defaultBudgetThresholds
+	}
+
+	var budget db.Budget
+	budget, err = server.db.CreateBudget(
+		ctx,
+		req.AccountId,
+		req.RecordType,
+		req.UserId,
+		req.Period,
+		req.Amount,
+		req.Rollover,
+		thresholds,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newBudgetResponse(budget))
+}
+
+/**
+ * 查询预算并检查当前用户对预算所在账单的权限
+ */
+func (server *Server) getBudgetWithAccess(
+	ctx *gin.Context,
+	id int64,
+	expect util.AccountRole,
+) (db.Budget, bool) {
+	budget, err := server.db.GetBudget(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return budget, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, budget.AccountID, expect)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return budget, false
+	}
+
+	return budget, true
+}
+
+/**
+ * Rollover的含义见createBudgetRequest
+ */
+type updateBudgetRequest struct {
+	ID         int64   `json:"id" binding:"required,min=1"`
+	Amount     string  `json:"amount" binding:"required,numeric"`
+	Rollover   bool    `json:"rollover"`
+	Thresholds []int32 `json:"thresholds" binding:"max=5,dive,min=1,max=1000"`
+}
+
+func (server *Server) updateBudget(ctx *gin.Context) {
+	var req updateBudgetRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = checkBudgetAmount(req.Amount)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	budget, ok := server.getBudgetWithAccess(ctx, req.ID, util.AccountRoleOwner)
+	if !ok {
+		return
+	}
+
+	thresholds := req.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = budget.Thresholds
+	}
+
+	err = server.db.UpdateBudget(ctx, req.ID, req.Amount, req.Rollover, thresholds)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+type deleteBudgetRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteBudget(ctx *gin.Context) {
+	var req deleteBudgetRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getBudgetWithAccess(ctx, req.ID, util.AccountRoleOwner)
+	if !ok {
+		return
+	}
+
+	err = server.db.DeleteBudget(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+type getBudgetProgressRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+	// 查询该日期所在的周期, 默认为当天
+	Date *util.Date `json:"date"`
+	// IANA时区名, 默认使用服务器所在时区
+	TimeZone string `json:"time_zone"`
+}
+
+/**
+ * PeriodEnd不包含在周期内
+ */
+type budgetProgressResponse struct {
+	Budget      budgetResponse `json:"budget"`
+	PeriodStart string         `json:"period_start"`
+	PeriodEnd   string         `json:"period_end"`
+	LimitAmount string         `json:"limit_amount"`
+	Spent       string         `json:"spent"`
+	Percent     int32          `json:"percent"`
+}
+
+func (server *Server) getBudgetProgress(ctx *gin.Context) {
+	var req getBudgetProgressRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var loc *time.Location
+	loc, err = loadLocation(req.TimeZone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	date := dateIn(time.Now(), loc)
+	if req.Date != nil {
+		date = dateIn(time.Time(*req.Date), loc)
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var budgets []db.Budget
+	budgets, err = server.db.GetBudgetsByAccountId(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var progresses []db.BudgetProgress
+	progresses, err = server.db.GetBudgetProgresses(ctx, req.AccountId, date)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	byId := map[int64]db.BudgetProgress{}
+	for _, progress := range progresses {
+		byId[progress.BudgetID] = progress
+	}
+
+	resp := []budgetProgressResponse{}
+	for _, budget := range budgets {
+		progress, ok := byId[budget.ID]
+		if !ok {
+			continue
+		}
+
+		resp = append(resp, budgetProgressResponse{
+			Budget:      newBudgetResponse(budget),
+			PeriodStart: progress.PeriodStart.Format(util.DateFormat),
+			PeriodEnd:   progress.PeriodEnd.Format(util.DateFormat),
+			LimitAmount: progress.LimitAmount,
+			Spent:       progress.Spent,
+			Percent:     progress.Percent,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type getBudgetAlertsRequest struct {
+	AccountId int64  `json:"account_id" binding:"required,min=1"`
+	PageSize  int64  `json:"page_size" binding:"required,min=5,max=20"`
+	Cursor    string `json:"cursor"`
+}
+
+func (server *Server) getBudgetAlerts(ctx *gin.Context) {
+	var req getBudgetAlertsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorBudgetAlerts)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var alerts []db.BudgetAlert
+	alerts, err = server.db.GetBudgetAlertsByAccountIdAfterCursor(ctx, req.AccountId, cursor.ID, req.PageSize+1)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(alerts, req.PageSize, cursorBudgetAlerts, func(alert db.BudgetAlert) pageCursor {
+		return pageCursor{ID: alert.ID}
+	}))
+}
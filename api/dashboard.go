@@ -0,0 +1,57 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+)
+
+type getDashboardRequest struct {
+	// IANA时区名, 决定本月和上月的边界, 默认使用服务器所在时区
+	TimeZone string `json:"time_zone"`
+}
+
+type getDashboardResponse struct {
+	Accounts []db.AccountSummary `json:"accounts"`
+}
+
+func (server *Server) getDashboard(ctx *gin.Context) {
+	var req getDashboardRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var loc *time.Location
+	loc, err = loadLocation(req.TimeZone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	thisMonthStart := truncateDate(dateIn(time.Now(), loc), statisticsIntervalMonth)
+	lastMonthStart := thisMonthStart.AddDate(0, -1, 0)
+	nextMonthStart := thisMonthStart.AddDate(0, 1, 0)
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var accounts []db.AccountSummary
+	accounts, err = server.db.GetAccountSummariesByUserId(
+		ctx,
+		authPayload.UserId,
+		lastMonthStart,
+		thisMonthStart,
+		nextMonthStart,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, getDashboardResponse{Accounts: accounts})
+}
@@ -0,0 +1,363 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/digest"
+	"github.com/timelyrain/star-account/mail"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 每个账单在摘要中列出的最大支出数
+ */
+const digestTopExpenses = 5
+
+var errMissingTimeZone = errors.New("missing time zone")
+
+type digestSubscriptionResponse struct {
+	Frequency    util.DigestFrequency `json:"frequency"`
+	TimeZone     string               `json:"time_zone"`
+	NextSendTime time.Time            `json:"next_send_time"`
+	LastSentTime *time.Time           `json:"last_sent_time"`
+}
+
+func newDigestSubscriptionResponse(sub db.DigestSubscription) digestSubscriptionResponse {
+	resp := digestSubscriptionResponse{
+		Frequency:    sub.Frequency,
+		TimeZone:     sub.TimeZone,
+		NextSendTime: sub.NextSendTime,
+	}
+	if sub.LastSentTime.Valid {
+		resp.LastSentTime = &sub.LastSentTime.Time
+	}
+
+	return resp
+}
+
+func (server *Server) getDigestSubscription(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sub, err := server.db.GetDigestSubscription(ctx, authPayload.UserId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newDigestSubscriptionResponse(sub))
+}
+
+type updateDigestSubscriptionRequest struct {
+	Frequency util.DigestFrequency `json:"frequency" binding:"required,oneof=1 2"`
+	// IANA时区名, 摘要在该时区的周一或1日早上发送
+	TimeZone string `json:"time_zone" binding:"required"`
+}
+
+/**
+ * 订阅或修改摘要邮件, 下次发送时间按新的频率和时区重新计算
+ */
+func (server *Server) updateDigestSubscription(ctx *gin.Context) {
+	var req updateDigestSubscriptionRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var loc *time.Location
+	loc, err = loadDigestLocation(req.TimeZone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var sub db.DigestSubscription
+	sub, err = server.db.UpsertDigestSubscription(
+		ctx,
+		authPayload.UserId,
+		req.Frequency,
+		req.TimeZone,
+		digest.NextSendTime(req.Frequency, time.Now(), loc),
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newDigestSubscriptionResponse(sub))
+}
+
+func (server *Server) deleteDigestSubscription(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.db.DeleteDigestSubscription(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+/**
+ * 与loadLocation不同, 摘要的时区必须明确指定, 不使用服务器所在时区
+ */
+func loadDigestLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, errMissingTimeZone
+	}
+	return time.LoadLocation(name)
+}
+
+/**
+ * 生成并发送一封摘要邮件, 成功后安排下一次发送
+ * 失败时不更新订阅, 在领取的租约到期后重试
+ */
+func (server *Server) sendDigest(ctx context.Context, sub db.DigestSubscription, now time.Time) error {
+	loc, err := loadDigestLocation(sub.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	var user db.User
+	user, err = server.db.GetUser(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+
+	var d digest.Digest
+	d, err = server.buildDigest(ctx, user, sub.Frequency, now, loc)
+	if err != nil {
+		return err
+	}
+
+	var text, html string
+	text, html, err = digest.Render(d)
+	if err != nil {
+		return err
+	}
+
+	err = server.mailSender.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: d.Subject(),
+		Text:    text,
+		HTML:    html,
+	})
+	if err != nil {
+		return err
+	}
+
+	return server.db.UpdateDigestSubscriptionSent(ctx, sub.UserID, digest.NextSendTime(sub.Frequency, now, loc), now)
+}
+
+/**
+ * 汇总用户所在的每个账单在上一个完整周期内的收支, 最大支出和预算情况
+ */
+func (server *Server) buildDigest(
+	ctx context.Context,
+	user db.User,
+	frequency util.DigestFrequency,
+	now time.Time,
+	loc *time.Location,
+) (digest.Digest, error) {
+	start, end := digest.Period(frequency, now, loc)
+	lastDay := end.AddDate(0, 0, -1)
+
+	d := digest.Digest{
+		UserName:    user.Name,
+		Frequency:   frequency,
+		PeriodStart: start.Format(util.DateFormat),
+		PeriodEnd:   lastDay.Format(util.DateFormat),
+		Accounts:    []digest.Account{},
+	}
+
+	rules, err := server.db.GetAccountAccessRulesByUserId(ctx, user.ID)
+	if err != nil {
+		return d, err
+	}
+
+	accountIds := make([]int64, len(rules))
+	for i, rule := range rules {
+		accountIds[i] = rule.AccountID
+	}
+
+	var accounts []db.Account
+	accounts, err = server.db.GetAccountsByIds(ctx, accountIds)
+	if err != nil {
+		return d, err
+	}
+
+	for _, account := range accounts {
+		var a digest.Account
+		a, err = server.buildAccountDigest(ctx, account, user.ID, frequency, start, lastDay)
+		if err != nil {
+			return d, err
+		}
+		d.Accounts = append(d.Accounts, a)
+	}
+
+	return d, nil
+}
+
+func (server *Server) buildAccountDigest(
+	ctx context.Context,
+	account db.Account,
+	userId int64,
+	frequency util.DigestFrequency,
+	start time.Time,
+	lastDay time.Time,
+) (digest.Account, error) {
+	a := digest.Account{Name: account.Name}
+
+	stats, err := server.db.GetRecordsStatistics(ctx, account.ID, statisticsIntervalMonth, "category", start, lastDay)
+	if err != nil {
+		return a, err
+	}
+
+	income, expense := new(big.Rat), new(big.Rat)
+	categoryIncome := map[int64]*big.Rat{}
+	categoryExpense := map[int64]*big.Rat{}
+	for _, stat := range stats {
+		sumAmount(income, stat.Income)
+		sumAmount(expense, stat.Expense)
+		if categoryIncome[stat.GroupKey] == nil {
+			categoryIncome[stat.GroupKey], categoryExpense[stat.GroupKey] = new(big.Rat), new(big.Rat)
+		}
+		sumAmount(categoryIncome[stat.GroupKey], stat.Income)
+		sumAmount(categoryExpense[stat.GroupKey], stat.Expense)
+	}
+
+	for t := util.RecordType(util.RecordTypeFood); t <= util.RecordTypeGift; t++ {
+		if categoryIncome[int64(t)] == nil {
+			continue
+		}
+		a.Categories = append(a.Categories, digest.Category{
+			Name:    digest.CategoryName(t),
+			Income:  categoryIncome[int64(t)].FloatString(2),
+			Expense: categoryExpense[int64(t)].FloatString(2),
+		})
+	}
+
+	prevStart, prevEnd := digest.PreviousPeriod(frequency, start)
+	stats, err = server.db.GetRecordsStatistics(ctx, account.ID, statisticsIntervalMonth, "", prevStart, prevEnd.AddDate(0, 0, -1))
+	if err != nil {
+		return a, err
+	}
+
+	prevIncome, prevExpense := new(big.Rat), new(big.Rat)
+	for _, stat := range stats {
+		sumAmount(prevIncome, stat.Income)
+		sumAmount(prevExpense, stat.Expense)
+	}
+
+	a.Income = income.FloatString(2)
+	a.Expense = expense.FloatString(2)
+	a.PreviousIncome = prevIncome.FloatString(2)
+	a.PreviousExpense = prevExpense.FloatString(2)
+	if prevExpense.Sign() > 0 {
+		change := new(big.Rat).Sub(expense, prevExpense)
+		change.Mul(change, big.NewRat(100, 1)).Quo(change, prevExpense)
+		a.ExpenseChange = change.FloatString(1) + "%"
+		if change.Sign() >= 0 {
+			a.ExpenseChange = "+" + a.ExpenseChange
+		}
+	}
+
+	var records []db.Record
+	records, err = server.db.GetTopExpensesByAccountId(ctx, account.ID, start, lastDay, digestTopExpenses)
+	if err != nil {
+		return a, err
+	}
+
+	for _, record := range records {
+		amount := new(big.Rat)
+		sumAmount(amount, record.Amount)
+		a.TopExpenses = append(a.TopExpenses, digest.Expense{
+			Date:     record.Date.Format(util.DateFormat),
+			Name:     record.Name,
+			Category: digest.CategoryName(record.Type),
+			Amount:   amount.FloatString(2),
+		})
+	}
+
+	a.Budgets, err = server.buildBudgetDigests(ctx, account.ID, userId, lastDay)
+	return a, err
+}
+
+/**
+ * 账单的总预算和当前用户的个人预算在周期最后一天所在预算周期内的进度
+ */
+func (server *Server) buildBudgetDigests(
+	ctx context.Context,
+	accountId int64,
+	userId int64,
+	date time.Time,
+) ([]digest.Budget, error) {
+	budgets, err := server.db.GetBudgetsByAccountId(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	var progresses []db.BudgetProgress
+	progresses, err = server.db.GetBudgetProgresses(ctx, accountId, date)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := map[int64]db.BudgetProgress{}
+	for _, progress := range progresses {
+		byId[progress.BudgetID] = progress
+	}
+
+	res := []digest.Budget{}
+	for _, budget := range budgets {
+		progress, ok := byId[budget.ID]
+		if !ok || budget.UserID.Valid && budget.UserID.Int64 != userId {
+			continue
+		}
+
+		name := "全部分类"
+		if budget.Type.Valid {
+			name = digest.CategoryName(budget.Type.Int32)
+		}
+		if budget.UserID.Valid {
+			name += "(个人)"
+		}
+
+		spent, limit := new(big.Rat), new(big.Rat)
+		sumAmount(spent, progress.Spent)
+		sumAmount(limit, progress.LimitAmount)
+		res = append(res, digest.Budget{
+			Name:    name,
+			Period:  progress.PeriodStart.Format(util.DateFormat) + " ~ " + progress.PeriodEnd.AddDate(0, 0, -1).Format(util.DateFormat),
+			Spent:   spent.FloatString(2),
+			Limit:   limit.FloatString(2),
+			Percent: progress.Percent,
+		})
+	}
+
+	return res, nil
+}
+
+/**
+ * 累加数据库返回的numeric字符串, 无法解析的值视为0
+ */
+func sumAmount(sum *big.Rat, amount string) {
+	if r, ok := new(big.Rat).SetString(amount); ok {
+		sum.Add(sum, r)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+)
+
+/**
+ * 账单中可以订阅的事件
+ */
+const (
+	eventRecordCreated  = "record.created"
+	eventRecordUpdated  = "record.updated"
+	eventRecordDeleted  = "record.deleted"
+	eventMemberAdded    = "member.added"
+	eventMemberRemoved  = "member.removed"
+	eventAccountRenamed = "account.renamed"
+)
+
+/**
+ * 事件的内容, 同时作为webhook投递的请求体和实时推送的数据
+ */
+type accountEvent struct {
+	Event     string    `json:"event"`
+	AccountId int64     `json:"account_id"`
+	UserId    int64     `json:"user_id"`
+	Time      time.Time `json:"time"`
+	Data      any       `json:"data"`
+}
+
+type memberEventData struct {
+	UserId int64 `json:"user_id"`
+	Role   int32 `json:"role"`
+}
+
+type accountEventData struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func newAccountEvent(ctx *gin.Context, event string, accountId int64, data any) accountEvent {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	return accountEvent{
+		Event:     event,
+		AccountId: accountId,
+		UserId:    authPayload.UserId,
+		Time:      time.Now(),
+		Data:      data,
+	}
+}
+
+func newRecordEvents(ctx *gin.Context, event string, records []db.Record) []accountEvent {
+	events := make([]accountEvent, len(records))
+	for i, record := range records {
+		events[i] = newAccountEvent(ctx, event, record.AccountID, record)
+	}
+
+	return events
+}
+
+/**
+ * 在一个事务中执行写入并发布写入产生的事件, 写入失败时不发布, 发布失败时写入一起回滚
+ * fn中的写入必须通过store执行
+ */
+func (server *Server) execWithEvents(ctx *gin.Context, fn func(store *db.DB) ([]accountEvent, error)) error {
+	return server.db.ExecWithEvents(ctx, func(tx *db.DB) ([]db.AccountEvent, error) {
+		events, err := fn(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		accountEvents := make([]db.AccountEvent, 0, len(events))
+		for _, event := range events {
+			var payload []byte
+			payload, err = json.Marshal(event)
+			if err != nil {
+				return nil, err
+			}
+
+			accountEvents = append(accountEvents, db.AccountEvent{
+				AccountId: event.AccountId,
+				Event:     event.Event,
+				Payload:   string(payload),
+			})
+		}
+
+		return accountEvents, nil
+	})
+}
@@ -1,12 +1,18 @@
 package api
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
 	"github.com/timelyrain/star-account/token"
 )
 
@@ -16,6 +22,22 @@ const (
 	authorizationPayloadKey = "authorzation_payload"
 )
 
+const (
+	idempotencyKeyHeaderKey      = "Idempotency-Key"
+	idempotencyReplayedHeaderKey = "Idempotent-Replayed"
+	idempotencyKeyMaxLength      = 255
+	// 需要读出请求体计算摘要, 上限为所有修改接口中最大的请求体
+	// 各接口自己的上限在读取缓存的请求体时仍然生效
+	maxIdempotentRequestSize = maxRestoreBodySize
+)
+
+var (
+	errIdempotencyKeyTooLong     = errors.New("idempotency key is too long")
+	errIdempotencyKeyReused      = errors.New("idempotency key is already used by a different request")
+	errIdempotencyKeyInProgress  = errors.New("a request with the same idempotency key is in progress")
+	errIdempotentRequestTooLarge = fmt.Errorf("request body is larger than %d bytes", maxIdempotentRequestSize)
+)
+
 func authMiddleWare(tokenMaker token.Maker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
@@ -51,11 +73,114 @@ func authMiddleWare(tokenMaker token.Maker) gin.HandlerFunc {
 	}
 }
 
+/**
+ * 记录处理器写出的响应体, 用于保存幂等请求的响应
+ */
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+/**
+ * 必须位于authMiddleWare之后
+ * 1. 请求没有Idempotency-Key头时直接放行
+ * 2. 第一次出现的key: 执行请求并保存响应和Content-Type, 5xx响应和处理器panic时删除key以便客户端重试
+ * 3. 已保存响应的key: 请求体一致时重放响应, 不一致时返回409
+ * 4. 尚未完成的key: 返回409
+ */
+func idempotencyMiddleware(store *db.DB, ttl time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeaderKey)
+		if len(key) == 0 {
+			ctx.Next()
+			return
+		}
+
+		if len(key) > idempotencyKeyMaxLength {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(errIdempotencyKeyTooLong))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxIdempotentRequestSize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, errorResponse(errIdempotentRequestTooLarge))
+			} else {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(err))
+			}
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(ctx.Request.Method + " " + ctx.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		record, created, err := store.CreateIdempotencyKey(ctx, authPayload.UserId, key, requestHash, ttl)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if !created {
+			switch {
+			case record.RequestHash != requestHash:
+				ctx.AbortWithStatusJSON(http.StatusConflict, errorResponse(errIdempotencyKeyReused))
+			case record.StatusCode == 0:
+				ctx.AbortWithStatusJSON(http.StatusConflict, errorResponse(errIdempotencyKeyInProgress))
+			default:
+				ctx.Header(idempotencyReplayedHeaderKey, "true")
+				ctx.Data(int(record.StatusCode), record.ContentType, record.ResponseBody)
+				ctx.Abort()
+			}
+			return
+		}
+
+		// 处理器panic时释放key, 再交给Recovery中间件处理
+		defer func() {
+			if r := recover(); r != nil {
+				if err := store.DeleteIdempotencyKey(ctx, record.ID); err != nil {
+					_ = ctx.Error(err)
+				}
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			err = store.DeleteIdempotencyKey(ctx, record.ID)
+		} else {
+			err = store.UpdateIdempotencyKeyResponse(ctx, record.ID, int32(status), recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			_ = ctx.Error(err)
+		}
+	}
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*") // 允许所有域名
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/mail"
+	"github.com/timelyrain/star-account/token"
+)
+
+var errNotificationRecipientNotFound = errors.New("notification recipient not found")
+
+/**
+ * 通知的类型
+ */
+const (
+	notificationMemberAdded    = "member.added"
+	notificationMemberRemoved  = "member.removed"
+	notificationAccountDeleted = "account.deleted"
+	notificationRecordApproved = "record.approved"
+	notificationRecordRejected = "record.rejected"
+	notificationBudgetAlert    = "budget.alert"
+)
+
+type notificationPreference struct {
+	Type  string `json:"type"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+}
+
+/**
+ * 所有通知类型及用户没有设置时的默认偏好, 失去账单访问权限的通知默认同时发送邮件
+ */
+var defaultNotificationPreferences = []notificationPreference{
+	{Type: notificationMemberAdded, InApp: true, Email: false},
+	{Type: notificationMemberRemoved, InApp: true, Email: true},
+	{Type: notificationAccountDeleted, InApp: true, Email: true},
+	{Type: notificationRecordApproved, InApp: true, Email: false},
+	{Type: notificationRecordRejected, InApp: true, Email: true},
+	{Type: notificationBudgetAlert, InApp: true, Email: true},
+}
+
+/**
+ * 通知的内容, 只保存id和账单名称, 用户名在显示时查询
+ */
+type notificationData struct {
+	AccountId   int64  `json:"account_id"`
+	AccountName string `json:"account_name"`
+	ActorUserId int64  `json:"actor_user_id"`
+	Role        int32  `json:"role,omitempty"`
+	RecordId    int64  `json:"record_id,omitempty"`
+	RecordName  string `json:"record_name,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	BudgetId    int64  `json:"budget_id,omitempty"`
+	Threshold   int32  `json:"threshold,omitempty"`
+	Spent       string `json:"spent,omitempty"`
+	Limit       string `json:"limit,omitempty"`
+}
+
+/**
+ * 按接收者的偏好通知userIds, 当前用户不通知自己
+ * 通知在变更提交后写入, 失败不影响请求的结果
+ */
+func (server *Server) notify(ctx *gin.Context, notificationType string, userIds []int64, data notificationData) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	recipients := make([]int64, 0, len(userIds))
+	for _, userId := range userIds {
+		if userId != authPayload.UserId {
+			recipients = append(recipients, userId)
+		}
+	}
+
+	server.sendNotifications(ctx, notificationType, recipients, data)
+}
+
+/**
+ * 按接收者的偏好通知userIds, 当前用户也会收到通知
+ */
+func (server *Server) sendNotifications(ctx *gin.Context, notificationType string, userIds []int64, data notificationData) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	data.ActorUserId = authPayload.UserId
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	var preferences []db.NotificationPreference
+	preferences, err = server.db.GetNotificationPreferencesByTypeAndUserIds(ctx, notificationType, userIds)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	preferenceByUserId := make(map[int64]db.NotificationPreference, len(preferences))
+	for _, preference := range preferences {
+		preferenceByUserId[preference.UserID] = preference
+	}
+
+	defaults := defaultNotificationPreference(notificationType)
+	notifications := make([]db.NewNotification, 0, len(userIds))
+	for _, userId := range userIds {
+		inApp, email := defaults.InApp, defaults.Email
+		if preference, ok := preferenceByUserId[userId]; ok {
+			inApp, email = preference.InApp, preference.Email
+		}
+
+		notifications = append(notifications, db.NewNotification{
+			UserId: userId,
+			Type:   notificationType,
+			Data:   payload,
+			InApp:  inApp,
+			Email:  email,
+		})
+	}
+
+	err = server.db.CreateNotifications(ctx, notifications)
+	if err != nil {
+		_ = ctx.Error(err)
+	}
+}
+
+/**
+ * 通知与账单成员相关的变更, 通知中带上账单当前的名称
+ */
+func (server *Server) notifyMembers(ctx *gin.Context, notificationType string, accountId int64, userIds []int64, role int32) {
+	account, err := server.db.GetAccount(ctx, accountId)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	server.notify(ctx, notificationType, userIds, notificationData{
+		AccountId:   account.ID,
+		AccountName: account.Name,
+		Role:        role,
+	})
+}
+
+func defaultNotificationPreference(notificationType string) notificationPreference {
+	for _, preference := range defaultNotificationPreferences {
+		if preference.Type == notificationType {
+			return preference
+		}
+	}
+
+	return notificationPreference{Type: notificationType, InApp: true}
+}
+
+type notificationResponse struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	Data       json.RawMessage `json:"data"`
+	Read       bool            `json:"read"`
+	CreateTime time.Time       `json:"create_time"`
+}
+
+func newNotificationResponse(notification db.Notification) notificationResponse {
+	return notificationResponse{
+		ID:         notification.ID,
+		Type:       notification.Type,
+		Data:       notification.Data,
+		Read:       notification.ReadTime.Valid,
+		CreateTime: notification.CreateTime,
+	}
+}
+
+type getNotificationsRequest struct {
+	PageSize   int64  `json:"page_size" binding:"required,min=5,max=20"`
+	Cursor     string `json:"cursor"`
+	UnreadOnly bool   `json:"unread_only"`
+}
+
+/**
+ * 当前用户收件箱中的通知, 按时间倒序
+ */
+func (server *Server) getNotifications(ctx *gin.Context) {
+	var req getNotificationsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorNotifications)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var notifications []db.Notification
+	notifications, err = server.db.GetNotificationsByUserIdAfterCursor(
+		ctx,
+		authPayload.UserId,
+		req.UnreadOnly,
+		cursor.ID,
+		req.PageSize+1,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	page := newPageResponse(notifications, req.PageSize, cursorNotifications, func(notification db.Notification) pageCursor {
+		return pageCursor{ID: notification.ID}
+	})
+
+	resp := pageResponse[notificationResponse]{
+		Items:      []notificationResponse{},
+		NextCursor: page.NextCursor,
+	}
+	for _, notification := range page.Items {
+		resp.Items = append(resp.Items, newNotificationResponse(notification))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) getUnreadNotificationsCount(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	count, err := server.db.GetUnreadNotificationsCountByUserId(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, count)
+}
+
+type markNotificationReadRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+func (server *Server) markNotificationRead(ctx *gin.Context) {
+	var req markNotificationReadRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var notification db.Notification
+	notification, err = server.db.MarkNotificationRead(ctx, req.ID, authPayload.UserId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newNotificationResponse(notification))
+}
+
+func (server *Server) markAllNotificationsRead(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	_, err := server.db.MarkAllNotificationsRead(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+/**
+ * 返回所有通知类型的偏好, 没有设置的类型使用默认值
+ */
+func (server *Server) getNotificationPreferences(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	preferences, err := server.db.GetNotificationPreferencesByUserId(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	preferenceByType := make(map[string]db.NotificationPreference, len(preferences))
+	for _, preference := range preferences {
+		preferenceByType[preference.Type] = preference
+	}
+
+	resp := make([]notificationPreference, len(defaultNotificationPreferences))
+	for i, preference := range defaultNotificationPreferences {
+		if p, ok := preferenceByType[preference.Type]; ok {
+			preference.InApp, preference.Email = p.InApp, p.Email
+		}
+		resp[i] = preference
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type updateNotificationPreferenceRequest struct {
+	Type  string `json:"type" binding:"required,oneof=member.added member.removed account.deleted record.approved record.rejected budget.alert"`
+	InApp bool   `json:"in_app"`
+	Email bool   `json:"email"`
+}
+
+func (server *Server) updateNotificationPreference(ctx *gin.Context) {
+	var req updateNotificationPreferenceRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var preference db.NotificationPreference
+	preference, err = server.db.UpsertNotificationPreference(ctx, authPayload.UserId, req.Type, req.InApp, req.Email)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, notificationPreference{
+		Type:  preference.Type,
+		InApp: preference.InApp,
+		Email: preference.Email,
+	})
+}
+
+/**
+ * 按通知类型生成邮件, actorName是触发通知的用户的名称
+ */
+func newNotificationEmail(notification db.Notification, to db.User, actorName string) (mail.Message, error) {
+	var data notificationData
+	err := json.Unmarshal(notification.Data, &data)
+	if err != nil {
+		return mail.Message{}, err
+	}
+
+	if to.Email == "" {
+		return mail.Message{}, errNotificationRecipientNotFound
+	}
+
+	msg := mail.Message{To: to.Email}
+	switch notification.Type {
+	case notificationMemberAdded:
+		msg.Subject = fmt.Sprintf("你被加入了账单「%s」", data.AccountName)
+		msg.Text = fmt.Sprintf("%s, 你好:\n\n%s 把你加入了账单「%s」, 现在你可以查看和记录该账单的收支。\n", to.Name, actorName, data.AccountName)
+	case notificationMemberRemoved:
+		msg.Subject = fmt.Sprintf("你被移出了账单「%s」", data.AccountName)
+		msg.Text = fmt.Sprintf("%s, 你好:\n\n%s 把你移出了账单「%s」, 你将无法再访问该账单。\n", to.Name, actorName, data.AccountName)
+	case notificationAccountDeleted:
+		msg.Subject = fmt.Sprintf("账单「%s」已被删除", data.AccountName)
+		msg.Text = fmt.Sprintf("%s, 你好:\n\n%s 删除了账单「%s」, 其中的所有记录已被删除。\n", to.Name, actorName, data.AccountName)
+	case notificationRecordApproved:
+		msg.Subject = fmt.Sprintf("记录「%s」已通过审批", data.RecordName)
+		msg.Text = fmt.Sprintf("%s, 你好:\n\n%s 通过了你在账单「%s」中提交的记录「%s」。\n", to.Name, actorName, data.AccountName, data.RecordName)
+	case notificationRecordRejected:
+		msg.Subject = fmt.Sprintf("记录「%s」被驳回", data.RecordName)
+		msg.Text = fmt.Sprintf("%s, 你好:\n\n%s 驳回了你在账单「%s」中提交的记录「%s」, 原因: %s\n", to.Name, actorName, data.AccountName, data.RecordName, data.Reason)
+	case notificationBudgetAlert:
+		msg.Subject = fmt.Sprintf("账单「%s」的预算已使用%d%%", data.AccountName, data.Threshold)
+		msg.Text = fmt.Sprintf("%s, 你好:\n\n账单「%s」的一项预算本期已支出%s, 达到预算%s的%d%%。\n", to.Name, data.AccountName, data.Spent, data.Limit, data.Threshold)
+	default:
+		return mail.Message{}, fmt.Errorf("unknown notification type %q", notification.Type)
+	}
+
+	return msg, nil
+}
+
+/**
+ * 通知的接收者和触发者
+ */
+func notificationUserIds(notifications []db.Notification) []int64 {
+	ids := make([]int64, 0, len(notifications)*2)
+	for _, notification := range notifications {
+		ids = append(ids, notification.UserID)
+
+		var data notificationData
+		if json.Unmarshal(notification.Data, &data) == nil && data.ActorUserId != 0 {
+			ids = append(ids, data.ActorUserId)
+		}
+	}
+
+	return uniqueIds(ids)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/util"
+)
+
+var (
+	errInvalidCursor = errors.New("invalid cursor")
+)
+
+/**
+ * 游标所属的接口和排序方式, 解码时拒绝其他接口或其他排序方式的游标
+ * date, amount和time表示该排序的游标必须带有对应的排序键
+ */
+type cursorKind struct {
+	name   string
+	date   bool
+	amount bool
+	time   bool
+}
+
+var (
+	cursorAccounts          = cursorKind{name: "accounts"}
+	cursorBudgetAlerts      = cursorKind{name: "budget_alerts"}
+	cursorNotifications     = cursorKind{name: "notifications"}
+	cursorRunningBalances   = cursorKind{name: "running_balances", date: true}
+	cursorRecords           = cursorKind{name: "records", date: true}
+	cursorPendingRecords    = cursorKind{name: "pending_records"}
+	cursorUsersByName       = cursorKind{name: "users_by_name"}
+	cursorUsersByAccount    = cursorKind{name: "users_by_account"}
+	cursorWebhookDeliveries = cursorKind{name: "webhook_deliveries"}
+)
+
+/**
+ * 检索账单记录的游标按排序字段和方向区分, 排序键总是包含日期和id
+ */
+func searchRecordsCursorKind(sortBy string, sortDesc bool) cursorKind {
+	name := "records_search:" + sortBy + ":asc"
+	if sortDesc {
+		name = "records_search:" + sortBy + ":desc"
+	}
+
+	return cursorKind{
+		name:   name,
+		date:   true,
+		amount: sortBy == "amount",
+		time:   sortBy == "create_time",
+	}
+}
+
+/**
+ * 列表接口的游标, 记录上一页最后一项的排序键
+ * 对客户端不透明, 以base64编码的json传递
+ */
+type pageCursor struct {
+	Kind   string `json:"k"`
+	Date   string `json:"d,omitempty"`
+	Amount string `json:"a,omitempty"`
+	Time   string `json:"t,omitempty"`
+	ID     int64  `json:"i"`
+}
+
+func encodeCursor(kind cursorKind, cursor pageCursor) string {
+	cursor.Kind = kind.name
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+/**
+ * 空字符串表示第一页, 返回零值的游标
+ */
+func decodeCursor(s string, kind cursorKind) (pageCursor, error) {
+	var cursor pageCursor
+	if s == "" {
+		return cursor, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.Kind != kind.name || cursor.ID < 1 {
+		return cursor, errInvalidCursor
+	}
+
+	if kind.date != (cursor.Date != "") || kind.amount != (cursor.Amount != "") || kind.time != (cursor.Time != "") {
+		return cursor, errInvalidCursor
+	}
+
+	if cursor.Date != "" {
+		_, err = time.Parse(util.DateFormat, cursor.Date)
+		if err != nil {
+			return cursor, errInvalidCursor
+		}
+	}
+
+	if cursor.Amount != "" {
+		_, ok := new(big.Rat).SetString(cursor.Amount)
+		if !ok || strings.Contains(cursor.Amount, "/") {
+			return cursor, errInvalidCursor
+		}
+	}
+
+	if cursor.Time != "" {
+		_, err = time.Parse(time.RFC3339Nano, cursor.Time)
+		if err != nil {
+			return cursor, errInvalidCursor
+		}
+	}
+
+	return cursor, nil
+}
+
+func (cursor pageCursor) date() time.Time {
+	t, _ := time.Parse(util.DateFormat, cursor.Date)
+	return t
+}
+
+func (cursor pageCursor) time() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, cursor.Time)
+	return t
+}
+
+/**
+ * 列表接口的统一返回格式, NextCursor为空表示没有下一页
+ */
+type pageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+}
+
+/**
+ * items需要按pageSize+1条查询, 多出的一条用于判断是否存在下一页
+ */
+func newPageResponse[T any](items []T, pageSize int64, kind cursorKind, cursorOf func(T) pageCursor) pageResponse[T] {
+	resp := pageResponse[T]{Items: items}
+	if int64(len(items)) > pageSize {
+		resp.Items = items[:pageSize]
+		resp.NextCursor = encodeCursor(kind, cursorOf(resp.Items[pageSize-1]))
+	}
+
+	return resp
+}
+
+/**
+ * page_id分页已弃用, 仍然可用但会在响应头中提示
+ */
+func setDeprecatedPagination(ctx *gin.Context) {
+	ctx.Header("Deprecation", "true")
+}
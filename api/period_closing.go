@@ -0,0 +1,231 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * ClosedUntil和PreviousClosedUntil为空表示没有关账
+ */
+type periodClosingResponse struct {
+	ID                  int64                    `json:"id"`
+	AccountId           int64                    `json:"account_id"`
+	Action              util.PeriodClosingAction `json:"action"`
+	ClosedUntil         string                   `json:"closed_until"`
+	PreviousClosedUntil string                   `json:"previous_closed_until"`
+	UserId              int64                    `json:"user_id"`
+	Reason              string                   `json:"reason"`
+	CreateTime          time.Time                `json:"create_time"`
+}
+
+func formatNullDate(date sql.NullTime) string {
+	if !date.Valid {
+		return ""
+	}
+	return date.Time.Format(util.DateFormat)
+}
+
+func newPeriodClosingResponse(closing db.PeriodClosing) periodClosingResponse {
+	return periodClosingResponse{
+		ID:                  closing.ID,
+		AccountId:           closing.AccountID,
+		Action:              closing.Action,
+		ClosedUntil:         formatNullDate(closing.ClosedUntil),
+		PreviousClosedUntil: formatNullDate(closing.PreviousClosedUntil),
+		UserId:              closing.UserID,
+		Reason:              closing.Reason,
+		CreateTime:          closing.CreateTime,
+	}
+}
+
+/**
+ * 批量操作涉及的账单的关账日期, 没有关账的账单不在结果中
+ */
+func (server *Server) getClosedUntils(ctx *gin.Context, accountIds []int64) (map[int64]time.Time, error) {
+	closed := map[int64]time.Time{}
+	checked := map[int64]bool{}
+
+	for _, accountId := range accountIds {
+		if checked[accountId] {
+			continue
+		}
+		checked[accountId] = true
+
+		closedUntil, err := server.db.GetClosedUntil(ctx, accountId)
+		if err != nil {
+			return nil, err
+		}
+		if closedUntil.Valid {
+			closed[accountId] = closedUntil.Time
+		}
+	}
+
+	return closed, nil
+}
+
+/**
+ * 零值的日期不检查
+ */
+func isPeriodClosed(closed map[int64]time.Time, accountId int64, dates ...time.Time) bool {
+	closedUntil, ok := closed[accountId]
+	if !ok {
+		return false
+	}
+
+	for _, date := range dates {
+		if !date.IsZero() && db.IsDateClosed(date, closedUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+type closePeriodRequest struct {
+	AccountId int64     `json:"account_id" binding:"required,min=1"`
+	Date      util.Date `json:"date" binding:"required"`
+	Reason    string    `json:"reason" binding:"max=100"`
+}
+
+/**
+ * 关账到指定日期, 之后该日期及之前的记录不能创建, 修改和删除, 包括审批
+ * 该日期及之前还有待审批的记录时不能关账, 需要先审批这些记录
+ */
+func (server *Server) closePeriod(ctx *gin.Context) {
+	var req closePeriodRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var closing db.PeriodClosing
+	closing, err = server.db.ClosePeriod(ctx, req.AccountId, time.Time(req.Date), authPayload.UserId, req.Reason)
+	if err != nil {
+		if err == db.ErrInvalidClosingDate || err == db.ErrPendingRecords {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPeriodClosingResponse(closing))
+}
+
+type reopenPeriodRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+	// 重新开放后的关账日期, 为空表示完全重新开放
+	Date   *util.Date `json:"date"`
+	Reason string     `json:"reason" binding:"required,max=100"`
+}
+
+/**
+ * 重新开放需要填写原因, 修改完成后可以再次关账
+ */
+func (server *Server) reopenPeriod(ctx *gin.Context) {
+	var req reopenPeriodRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var closedUntil time.Time
+	if req.Date != nil {
+		closedUntil = time.Time(*req.Date)
+	}
+
+	var closing db.PeriodClosing
+	closing, err = server.db.ReopenPeriod(ctx, req.AccountId, closedUntil, authPayload.UserId, req.Reason)
+	if err != nil {
+		if err == db.ErrPeriodNotClosed || err == db.ErrInvalidReopenDate {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPeriodClosingResponse(closing))
+}
+
+type getPeriodClosingsRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+}
+
+type getPeriodClosingsResponse struct {
+	ClosedUntil string                  `json:"closed_until"`
+	History     []periodClosingResponse `json:"history"`
+}
+
+/**
+ * 账单当前的关账日期和关账, 重新开放的历史, 按时间倒序
+ */
+func (server *Server) getPeriodClosings(ctx *gin.Context) {
+	var req getPeriodClosingsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var closings []db.PeriodClosing
+	closings, err = server.db.GetPeriodClosingsByAccountId(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := getPeriodClosingsResponse{History: []periodClosingResponse{}}
+	if len(closings) > 0 {
+		resp.ClosedUntil = formatNullDate(closings[0].ClosedUntil)
+	}
+	for _, closing := range closings {
+		resp.History = append(resp.History, newPeriodClosingResponse(closing))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
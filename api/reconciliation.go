@@ -0,0 +1,386 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+type setOpeningBalanceRequest struct {
+	AccountId int64     `json:"account_id" binding:"required,min=1"`
+	Amount    string    `json:"amount" binding:"required,numeric"`
+	Date      util.Date `json:"date" binding:"required"`
+}
+
+/**
+ * 期初余额是date当天记录发生之前的实际余额, date之前的记录不再计入余额
+ */
+func (server *Server) setOpeningBalance(ctx *gin.Context) {
+	var req setOpeningBalanceRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var opening db.OpeningBalance
+	opening, err = server.db.SetOpeningBalance(ctx, req.AccountId, req.Amount, time.Time(req.Date), authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, opening)
+}
+
+type deleteOpeningBalanceRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+}
+
+/**
+ * 删除后余额从0开始累加所有记录
+ */
+func (server *Server) deleteOpeningBalance(ctx *gin.Context) {
+	var req deleteOpeningBalanceRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	err = server.db.DeleteOpeningBalance(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+type createBalanceCheckpointRequest struct {
+	AccountId int64     `json:"account_id" binding:"required,min=1"`
+	Date      util.Date `json:"date" binding:"required"`
+	// 当天结束时实际的余额, 例如点算现金或银行卡余额得到的值
+	Balance string `json:"balance" binding:"required,numeric"`
+	Note    string `json:"note" binding:"max=100"`
+}
+
+func (server *Server) createBalanceCheckpoint(ctx *gin.Context) {
+	var req createBalanceCheckpointRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var checkpoint db.BalanceCheckpoint
+	checkpoint, err = server.db.CreateBalanceCheckpoint(ctx, req.AccountId, time.Time(req.Date), req.Balance, req.Note, authPayload.UserId)
+	if err != nil {
+		if err == db.ErrCheckpointExists {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, checkpoint)
+}
+
+type getBalanceCheckpointsRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+}
+
+/**
+ * OpeningBalance为空表示没有设置期初余额
+ */
+type getBalanceCheckpointsResponse struct {
+	OpeningBalance *db.OpeningBalance     `json:"opening_balance"`
+	Balance        db.AccountBalance      `json:"balance"`
+	Checkpoints    []db.CheckpointBalance `json:"checkpoints"`
+}
+
+/**
+ * 账单当前的余额和每个检查点的对账结果
+ * 记录计算出的余额为期初余额减去记录金额之和, 记录的金额为正表示支出
+ * Difference是实际余额减去记录计算出的余额, ClearedDifference只计算已核对的记录, 为0时可以对账
+ */
+func (server *Server) getBalanceCheckpoints(ctx *gin.Context) {
+	var req getBalanceCheckpointsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var resp getBalanceCheckpointsResponse
+
+	opening, err := server.db.GetOpeningBalance(ctx, req.AccountId)
+	if err == nil {
+		resp.OpeningBalance = &opening
+	} else if err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp.Balance, err = server.db.GetAccountBalance(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp.Checkpoints, err = server.db.GetCheckpointBalancesByAccountId(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+/**
+ * 查询检查点并检查当前用户是检查点所在账单的管理者, 失败时写入响应
+ */
+func (server *Server) getManagedBalanceCheckpoint(ctx *gin.Context, id int64) (db.BalanceCheckpoint, bool) {
+	checkpoint, err := server.db.GetBalanceCheckpoint(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return checkpoint, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, checkpoint.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return checkpoint, false
+	}
+
+	return checkpoint, true
+}
+
+type deleteBalanceCheckpointRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+/**
+ * 删除检查点不影响已经对账的记录
+ */
+func (server *Server) deleteBalanceCheckpoint(ctx *gin.Context) {
+	var req deleteBalanceCheckpointRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getManagedBalanceCheckpoint(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	err = server.db.DeleteBalanceCheckpoint(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+type reconcileBalanceCheckpointRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+type reconcileBalanceCheckpointResponse struct {
+	ReconciledCount int64 `json:"reconciled_count"`
+}
+
+/**
+ * 已核对的记录计算出的余额与检查点一致时, 将检查点及之前已核对的记录标记为已对账
+ * 已对账的记录不能再修改核对状态, 也不能修改金额和日期, 已核对的记录修改金额或日期后需要重新核对
+ */
+func (server *Server) reconcileBalanceCheckpoint(ctx *gin.Context) {
+	var req reconcileBalanceCheckpointRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getManagedBalanceCheckpoint(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	var count int64
+	count, err = server.db.ReconcileBalanceCheckpoint(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else if err == db.ErrCheckpointNotBalanced {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reconcileBalanceCheckpointResponse{ReconciledCount: count})
+}
+
+type getRunningBalancesRequest struct {
+	AccountId int64  `json:"account_id" binding:"required,min=1"`
+	PageSize  int64  `json:"page_size" binding:"required,min=5,max=20"`
+	Cursor    string `json:"cursor"`
+}
+
+/**
+ * 按日期和id的顺序返回已通过的记录, 以及每条记录之后的余额和已核对部分的余额
+ */
+func (server *Server) getRunningBalances(ctx *gin.Context) {
+	var req getRunningBalancesRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorRunningBalances)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var balances []db.RunningBalance
+	balances, err = server.db.GetRunningBalancesByAccountIdAfterCursor(ctx, req.AccountId, cursor.date(), cursor.ID, req.PageSize+1)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(balances, req.PageSize, cursorRunningBalances, func(balance db.RunningBalance) pageCursor {
+		return pageCursor{Date: balance.Date.Format(util.DateFormat), ID: balance.ID}
+	}))
+}
+
+type updateRecordsClearStatusRequest struct {
+	AccountId   int64   `json:"account_id" binding:"required,min=1"`
+	IDs         []int64 `json:"ids" binding:"required,min=1,max=200,dive,min=1"`
+	ClearStatus int32   `json:"clear_status" binding:"min=0,max=1"`
+}
+
+/**
+ * 把记录标记为已核对或未核对, 已对账的记录会被忽略, 返回修改了的记录
+ */
+func (server *Server) updateRecordsClearStatus(ctx *gin.Context) {
+	var req updateRecordsClearStatusRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var records []db.Record
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		var err error
+		records, err = store.UpdateRecordsClearStatus(ctx, req.AccountId, req.IDs, req.ClearStatus)
+		if err != nil {
+			return nil, err
+		}
+
+		return newRecordEvents(ctx, eventRecordUpdated, records), nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, records)
+}
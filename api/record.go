@@ -40,20 +40,35 @@ func (server *Server) createRecord(ctx *gin.Context) {
 	}
 
 	var record db.Record
-	record, err = server.db.CreateRecord(
-		ctx,
-		req.Name,
-		req.RecordType,
-		time.Time(req.Date),
-		req.Amount,
-		req.AccountId,
-		authPayload.UserId,
-	)
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		var err error
+		record, err = store.CreateRecord(
+			ctx,
+			req.Name,
+			req.RecordType,
+			time.Time(req.Date),
+			req.Amount,
+			req.AccountId,
+			authPayload.UserId,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return []accountEvent{newAccountEvent(ctx, eventRecordCreated, record.AccountID, record)}, nil
+	})
 	if err != nil {
+		if err == db.ErrPeriodClosed {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
 		// TODO: 判定是UserId,AccountId不存在产生的错误还是内部错误
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
+	server.checkBudgetAlerts(ctx, record.AccountID, record.Type, record.CreateUserID, record.Date)
+
 	ctx.JSON(http.StatusOK, record)
 }
 
@@ -92,9 +107,20 @@ func (server *Server) deleteRecord(ctx *gin.Context) {
 		return
 	}
 
-	err = server.db.DeleteRecord(ctx, req.ID)
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		err := store.DeleteRecord(ctx, req.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return []accountEvent{newAccountEvent(ctx, eventRecordDeleted, record.AccountID, record)}, nil
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		if err == db.ErrPeriodClosed {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
 		return
 	}
 
@@ -102,9 +128,11 @@ func (server *Server) deleteRecord(ctx *gin.Context) {
 }
 
 type getRecordsByAccountIdRequest struct {
-	AccountId int64 `json:"account_id" binding:"required,min=1"`
-	PageSize  int64 `json:"page_size" binding:"required,min=5,max=20"`
-	PageId    int64 `json:"page_id" binding:"required,min=1"`
+	AccountId int64  `json:"account_id" binding:"required,min=1"`
+	PageSize  int64  `json:"page_size" binding:"required,min=5,max=20"`
+	Cursor    string `json:"cursor"`
+	// Deprecated: 使用cursor分页
+	PageId int64 `json:"page_id" binding:"omitempty,min=1"`
 }
 
 func (server *Server) getRecordsByAccountId(ctx *gin.Context) {
@@ -128,13 +156,43 @@ func (server *Server) getRecordsByAccountId(ctx *gin.Context) {
 	}
 
 	var records []db.Record
-	offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
-	records, err = server.db.GetRecordsByAccountId(ctx, req.AccountId, offset, limit)
+	if req.PageId != 0 {
+		setDeprecatedPagination(ctx)
+		offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
+		records, err = server.db.GetRecordsByAccountId(ctx, req.AccountId, offset, limit)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, records)
+		return
+	}
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorRecords)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	records, err = server.db.GetRecordsByAccountIdAfterCursor(
+		ctx,
+		req.AccountId,
+		cursor.date(),
+		cursor.ID,
+		req.PageSize+1,
+	)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, records)
+	ctx.JSON(http.StatusOK, newPageResponse(records, req.PageSize, cursorRecords, recordCursor))
+}
+
+func recordCursor(record db.Record) pageCursor {
+	return pageCursor{Date: record.Date.Format(util.DateFormat), ID: record.ID}
 }
 
 type getRecordsCountByAccountIdRequest struct {
@@ -242,19 +300,165 @@ func (server *Server) updateRecord(ctx *gin.Context) {
 		return
 	}
 
-	err = server.db.UpdateRecord(
-		ctx,
-		req.ID,
-		req.Name,
-		req.RecordType,
-		time.Time(req.Date),
-		req.Amount,
-		authPayload.UserId,
-	)
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		updated, err := store.UpdateRecord(
+			ctx,
+			req.ID,
+			req.Name,
+			req.RecordType,
+			time.Time(req.Date),
+			req.Amount,
+			authPayload.UserId,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return []accountEvent{newAccountEvent(ctx, eventRecordUpdated, record.AccountID, updated)}, nil
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		if err == db.ErrPeriodClosed || err == db.ErrRecordReconciled {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
 		return
 	}
 
+	server.checkBudgetAlerts(ctx, record.AccountID, req.RecordType, record.CreateUserID, time.Time(req.Date))
+
 	ctx.JSON(http.StatusOK, nil)
 }
+
+type searchRecordsRequest struct {
+	AccountId          int64             `json:"account_id" binding:"required,min=1"`
+	StartDate          *util.Date        `json:"start_date"`
+	EndDate            *util.Date        `json:"end_date"`
+	RecordTypes        []util.RecordType `json:"record_types" binding:"dive,min=1,max=7"`
+	MinAmount          string            `json:"min_amount" binding:"omitempty,numeric"`
+	MaxAmount          string            `json:"max_amount" binding:"omitempty,numeric"`
+	CreateUserId       int64             `json:"create_user_id" binding:"omitempty,min=1"`
+	LastModifiedUserId int64             `json:"last_modified_user_id" binding:"omitempty,min=1"`
+	Name               string            `json:"name" binding:"max=15"`
+	Query              string            `json:"query" binding:"max=50"`
+	SortBy             string            `json:"sort_by" binding:"omitempty,oneof=date amount create_time"`
+	SortOrder          string            `json:"sort_order" binding:"omitempty,oneof=asc desc"`
+	PageSize           int64             `json:"page_size" binding:"required,min=5,max=20"`
+	Cursor             string            `json:"cursor"`
+	// Deprecated: 使用cursor分页
+	PageId int64 `json:"page_id" binding:"omitempty,min=1"`
+}
+
+/**
+ * NextCursor只在按cursor分页时返回, 为空表示没有下一页
+ */
+type searchRecordsResponse struct {
+	Records    []db.Record `json:"records"`
+	Total      int64       `json:"total"`
+	AmountSum  string      `json:"amount_sum"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+func (server *Server) searchRecords(ctx *gin.Context) {
+	var req searchRecordsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	filter := db.RecordFilter{
+		AccountId:          req.AccountId,
+		RecordTypes:        req.RecordTypes,
+		MinAmount:          req.MinAmount,
+		MaxAmount:          req.MaxAmount,
+		CreateUserId:       req.CreateUserId,
+		LastModifiedUserId: req.LastModifiedUserId,
+		Name:               req.Name,
+		Query:              req.Query,
+	}
+	if req.StartDate != nil {
+		filter.StartDate = time.Time(*req.StartDate)
+	}
+	if req.EndDate != nil {
+		filter.EndDate = time.Time(*req.EndDate)
+	}
+
+	sortBy := req.SortBy
+	if sortBy == "" {
+		sortBy = "date"
+	}
+	sortDesc := req.SortOrder != "asc"
+
+	var records []db.Record
+	var summary db.RecordsSummary
+	if req.PageId != 0 {
+		setDeprecatedPagination(ctx)
+		offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
+		records, summary, err = server.db.SearchRecords(ctx, filter, sortBy, sortDesc, offset, limit)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		resp := searchRecordsResponse{
+			Records:   records,
+			Total:     summary.Total,
+			AmountSum: summary.AmountSum,
+		}
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	kind := searchRecordsCursorKind(sortBy, sortDesc)
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, kind)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	after := db.RecordSearchCursor{
+		Date:       cursor.date(),
+		Amount:     cursor.Amount,
+		CreateTime: cursor.time(),
+		ID:         cursor.ID,
+	}
+	records, summary, err = server.db.SearchRecordsAfterCursor(ctx, filter, sortBy, sortDesc, after, req.PageSize+1)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	page := newPageResponse(records, req.PageSize, kind, func(record db.Record) pageCursor {
+		cursor := recordCursor(record)
+		if sortBy == "amount" {
+			cursor.Amount = record.Amount
+		}
+		if sortBy == "create_time" {
+			cursor.Time = record.CreateTime.Format(time.RFC3339Nano)
+		}
+		return cursor
+	})
+
+	resp := searchRecordsResponse{
+		Records:    page.Items,
+		Total:      summary.Total,
+		AmountSum:  summary.AmountSum,
+		NextCursor: page.NextCursor,
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,414 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"math/big"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+var errInvalidMinAmount = errors.New("min_amount must not be negative")
+
+/**
+ * 审批规则的金额下限与记录金额的绝对值比较, 同时适用于收入和支出
+ */
+func checkMinAmount(minAmount string) error {
+	amount, ok := new(big.Rat).SetString(minAmount)
+	if !ok || amount.Sign() < 0 {
+		return errInvalidMinAmount
+	}
+	return nil
+}
+
+type createApprovalRuleRequest struct {
+	AccountId int64  `json:"account_id" binding:"required,min=1"`
+	MinAmount string `json:"min_amount" binding:"required,numeric"`
+	// 为空表示所有类型
+	Types []int32 `json:"types" binding:"max=7,dive,min=1,max=7"`
+}
+
+/**
+ * 管理者创建或修改的记录匹配任一规则时进入等待审批状态, 规则只对之后的变更生效
+ */
+func (server *Server) createApprovalRule(ctx *gin.Context) {
+	var req createApprovalRuleRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = checkMinAmount(req.MinAmount)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var rule db.ApprovalRule
+	rule, err = server.db.CreateApprovalRule(ctx, req.AccountId, req.MinAmount, uniqueTypes(req.Types), authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rule)
+}
+
+type getApprovalRulesRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+}
+
+func (server *Server) getApprovalRules(ctx *gin.Context) {
+	var req getApprovalRulesRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var rules []db.ApprovalRule
+	rules, err = server.db.GetApprovalRulesByAccountId(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rules)
+}
+
+/**
+ * 查询审批规则并检查当前用户是规则所在账单的拥有者, 失败时写入响应
+ */
+func (server *Server) getOwnedApprovalRule(ctx *gin.Context, id int64) (db.ApprovalRule, bool) {
+	rule, err := server.db.GetApprovalRule(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return rule, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, rule.AccountID, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return rule, false
+	}
+
+	return rule, true
+}
+
+type updateApprovalRuleRequest struct {
+	ID        int64   `json:"id" binding:"required,min=1"`
+	MinAmount string  `json:"min_amount" binding:"required,numeric"`
+	Types     []int32 `json:"types" binding:"max=7,dive,min=1,max=7"`
+}
+
+func (server *Server) updateApprovalRule(ctx *gin.Context) {
+	var req updateApprovalRuleRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = checkMinAmount(req.MinAmount)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getOwnedApprovalRule(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	var rule db.ApprovalRule
+	rule, err = server.db.UpdateApprovalRule(ctx, req.ID, req.MinAmount, uniqueTypes(req.Types))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rule)
+}
+
+type deleteApprovalRuleRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+/**
+ * 删除规则不影响已经在等待审批的记录
+ */
+func (server *Server) deleteApprovalRule(ctx *gin.Context) {
+	var req deleteApprovalRuleRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getOwnedApprovalRule(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	err = server.db.DeleteApprovalRule(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+type getPendingRecordsRequest struct {
+	AccountId int64  `json:"account_id" binding:"required,min=1"`
+	PageSize  int64  `json:"page_size" binding:"required,min=5,max=20"`
+	Cursor    string `json:"cursor"`
+}
+
+/**
+ * 账单中等待审批的记录, 按提交顺序
+ */
+func (server *Server) getPendingRecords(ctx *gin.Context) {
+	var req getPendingRecordsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorPendingRecords)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var records []db.Record
+	records, err = server.db.GetPendingRecordsByAccountIdAfterCursor(ctx, req.AccountId, cursor.ID, req.PageSize+1)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(records, req.PageSize, cursorPendingRecords, func(record db.Record) pageCursor {
+		return pageCursor{ID: record.ID}
+	}))
+}
+
+type approveRecordRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+func (server *Server) approveRecord(ctx *gin.Context) {
+	var req approveRecordRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.reviewRecord(ctx, req.ID, util.ApprovalStatusApproved, "")
+}
+
+type rejectRecordRequest struct {
+	ID     int64  `json:"id" binding:"required,min=1"`
+	Reason string `json:"reason" binding:"required,max=100"`
+}
+
+/**
+ * 被驳回的记录保留在账单中但不计入统计, 管理者修改金额或类型后重新判定是否需要审批
+ */
+func (server *Server) rejectRecord(ctx *gin.Context) {
+	var req rejectRecordRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.reviewRecord(ctx, req.ID, util.ApprovalStatusRejected, req.Reason)
+}
+
+/**
+ * 1. 检查当前用户是记录所在账单的拥有者
+ * 2. 修改审批状态并写入审批历史, 关账期间内的记录不能审批
+ * 3. 通过的记录计入预算, 通知提交者并发布记录修改事件
+ */
+func (server *Server) reviewRecord(ctx *gin.Context, id int64, status util.ApprovalStatus, reason string) {
+	record, err := server.db.GetRecord(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, record.AccountID, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		var err error
+		record, err = store.ReviewRecord(ctx, id, status, authPayload.UserId, reason)
+		if err != nil {
+			return nil, err
+		}
+
+		return []accountEvent{newAccountEvent(ctx, eventRecordUpdated, record.AccountID, record)}, nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else if err == db.ErrRecordNotPending || err == db.ErrPeriodClosed {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	if status == util.ApprovalStatusApproved {
+		server.checkBudgetAlerts(ctx, record.AccountID, record.Type, record.CreateUserID, record.Date)
+	}
+
+	notificationType := notificationRecordApproved
+	if status == util.ApprovalStatusRejected {
+		notificationType = notificationRecordRejected
+	}
+
+	account, err := server.db.GetAccount(ctx, record.AccountID)
+	if err != nil {
+		_ = ctx.Error(err)
+	} else {
+		server.notify(ctx, notificationType, []int64{record.LastModifiedUserID}, notificationData{
+			AccountId:   account.ID,
+			AccountName: account.Name,
+			RecordId:    record.ID,
+			RecordName:  record.Name,
+			Reason:      reason,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, record)
+}
+
+type getRecordApprovalsRequest struct {
+	RecordId int64 `json:"record_id" binding:"required,min=1"`
+}
+
+/**
+ * 记录的审批历史, 包括提交, 自动重新判定和拥有者的审批
+ */
+func (server *Server) getRecordApprovals(ctx *gin.Context) {
+	var req getRecordApprovalsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var record db.Record
+	record, err = server.db.GetRecord(ctx, req.RecordId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, record.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var approvals []db.RecordApproval
+	approvals, err = server.db.GetRecordApprovalsByRecordId(ctx, req.RecordId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, approvals)
+}
+
+func uniqueTypes(types []int32) []int32 {
+	seen := make(map[int32]bool, len(types))
+	res := []int32{}
+	for _, t := range types {
+		if !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	return res
+}
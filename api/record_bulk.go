@@ -0,0 +1,487 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 批量操作的模式
+ * atomic: 任意一条记录校验失败时不写入任何记录
+ * best_effort: 跳过校验失败的记录, 写入其余记录
+ *   写入时才被触发器拒绝的记录(例如校验之后账单被关账)同样单独标记为失败
+ */
+const (
+	bulkModeAtomic     = "atomic"
+	bulkModeBestEffort = "best_effort"
+)
+
+var (
+	errRecordNotFound    = errors.New("record not found")
+	errDuplicateRecordId = errors.New("duplicate record id")
+)
+
+type bulkItemResult struct {
+	Index  int        `json:"index"`
+	Record *db.Record `json:"record,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type bulkResponse struct {
+	Results   []bulkItemResult `json:"results"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+
+	// atomic模式下整个请求失败时返回的状态码
+	status int
+}
+
+func newBulkResponse(n int) *bulkResponse {
+	resp := &bulkResponse{
+		Results: make([]bulkItemResult, n),
+		status:  http.StatusBadRequest,
+	}
+	for i := range resp.Results {
+		resp.Results[i].Index = i
+	}
+
+	return resp
+}
+
+func (resp *bulkResponse) fail(i int, err error) {
+	if resp.Results[i].Error != "" {
+		return
+	}
+
+	resp.Results[i].Error = err.Error()
+	resp.Failed++
+
+	switch err {
+	case errAccessDenied:
+		resp.status = http.StatusForbidden
+	case errRecordNotFound:
+		if resp.status != http.StatusForbidden {
+			resp.status = http.StatusNotFound
+		}
+	case db.ErrPeriodClosed, db.ErrRecordReconciled:
+		if resp.status == http.StatusBadRequest {
+			resp.status = http.StatusConflict
+		}
+	}
+}
+
+func (resp *bulkResponse) ok(i int) bool {
+	return resp.Results[i].Error == ""
+}
+
+func (resp *bulkResponse) succeed(i int, record db.Record) {
+	resp.Results[i].Record = &record
+	resp.Succeeded++
+}
+
+/**
+ * 写入时被记录的触发器拒绝的错误, 见db.translateError
+ */
+func isRejectedByTrigger(err error) bool {
+	return err == db.ErrPeriodClosed || err == db.ErrRecordReconciled
+}
+
+/**
+ * best_effort模式下整批写入被记录的触发器拒绝时改为逐条写入
+ * 每条记录在各自的保存点中写入, 被拒绝的记录标记为失败, 其他错误仍然使整个请求失败
+ */
+func writeEachRecord(resp *bulkResponse, indexes []int, write func(j int) error) error {
+	for j, i := range indexes {
+		err := write(j)
+		if isRejectedByTrigger(err) {
+			resp.fail(i, err)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+ * 对每个账单只查询一次权限, 返回没有权限的账单
+ */
+func (server *Server) checkAccountAccessRules(
+	ctx *gin.Context,
+	userId int64,
+	accountIds []int64,
+	expect util.AccountRole,
+) (map[int64]bool, error) {
+	denied := map[int64]bool{}
+	checked := map[int64]bool{}
+
+	for _, accountId := range accountIds {
+		if checked[accountId] {
+			continue
+		}
+		checked[accountId] = true
+
+		err := server.checkAccountAccessRule(ctx, userId, accountId, expect)
+		if err == errAccessDenied {
+			denied[accountId] = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	return denied, nil
+}
+
+type bulkCreateRecordsRequest struct {
+	Mode    string                `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Records []createRecordRequest `json:"records" binding:"required,min=1,max=200"`
+}
+
+func (server *Server) bulkCreateRecords(ctx *gin.Context) {
+	var req bulkCreateRecordsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	resp := newBulkResponse(len(req.Records))
+	accountIds := []int64{}
+	for i := range req.Records {
+		err = binding.Validator.ValidateStruct(&req.Records[i])
+		if err != nil {
+			resp.fail(i, err)
+			continue
+		}
+		accountIds = append(accountIds, req.Records[i].AccountId)
+	}
+
+	var denied map[int64]bool
+	denied, err = server.checkAccountAccessRules(ctx, authPayload.UserId, accountIds, util.AccountRoleManager)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var closed map[int64]time.Time
+	closed, err = server.getClosedUntils(ctx, accountIds)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	records := []db.NewRecord{}
+	indexes := []int{}
+	for i, item := range req.Records {
+		if resp.ok(i) && denied[item.AccountId] {
+			resp.fail(i, errAccessDenied)
+		}
+		if resp.ok(i) && isPeriodClosed(closed, item.AccountId, time.Time(item.Date)) {
+			resp.fail(i, db.ErrPeriodClosed)
+		}
+		if !resp.ok(i) {
+			continue
+		}
+
+		records = append(records, db.NewRecord{
+			Name:       item.Name,
+			RecordType: item.RecordType,
+			Date:       time.Time(item.Date),
+			Amount:     item.Amount,
+			AccountId:  item.AccountId,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if resp.Failed > 0 && req.Mode != bulkModeBestEffort {
+		ctx.JSON(resp.status, resp)
+		return
+	}
+
+	if len(records) > 0 {
+		var created []db.Record
+		err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+			var err error
+			created, err = store.CreateRecords(ctx, records, authPayload.UserId)
+			if isRejectedByTrigger(err) && req.Mode == bulkModeBestEffort {
+				created = nil
+				err = writeEachRecord(resp, indexes, func(j int) error {
+					res, err := store.CreateRecords(ctx, records[j:j+1], authPayload.UserId)
+					created = append(created, res...)
+					return err
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			return newRecordEvents(ctx, eventRecordCreated, created), nil
+		})
+		if err != nil {
+			if err == db.ErrPeriodClosed {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+			} else {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			}
+			return
+		}
+
+		j := 0
+		for _, i := range indexes {
+			if resp.ok(i) {
+				resp.succeed(i, created[j])
+				j++
+			}
+		}
+		server.checkRecordsBudgetAlerts(ctx, created)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+/**
+ * 查询批量操作涉及的记录并检查权限, 不存在, 没有权限或日期已关账的记录标记为失败
+ */
+func (server *Server) loadRecordsForBulk(
+	ctx *gin.Context,
+	userId int64,
+	ids []int64,
+	dates []time.Time,
+	resp *bulkResponse,
+) (map[int64]db.Record, error) {
+	records, err := server.db.GetRecordsByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := map[int64]db.Record{}
+	accountIds := []int64{}
+	for _, record := range records {
+		byId[record.ID] = record
+		accountIds = append(accountIds, record.AccountID)
+	}
+
+	var denied map[int64]bool
+	denied, err = server.checkAccountAccessRules(ctx, userId, accountIds, util.AccountRoleManager)
+	if err != nil {
+		return nil, err
+	}
+
+	var closed map[int64]time.Time
+	closed, err = server.getClosedUntils(ctx, accountIds)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[int64]bool{}
+	for i, id := range ids {
+		record, ok := byId[id]
+		switch {
+		case seen[id]:
+			resp.fail(i, errDuplicateRecordId)
+		case !ok:
+			resp.fail(i, errRecordNotFound)
+		case denied[record.AccountID]:
+			resp.fail(i, errAccessDenied)
+		case isPeriodClosed(closed, record.AccountID, record.Date, dates[i]):
+			resp.fail(i, db.ErrPeriodClosed)
+		}
+		seen[id] = true
+	}
+
+	return byId, nil
+}
+
+type bulkUpdateRecordsRequest struct {
+	Mode    string                `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Records []updateRecordRequest `json:"records" binding:"required,min=1,max=200"`
+}
+
+func (server *Server) bulkUpdateRecords(ctx *gin.Context) {
+	var req bulkUpdateRecordsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	resp := newBulkResponse(len(req.Records))
+	ids := make([]int64, len(req.Records))
+	dates := make([]time.Time, len(req.Records))
+	for i := range req.Records {
+		ids[i] = req.Records[i].ID
+		dates[i] = time.Time(req.Records[i].Date)
+		err = binding.Validator.ValidateStruct(&req.Records[i])
+		if err != nil {
+			resp.fail(i, err)
+		}
+	}
+
+	var records map[int64]db.Record
+	records, err = server.loadRecordsForBulk(ctx, authPayload.UserId, ids, dates, resp)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	for i, item := range req.Records {
+		record, ok := records[item.ID]
+		if ok && db.IsReconciledRecordChanged(record, time.Time(item.Date), item.Amount) {
+			resp.fail(i, db.ErrRecordReconciled)
+		}
+	}
+
+	if resp.Failed > 0 && req.Mode != bulkModeBestEffort {
+		ctx.JSON(resp.status, resp)
+		return
+	}
+
+	changes := []db.RecordChange{}
+	indexes := []int{}
+	for i, item := range req.Records {
+		if !resp.ok(i) {
+			continue
+		}
+
+		changes = append(changes, db.RecordChange{
+			ID:         item.ID,
+			Name:       item.Name,
+			RecordType: item.RecordType,
+			Date:       time.Time(item.Date),
+			Amount:     item.Amount,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if len(changes) > 0 {
+		var updated []db.Record
+		err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+			var err error
+			updated, err = store.UpdateRecords(ctx, changes, authPayload.UserId)
+			if isRejectedByTrigger(err) && req.Mode == bulkModeBestEffort {
+				updated = nil
+				err = writeEachRecord(resp, indexes, func(j int) error {
+					res, err := store.UpdateRecords(ctx, changes[j:j+1], authPayload.UserId)
+					updated = append(updated, res...)
+					return err
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			return newRecordEvents(ctx, eventRecordUpdated, updated), nil
+		})
+		if err != nil {
+			if err == db.ErrPeriodClosed || err == db.ErrRecordReconciled {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+			} else {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			}
+			return
+		}
+
+		byId := map[int64]db.Record{}
+		for _, record := range updated {
+			byId[record.ID] = record
+		}
+		for _, i := range indexes {
+			if resp.ok(i) {
+				resp.succeed(i, byId[req.Records[i].ID])
+			}
+		}
+		server.checkRecordsBudgetAlerts(ctx, updated)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type bulkDeleteRecordsRequest struct {
+	Mode string  `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	IDs  []int64 `json:"ids" binding:"required,min=1,max=200,dive,min=1"`
+}
+
+func (server *Server) bulkDeleteRecords(ctx *gin.Context) {
+	var req bulkDeleteRecordsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	resp := newBulkResponse(len(req.IDs))
+
+	var records map[int64]db.Record
+	// 删除只检查记录原来的日期
+	records, err = server.loadRecordsForBulk(ctx, authPayload.UserId, req.IDs, make([]time.Time, len(req.IDs)), resp)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if resp.Failed > 0 && req.Mode != bulkModeBestEffort {
+		ctx.JSON(resp.status, resp)
+		return
+	}
+
+	ids := []int64{}
+	indexes := []int{}
+	for i, id := range req.IDs {
+		if resp.ok(i) {
+			ids = append(ids, id)
+			indexes = append(indexes, i)
+		}
+	}
+
+	if len(ids) > 0 {
+		err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+			err := store.DeleteRecords(ctx, ids)
+			if isRejectedByTrigger(err) && req.Mode == bulkModeBestEffort {
+				err = writeEachRecord(resp, indexes, func(j int) error {
+					return store.DeleteRecords(ctx, ids[j:j+1])
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			deleted := []db.Record{}
+			for _, i := range indexes {
+				if resp.ok(i) {
+					deleted = append(deleted, records[req.IDs[i]])
+				}
+			}
+
+			return newRecordEvents(ctx, eventRecordDeleted, deleted), nil
+		})
+		if err != nil {
+			if err == db.ErrPeriodClosed {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+			} else {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			}
+			return
+		}
+
+		for i, id := range req.IDs {
+			if resp.ok(i) {
+				resp.succeed(i, records[id])
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
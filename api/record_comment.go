@@ -0,0 +1,370 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+var (
+	errEmptyComment     = errors.New("empty comment")
+	errNotCommentAuthor = errors.New("only the author can modify the comment")
+	errCommentDeleted   = errors.New("comment has been deleted")
+)
+
+/**
+ * 删除的评论保留在列表中, Content为空, Deleted为true
+ */
+type commentResponse struct {
+	ID             int64      `json:"id"`
+	RecordId       int64      `json:"record_id"`
+	AccountId      int64      `json:"account_id"`
+	ParentId       int64      `json:"parent_id"`
+	UserId         int64      `json:"user_id"`
+	Content        string     `json:"content"`
+	MentionUserIds []int64    `json:"mention_user_ids"`
+	CreateTime     time.Time  `json:"create_time"`
+	UpdateTime     *time.Time `json:"update_time"`
+	Deleted        bool       `json:"deleted"`
+}
+
+func newCommentResponse(comment db.RecordComment) commentResponse {
+	resp := commentResponse{
+		ID:             comment.ID,
+		RecordId:       comment.RecordID,
+		AccountId:      comment.AccountID,
+		ParentId:       comment.ParentID.Int64,
+		UserId:         comment.UserID,
+		Content:        comment.Content,
+		MentionUserIds: comment.MentionUserIds,
+		CreateTime:     comment.CreateTime,
+		Deleted:        comment.DeleteTime.Valid,
+	}
+	if comment.UpdateTime.Valid {
+		resp.UpdateTime = &comment.UpdateTime.Time
+	}
+	if resp.MentionUserIds == nil {
+		resp.MentionUserIds = []int64{}
+	}
+
+	return resp
+}
+
+func newCommentResponses(comments []db.RecordComment) []commentResponse {
+	resp := make([]commentResponse, len(comments))
+	for i, comment := range comments {
+		resp[i] = newCommentResponse(comment)
+	}
+
+	return resp
+}
+
+/**
+ * 去掉重复的@用户, 保持原有顺序
+ */
+func uniqueIds(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	res := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+
+	return res
+}
+
+type createRecordCommentRequest struct {
+	RecordId       int64   `json:"record_id" binding:"required,min=1"`
+	ParentId       int64   `json:"parent_id" binding:"omitempty,min=1"`
+	Content        string  `json:"content" binding:"required,max=2000"`
+	MentionUserIds []int64 `json:"mention_user_ids" binding:"max=20,dive,min=1"`
+}
+
+/**
+ * 评论的权限与所属的账单记录相同
+ */
+func (server *Server) createRecordComment(ctx *gin.Context) {
+	var req createRecordCommentRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errEmptyComment))
+		return
+	}
+
+	var record db.Record
+	record, err = server.db.GetRecord(ctx, req.RecordId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, record.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var comment db.RecordComment
+	comment, err = server.db.CreateRecordComment(
+		ctx,
+		record,
+		req.ParentId,
+		authPayload.UserId,
+		content,
+		uniqueIds(req.MentionUserIds),
+	)
+	if err != nil {
+		if err == db.ErrInvalidCommentParent || err == db.ErrInvalidMention {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+type getRecordCommentsRequest struct {
+	RecordId int64 `json:"record_id" binding:"required,min=1"`
+}
+
+/**
+ * 按发表顺序返回记录的所有评论, 客户端按parent_id组织回复
+ */
+func (server *Server) getRecordComments(ctx *gin.Context) {
+	var req getRecordCommentsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var record db.Record
+	record, err = server.db.GetRecord(ctx, req.RecordId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, record.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var comments []db.RecordComment
+	comments, err = server.db.GetRecordCommentsByRecordId(ctx, req.RecordId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponses(comments))
+}
+
+/**
+ * 查询评论并检查当前用户是评论的作者且仍有所在账单的权限, 失败时写入响应
+ */
+func (server *Server) getOwnComment(ctx *gin.Context, id int64) (db.RecordComment, bool) {
+	comment, err := server.db.GetRecordComment(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return db.RecordComment{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, comment.AccountID, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return db.RecordComment{}, false
+	}
+
+	if comment.UserID != authPayload.UserId {
+		ctx.JSON(http.StatusForbidden, errorResponse(errNotCommentAuthor))
+		return db.RecordComment{}, false
+	}
+
+	return comment, true
+}
+
+type updateRecordCommentRequest struct {
+	ID             int64   `json:"id" binding:"required,min=1"`
+	Content        string  `json:"content" binding:"required,max=2000"`
+	MentionUserIds []int64 `json:"mention_user_ids" binding:"max=20,dive,min=1"`
+}
+
+func (server *Server) updateRecordComment(ctx *gin.Context) {
+	var req updateRecordCommentRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errEmptyComment))
+		return
+	}
+
+	comment, ok := server.getOwnComment(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	if comment.DeleteTime.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(errCommentDeleted))
+		return
+	}
+
+	comment, err = server.db.UpdateRecordComment(ctx, comment, content, uniqueIds(req.MentionUserIds))
+	if err != nil {
+		if err == db.ErrInvalidMention {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+type deleteRecordCommentRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+/**
+ * 已经删除的评论视为删除成功
+ */
+func (server *Server) deleteRecordComment(ctx *gin.Context) {
+	var req deleteRecordCommentRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	comment, ok := server.getOwnComment(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	if !comment.DeleteTime.Valid {
+		comment, err = server.db.DeleteRecordComment(ctx, comment.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+type unreadCommentsCountResponse struct {
+	AccountId    int64 `json:"account_id"`
+	UnreadCount  int64 `json:"unread_count"`
+	MentionCount int64 `json:"mention_count"`
+}
+
+/**
+ * 当前用户所在的每个账单中其他成员发表的未读评论数, 以及其中@当前用户的数量
+ */
+func (server *Server) getUnreadCommentsCounts(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	counts, err := server.db.GetUnreadCommentsCounts(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]unreadCommentsCountResponse, len(counts))
+	for i, count := range counts {
+		resp[i] = unreadCommentsCountResponse{
+			AccountId:    count.AccountID,
+			UnreadCount:  count.UnreadCount,
+			MentionCount: count.MentionCount,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type markCommentsReadRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+}
+
+func (server *Server) markCommentsRead(ctx *gin.Context) {
+	var req markCommentsReadRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	err = server.db.MarkCommentsRead(ctx, authPayload.UserId, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,523 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/exporter"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 导出文件中可选的列, category是记录类型的名称
+ */
+var (
+	defaultExportColumns = []string{"date", "name", "category", "amount"}
+
+	csvNumberPattern = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+
+	exportColumnValues = map[string]func(db.Record) string{
+		"id":                    func(r db.Record) string { return strconv.FormatInt(r.ID, 10) },
+		"date":                  func(r db.Record) string { return r.Date.Format(util.DateFormat) },
+		"name":                  func(r db.Record) string { return r.Name },
+		"type":                  func(r db.Record) string { return strconv.Itoa(int(r.Type)) },
+		"category":              func(r db.Record) string { return util.RecordTypeName(r.Type) },
+		"amount":                func(r db.Record) string { return r.Amount },
+		"create_user_id":        func(r db.Record) string { return strconv.FormatInt(r.CreateUserID, 10) },
+		"last_modified_user_id": func(r db.Record) string { return strconv.FormatInt(r.LastModifiedUserID, 10) },
+		"create_time":           func(r db.Record) string { return r.CreateTime.Format(time.RFC3339) },
+		"approval_status":       func(r db.Record) string { return util.ApprovalStatusName(r.ApprovalStatus) },
+		"clear_status":          func(r db.Record) string { return util.ClearStatusName(r.ClearStatus) },
+	}
+)
+
+/**
+ * 检查导出请求的日期范围, 返回按时区换算后的日期, 未设置的日期为零值
+ */
+func exportDateRange(startDate *util.Date, endDate *util.Date, timeZone string) (time.Time, time.Time, error) {
+	var start, end time.Time
+
+	loc, err := loadLocation(timeZone)
+	if err != nil {
+		return start, end, err
+	}
+
+	if startDate != nil {
+		start = dateIn(time.Time(*startDate), loc)
+	}
+	if endDate != nil {
+		end = dateIn(time.Time(*endDate), loc)
+	}
+	if startDate != nil && endDate != nil && end.Before(start) {
+		return start, end, errInvalidDateRange
+	}
+
+	return start, end, nil
+}
+
+func setAttachmentHeaders(ctx *gin.Context, contentType string, filename string) {
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+}
+
+type exportRecordsCSVRequest struct {
+	AccountId int64      `json:"account_id" binding:"required,min=1"`
+	Columns   []string   `json:"columns" binding:"max=9,dive,oneof=id date name type category amount create_user_id last_modified_user_id create_time"`
+	StartDate *util.Date `json:"start_date"`
+	EndDate   *util.Date `json:"end_date"`
+	// IANA时区名, 默认使用服务器所在时区
+	TimeZone string `json:"time_zone"`
+	// 写入UTF-8 BOM, 使Excel能正确识别编码
+	BOM bool `json:"bom"`
+}
+
+/**
+ * 按日期顺序分页读取记录并逐行写入响应, 不在内存中保留整个文件
+ * 开始写入后无法再修改状态码, 中途出错时只能中断响应
+ */
+func (server *Server) exportRecordsCSV(ctx *gin.Context) {
+	var req exportRecordsCSVRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var startDate, endDate time.Time
+	startDate, endDate, err = exportDateRange(req.StartDate, req.EndDate, req.TimeZone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	columns := req.Columns
+	if len(columns) == 0 {
+		columns = defaultExportColumns
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	setAttachmentHeaders(ctx, "text/csv; charset=utf-8", fmt.Sprintf("account-%d.csv", req.AccountId))
+	ctx.Status(http.StatusOK)
+
+	if req.BOM {
+		_, _ = ctx.Writer.WriteString("\xef\xbb\xbf")
+	}
+
+	writer := csv.NewWriter(ctx.Writer)
+	err = writer.Write(columns)
+	if err == nil {
+		values := make([]string, len(columns))
+		err = server.db.IterateRecordsByAccountId(ctx, req.AccountId, startDate, endDate, func(record db.Record) error {
+			for i, column := range columns {
+				values[i] = escapeCSVFormula(exportColumnValues[column](record))
+			}
+			return writer.Write(values)
+		})
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		_ = ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+/**
+ * 以=, +, -, @, 制表符或回车开头的单元格会被电子表格当作公式执行, 在前面加上单引号
+ * 金额等数字不会被当作公式, 保持原样以便电子表格按数字处理
+ */
+func escapeCSVFormula(value string) string {
+	if value == "" || !strings.ContainsRune("=+-@\t\r", rune(value[0])) || csvNumberPattern.MatchString(value) {
+		return value
+	}
+	return "'" + value
+}
+
+/**
+ * 查询记录创建者的名称, 已删除的用户以#id表示
+ */
+func (server *Server) userNames(ctx *gin.Context, records []db.Record) (map[int64]string, error) {
+	names := map[int64]string{}
+	ids := []int64{}
+	for _, record := range records {
+		if _, ok := names[record.CreateUserID]; ok {
+			continue
+		}
+		names[record.CreateUserID] = fmt.Sprintf("#%d", record.CreateUserID)
+		ids = append(ids, record.CreateUserID)
+	}
+
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	users, err := server.db.GetUsersByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+
+	return names, nil
+}
+
+/**
+ * 读取导出范围内的所有记录, 按日期排序
+ */
+func (server *Server) loadRecordsForExport(
+	ctx *gin.Context,
+	accountId int64,
+	startDate time.Time,
+	endDate time.Time,
+) ([]db.Record, error) {
+	records := []db.Record{}
+	err := server.db.IterateRecordsByAccountId(ctx, accountId, startDate, endDate, func(record db.Record) error {
+		records = append(records, record)
+		return nil
+	})
+
+	return records, err
+}
+
+func addAmount(sums map[string]*big.Rat, key string, amount string) {
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return
+	}
+	if sums[key] == nil {
+		sums[key] = new(big.Rat)
+	}
+	sums[key].Add(sums[key], value)
+}
+
+/**
+ * 减去金额, 用于把以负数记录的收入累加为正数
+ */
+func subAmount(sums map[string]*big.Rat, key string, amount string) {
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return
+	}
+	if sums[key] == nil {
+		sums[key] = new(big.Rat)
+	}
+	sums[key].Sub(sums[key], value)
+}
+
+func sumOf(sums map[string]*big.Rat, key string) string {
+	if sums[key] == nil {
+		return "0"
+	}
+	return sums[key].FloatString(2)
+}
+
+type exportRecordsXLSXRequest struct {
+	AccountId int64      `json:"account_id" binding:"required,min=1"`
+	StartDate *util.Date `json:"start_date"`
+	EndDate   *util.Date `json:"end_date"`
+	// IANA时区名, 默认使用服务器所在时区
+	TimeZone string `json:"time_zone"`
+}
+
+/**
+ * 导出xlsx工作簿, 包含三个工作表
+ * 1. Records: 所有记录, 日期和金额使用Excel的日期和数字类型
+ * 2. By Category: 按类型和月份汇总的金额, 合计使用公式计算
+ * 3. By Member: 按创建者汇总的收入, 支出和记录数
+ */
+func (server *Server) exportRecordsXLSX(ctx *gin.Context) {
+	var req exportRecordsXLSXRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var startDate, endDate time.Time
+	startDate, endDate, err = exportDateRange(req.StartDate, req.EndDate, req.TimeZone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var records []db.Record
+	records, err = server.loadRecordsForExport(ctx, req.AccountId, startDate, endDate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var names map[int64]string
+	names, err = server.userNames(ctx, records)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	wb := exporter.NewWorkbook()
+
+	raw := wb.AddSheet("Records")
+	raw.AddRow(exporter.Header("Date"), exporter.Header("Name"), exporter.Header("Category"), exporter.Header("Amount"), exporter.Header("Creator"), exporter.Header("Approval"))
+	for _, record := range records {
+		raw.AddRow(
+			exporter.Date(record.Date),
+			exporter.Text(record.Name),
+			exporter.Text(util.RecordTypeName(record.Type)),
+			exporter.Number(record.Amount),
+			exporter.Text(names[record.CreateUserID]),
+			exporter.Text(util.ApprovalStatusName(record.ApprovalStatus)),
+		)
+	}
+	// 只汇总已通过审批的记录
+	lastRow := raw.NextRow() - 1
+	raw.AddRow(
+		exporter.Header("Total"),
+		exporter.Text(""),
+		exporter.Text(""),
+		exporter.Formula(fmt.Sprintf("SUMIF(F2:F%d,\"%s\",D2:D%d)", lastRow, util.ApprovalStatusName(util.ApprovalStatusApproved), lastRow)),
+	)
+
+	// 没有指定日期范围时使用记录的日期范围
+	if len(records) > 0 {
+		if startDate.IsZero() {
+			startDate = records[0].Date
+		}
+		if endDate.IsZero() {
+			endDate = records[len(records)-1].Date
+		}
+	}
+
+	months := []string{}
+	if !startDate.IsZero() {
+		for month := truncateDate(startDate, statisticsIntervalMonth); !month.After(endDate); month = month.AddDate(0, 1, 0) {
+			months = append(months, month.Format("2006-01"))
+		}
+	}
+
+	byCategory := map[string]*big.Rat{}
+	recordTypes := map[util.RecordType]bool{}
+	for _, record := range records {
+		if record.ApprovalStatus != util.ApprovalStatusApproved {
+			continue
+		}
+		recordTypes[record.Type] = true
+		addAmount(byCategory, fmt.Sprintf("%d|%s", record.Type, record.Date.Format("2006-01")), record.Amount)
+	}
+
+	sortedTypes := []util.RecordType{}
+	for recordType := range recordTypes {
+		sortedTypes = append(sortedTypes, recordType)
+	}
+	sort.Slice(sortedTypes, func(i, j int) bool { return sortedTypes[i] < sortedTypes[j] })
+
+	summary := wb.AddSheet("By Category")
+	header := []exporter.Cell{exporter.Header("Category")}
+	for _, month := range months {
+		header = append(header, exporter.Header(month))
+	}
+	summary.AddRow(append(header, exporter.Header("Total"))...)
+
+	lastMonthColumn := exporter.ColumnName(len(months))
+	for _, recordType := range sortedTypes {
+		row := summary.NextRow()
+		cells := []exporter.Cell{exporter.Text(util.RecordTypeName(recordType))}
+		for _, month := range months {
+			cells = append(cells, exporter.Number(sumOf(byCategory, fmt.Sprintf("%d|%s", recordType, month))))
+		}
+		cells = append(cells, exporter.Formula(fmt.Sprintf("SUM(B%d:%s%d)", row, lastMonthColumn, row)))
+		summary.AddRow(cells...)
+	}
+
+	totals := []exporter.Cell{exporter.Header("Total")}
+	for i := 1; i <= len(months)+1; i++ {
+		column := exporter.ColumnName(i)
+		totals = append(totals, exporter.Formula(fmt.Sprintf("SUM(%s2:%s%d)", column, column, summary.NextRow()-1)))
+	}
+	summary.AddRow(totals...)
+
+	income := map[string]*big.Rat{}
+	expense := map[string]*big.Rat{}
+	counts := map[int64]int64{}
+	for _, record := range records {
+		if record.ApprovalStatus != util.ApprovalStatusApproved {
+			continue
+		}
+		key := strconv.FormatInt(record.CreateUserID, 10)
+		if len(record.Amount) > 0 && record.Amount[0] == '-' {
+			subAmount(income, key, record.Amount)
+		} else {
+			addAmount(expense, key, record.Amount)
+		}
+		counts[record.CreateUserID]++
+	}
+
+	userIds := []int64{}
+	for userId := range counts {
+		userIds = append(userIds, userId)
+	}
+	sort.Slice(userIds, func(i, j int) bool { return names[userIds[i]] < names[userIds[j]] })
+
+	members := wb.AddSheet("By Member")
+	members.AddRow(exporter.Header("Member"), exporter.Header("Income"), exporter.Header("Expense"), exporter.Header("Net"), exporter.Header("Count"))
+	for _, userId := range userIds {
+		key := strconv.FormatInt(userId, 10)
+		row := members.NextRow()
+		members.AddRow(
+			exporter.Text(names[userId]),
+			exporter.Number(sumOf(income, key)),
+			exporter.Number(sumOf(expense, key)),
+			exporter.Formula(fmt.Sprintf("B%d-C%d", row, row)),
+			exporter.Integer(counts[userId]),
+		)
+	}
+
+	setAttachmentHeaders(
+		ctx,
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		fmt.Sprintf("account-%d.xlsx", req.AccountId),
+	)
+	ctx.Status(http.StatusOK)
+
+	err = wb.Write(ctx.Writer)
+	if err != nil {
+		_ = ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+/**
+ * 账单没有记录币种, 由请求指定, 默认为人民币
+ * ExpenseAccounts和IncomeAccounts以记录类型为键, 值为账户路径, 如Expenses:Food:Dining
+ */
+type exportRecordsJournalRequest struct {
+	AccountId       int64                      `json:"account_id" binding:"required,min=1"`
+	Format          string                     `json:"format" binding:"required,oneof=beancount ledger"`
+	Currency        string                     `json:"currency" binding:"omitempty,iso4217"`
+	AssetAccount    string                     `json:"asset_account" binding:"max=100"`
+	ExpenseAccounts map[util.RecordType]string `json:"expense_accounts" binding:"dive,keys,min=1,max=7,endkeys,required,max=100"`
+	IncomeAccounts  map[util.RecordType]string `json:"income_accounts" binding:"dive,keys,min=1,max=7,endkeys,required,max=100"`
+	StartDate       *util.Date                 `json:"start_date"`
+	EndDate         *util.Date                 `json:"end_date"`
+	// IANA时区名, 默认使用服务器所在时区
+	TimeZone string `json:"time_zone"`
+}
+
+func (server *Server) exportRecordsJournal(ctx *gin.Context) {
+	var req exportRecordsJournalRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	opts := exporter.JournalOptions{
+		Format:          req.Format,
+		Currency:        req.Currency,
+		AssetAccount:    req.AssetAccount,
+		ExpenseAccounts: req.ExpenseAccounts,
+		IncomeAccounts:  req.IncomeAccounts,
+	}
+	if opts.Currency == "" {
+		opts.Currency = "CNY"
+	}
+
+	err = opts.Validate()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var startDate, endDate time.Time
+	startDate, endDate, err = exportDateRange(req.StartDate, req.EndDate, req.TimeZone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var records []db.Record
+	records, err = server.loadRecordsForExport(ctx, req.AccountId, startDate, endDate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var names map[int64]string
+	names, err = server.userNames(ctx, records)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// 等待审批和被驳回的记录不入账
+	entries := make([]exporter.JournalEntry, 0, len(records))
+	for _, record := range records {
+		if record.ApprovalStatus != util.ApprovalStatusApproved {
+			continue
+		}
+		entries = append(entries, exporter.JournalEntry{
+			ID:         record.ID,
+			Date:       record.Date,
+			Name:       record.Name,
+			RecordType: record.Type,
+			Amount:     record.Amount,
+			Creator:    names[record.CreateUserID],
+		})
+	}
+
+	setAttachmentHeaders(ctx, "text/plain; charset=utf-8", fmt.Sprintf("account-%d.%s", req.AccountId, req.Format))
+	ctx.Status(http.StatusOK)
+
+	err = exporter.WriteJournal(ctx.Writer, entries, opts)
+	if err != nil {
+		_ = ctx.Error(err)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/importer"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+const (
+	maxImportFileSize = 5 << 20
+	maxImportRows     = 5000
+)
+
+var (
+	errMissingImportFile = errors.New("missing import file")
+	errDuplicateRecord   = errors.New("duplicate record")
+)
+
+/**
+ * 导入的每一行的处理结果, RecordId在实际写入后返回
+ */
+type importRowResult struct {
+	Line       int             `json:"line"`
+	Name       string          `json:"name"`
+	RecordType util.RecordType `json:"record_type"`
+	Date       string          `json:"date"`
+	Amount     string          `json:"amount"`
+	ExternalId string          `json:"external_id,omitempty"`
+	Duplicate  bool            `json:"duplicate"`
+	Error      string          `json:"error,omitempty"`
+	RecordId   int64           `json:"record_id,omitempty"`
+}
+
+type importRecordsResponse struct {
+	DryRun     bool              `json:"dry_run"`
+	Total      int               `json:"total"`
+	Valid      int               `json:"valid"`
+	Duplicates int               `json:"duplicates"`
+	Failed     int               `json:"failed"`
+	Imported   int               `json:"imported"`
+	Rows       []importRowResult `json:"rows"`
+}
+
+/**
+ * 读取multipart表单中的file和options字段
+ * options是json格式的导入选项, 按binding标签校验
+ */
+func bindImportForm(ctx *gin.Context, options any) ([]byte, bool) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportFileSize)
+
+	_, err := ctx.MultipartForm()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	err = json.Unmarshal([]byte(ctx.PostForm("options")), options)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(options)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		if err == http.ErrMissingFile {
+			err = errMissingImportFile
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, false
+	}
+
+	return data, true
+}
+
+/**
+ * 金额, 日期和名称都相同的记录视为重复
+ */
+func duplicateKey(date time.Time, amount string, name string) string {
+	if rat, ok := new(big.Rat).SetString(amount); ok {
+		amount = rat.RatString()
+	}
+	return date.Format(util.DateFormat) + "|" + amount + "|" + name
+}
+
+/**
+ * 校验, 去重并写入导入的记录
+ * 1. 每一行按createRecord的规则校验, 日期已关账的行视为无效
+ * 2. 与账单中已有的记录以及文件中前面的行比较, 检测重复的记录
+ *    有交易号的记录按交易号判断, 重复时总是跳过, 使重复导入同一份账单不产生新记录
+ * 3. 不是预览时, 在一个事务中写入所有有效且不重复的记录
+ */
+func (server *Server) importRows(
+	ctx *gin.Context,
+	accountId int64,
+	rows []importer.Row,
+	dryRun bool,
+	allowDuplicates bool,
+) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.checkAccountAccessRule(ctx, authPayload.UserId, accountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var closed map[int64]time.Time
+	closed, err = server.getClosedUntils(ctx, []int64{accountId})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := importRecordsResponse{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Rows:   make([]importRowResult, len(rows)),
+	}
+
+	var startDate, endDate time.Time
+	externalIds := []string{}
+	for i, row := range rows {
+		resp.Rows[i] = importRowResult{
+			Line:       row.Line,
+			Name:       row.Name,
+			RecordType: row.RecordType,
+			Amount:     row.Amount,
+			ExternalId: row.ExternalId,
+		}
+		if row.Err != nil {
+			resp.Rows[i].Error = row.Err.Error()
+			continue
+		}
+		resp.Rows[i].Date = row.Date.Format(util.DateFormat)
+
+		req := createRecordRequest{
+			Name:       row.Name,
+			RecordType: row.RecordType,
+			Date:       util.Date(row.Date),
+			Amount:     row.Amount,
+			AccountId:  accountId,
+		}
+		err = binding.Validator.ValidateStruct(&req)
+		if err != nil {
+			resp.Rows[i].Error = err.Error()
+			continue
+		}
+
+		if isPeriodClosed(closed, accountId, row.Date) {
+			resp.Rows[i].Error = db.ErrPeriodClosed.Error()
+			continue
+		}
+
+		if startDate.IsZero() || row.Date.Before(startDate) {
+			startDate = row.Date
+		}
+		if row.Date.After(endDate) {
+			endDate = row.Date
+		}
+		if row.ExternalId != "" {
+			externalIds = append(externalIds, row.ExternalId)
+		}
+	}
+
+	seen := map[string]bool{}
+	if len(externalIds) > 0 {
+		var imported []string
+		imported, err = server.db.GetRecordsExternalIds(ctx, accountId, externalIds)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		for _, externalId := range imported {
+			seen[externalId] = true
+		}
+	}
+
+	if !startDate.IsZero() {
+		err = server.db.IterateRecordsByAccountId(ctx, accountId, startDate, endDate, func(record db.Record) error {
+			seen[duplicateKey(record.Date, record.Amount, record.Name)] = true
+			return nil
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	records := []db.NewRecord{}
+	indexes := []int{}
+	for i, row := range rows {
+		if resp.Rows[i].Error != "" {
+			resp.Failed++
+			continue
+		}
+		resp.Valid++
+
+		if row.ExternalId != "" {
+			if seen[row.ExternalId] {
+				resp.Rows[i].Duplicate = true
+				resp.Duplicates++
+				continue
+			}
+			seen[row.ExternalId] = true
+		}
+
+		// 有外部id的行只按外部id去重, 同一天同金额同名称的不同交易不算重复
+		key := duplicateKey(row.Date, row.Amount, row.Name)
+		if row.ExternalId == "" && seen[key] {
+			resp.Rows[i].Duplicate = true
+			resp.Duplicates++
+			if !allowDuplicates {
+				continue
+			}
+		}
+		seen[key] = true
+
+		records = append(records, db.NewRecord{
+			Name:       row.Name,
+			RecordType: row.RecordType,
+			Date:       row.Date,
+			Amount:     row.Amount,
+			AccountId:  accountId,
+			ExternalId: row.ExternalId,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if !dryRun && len(records) > 0 {
+		var created []db.Record
+		err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+			var err error
+			created, err = store.ImportRecords(ctx, records, authPayload.UserId)
+			if err != nil {
+				return nil, err
+			}
+
+			return newRecordEvents(ctx, eventRecordCreated, created), nil
+		})
+		if err != nil {
+			if err == db.ErrPeriodClosed {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+			} else {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			}
+			return
+		}
+
+		for i, record := range created {
+			resp.Rows[indexes[i]].RecordId = record.ID
+		}
+		resp.Imported = len(created)
+		server.checkRecordsBudgetAlerts(ctx, created)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type csvMappingRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Date     string `json:"date" binding:"required"`
+	Amount   string `json:"amount" binding:"required"`
+	Category string `json:"category"`
+}
+
+/**
+ * CSV导入的选项, 通过multipart表单的options字段以json传递
+ * DateFormat使用YYYY, MM, DD等占位, 默认为YYYY-MM-DD
+ * 没有分类列或分类无法识别时使用DefaultRecordType
+ */
+type importRecordsCSVOptions struct {
+	AccountId         int64                      `json:"account_id" binding:"required,min=1"`
+	Mapping           csvMappingRequest          `json:"mapping" binding:"required"`
+	SkipRows          int                        `json:"skip_rows" binding:"min=0,max=100"`
+	Delimiter         string                     `json:"delimiter" binding:"omitempty,len=1"`
+	DateFormat        string                     `json:"date_format" binding:"max=32"`
+	DecimalComma      bool                       `json:"decimal_comma"`
+	NegateAmount      bool                       `json:"negate_amount"`
+	Categories        map[string]util.RecordType `json:"categories" binding:"max=100,dive,min=1,max=7"`
+	DefaultRecordType util.RecordType            `json:"default_record_type" binding:"omitempty,min=1,max=7"`
+	AllowDuplicates   bool                       `json:"allow_duplicates"`
+	DryRun            bool                       `json:"dry_run"`
+}
+
+func (server *Server) importRecordsCSV(ctx *gin.Context) {
+	var options importRecordsCSVOptions
+	data, ok := bindImportForm(ctx, &options)
+	if !ok {
+		return
+	}
+
+	var delimiter rune
+	if options.Delimiter != "" {
+		delimiter = rune(options.Delimiter[0])
+	}
+
+	rows, err := importer.ParseCSV(bytes.NewReader(data), importer.CSVOptions{
+		Mapping: importer.CSVMapping{
+			Name:     options.Mapping.Name,
+			Date:     options.Mapping.Date,
+			Amount:   options.Mapping.Amount,
+			Category: options.Mapping.Category,
+		},
+		SkipRows:          options.SkipRows,
+		Delimiter:         delimiter,
+		DateFormat:        options.DateFormat,
+		DecimalComma:      options.DecimalComma,
+		NegateAmount:      options.NegateAmount,
+		Categories:        options.Categories,
+		DefaultRecordType: options.DefaultRecordType,
+		MaxRows:           maxImportRows,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.importRows(ctx, options.AccountId, rows, options.DryRun, options.AllowDuplicates)
+}
+
+/**
+ * 第三方平台或银行导出的账单的导入选项, 通过multipart表单的options字段以json传递
+ * Categories以平台的交易分类为键, 覆盖默认的分类映射
+ * dry_run为true时只返回预览, 确认后再以dry_run为false提交同一个文件
+ */
+type importRecordsStatementOptions struct {
+	AccountId         int64                      `json:"account_id" binding:"required,min=1"`
+	Format            string                     `json:"format" binding:"required,oneof=alipay wechat ofx qfx qif"`
+	Categories        map[string]util.RecordType `json:"categories" binding:"max=100,dive,min=1,max=7"`
+	DefaultRecordType util.RecordType            `json:"default_record_type" binding:"omitempty,min=1,max=7"`
+	// 只用于QIF文件, 默认为mdy
+	DateOrder       string `json:"date_order" binding:"omitempty,oneof=mdy dmy ymd"`
+	DecimalComma    bool   `json:"decimal_comma"`
+	AllowDuplicates bool   `json:"allow_duplicates"`
+	DryRun          bool   `json:"dry_run"`
+}
+
+func (server *Server) importRecordsStatement(ctx *gin.Context) {
+	var options importRecordsStatementOptions
+	data, ok := bindImportForm(ctx, &options)
+	if !ok {
+		return
+	}
+
+	statementOptions := importer.StatementOptions{
+		Categories:        options.Categories,
+		DefaultRecordType: options.DefaultRecordType,
+		MaxRows:           maxImportRows,
+		DateOrder:         options.DateOrder,
+		DecimalComma:      options.DecimalComma,
+	}
+
+	var rows []importer.Row
+	var err error
+	switch options.Format {
+	case "alipay":
+		rows, err = importer.ParseAlipay(data, statementOptions)
+	case "wechat":
+		rows, err = importer.ParseWeChat(data, statementOptions)
+	case "ofx", "qfx":
+		rows, err = importer.ParseOFX(data, statementOptions)
+	case "qif":
+		rows, err = importer.ParseQIF(data, statementOptions)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.importRows(ctx, options.AccountId, rows, options.DryRun, options.AllowDuplicates)
+}
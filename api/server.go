@@ -5,25 +5,45 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/blob"
+	"github.com/timelyrain/star-account/broker"
 	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/exporter"
+	"github.com/timelyrain/star-account/mail"
 	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/webhook"
 )
 
 var (
 	errAccessDenied = errors.New("access denied")
 )
 
+/**
+ * 单次webhook投递的超时时间
+ */
+const webhookTimeout = 10 * time.Second
+
 type Server struct {
-	db            *db.DB
-	router        *gin.Engine
-	tokenMaker    *token.Maker
-	tokenDuration time.Duration
+	db                *db.DB
+	router            *gin.Engine
+	tokenMaker        *token.Maker
+	tokenDuration     time.Duration
+	idempotencyKeyTTL time.Duration
+	webhookSender     *webhook.Sender
+	broker            *broker.Broker
+	blobStore         blob.BlobStore
+	mailSender        mail.Sender
+	statementFont     *exporter.Font
 }
 
 func NewServer(
 	db *db.DB,
+	blobStore blob.BlobStore,
+	mailSender mail.Sender,
+	statementFont *exporter.Font,
 	symmetricKey string,
 	tokenDuration time.Duration,
+	idempotencyKeyTTL time.Duration,
 ) (*Server, error) {
 	tokenMaker, err := token.NewMaker(symmetricKey)
 	if err != nil {
@@ -31,9 +51,15 @@ func NewServer(
 	}
 
 	server := &Server{
-		db:            db,
-		tokenMaker:    tokenMaker,
-		tokenDuration: tokenDuration,
+		db:                db,
+		tokenMaker:        tokenMaker,
+		tokenDuration:     tokenDuration,
+		idempotencyKeyTTL: idempotencyKeyTTL,
+		webhookSender:     webhook.NewSender(webhookTimeout),
+		broker:            broker.New(),
+		blobStore:         blobStore,
+		mailSender:        mailSender,
+		statementFont:     statementFont,
 	}
 	server.setupRouter()
 
@@ -44,16 +70,24 @@ func (server *Server) setupRouter() {
 	router := gin.Default()
 	router.Use(CORSMiddleware())
 	authRoutes := router.Group("/").Use(authMiddleWare(*server.tokenMaker))
+	// 修改数据的接口支持Idempotency-Key
+	mutatingRoutes := router.Group("/").Use(
+		authMiddleWare(*server.tokenMaker),
+		idempotencyMiddleware(server.db, server.idempotencyKeyTTL),
+	)
 
 	// user apis
 	router.POST("/api/login-user", server.loginUser)
 	router.POST("/api/create-user", server.createUser)
 	authRoutes.POST("/api/check-user-role", server.checkUserRole)
 	authRoutes.POST("/api/check-current-user-role", server.checkCurrentUserRole)
-	authRoutes.POST("/api/delete-user", server.deleteUser)
-	authRoutes.POST("/api/update-user-name", server.updateUserName)
-	authRoutes.POST("/api/update-user-email", server.updateUserEmail)
-	authRoutes.POST("/api/update-user-password", server.updateUserPassword)
+	mutatingRoutes.POST("/api/delete-user", server.deleteUser)
+	mutatingRoutes.POST("/api/cancel-user-erasure", server.cancelUserErasure)
+	authRoutes.POST("/api/get-user-erasure", server.getUserErasure)
+	authRoutes.POST("/api/export-user-data", server.exportUserData)
+	mutatingRoutes.POST("/api/update-user-name", server.updateUserName)
+	mutatingRoutes.POST("/api/update-user-email", server.updateUserEmail)
+	mutatingRoutes.POST("/api/update-user-password", server.updateUserPassword)
 	authRoutes.POST("/api/get-user", server.getUser)
 	authRoutes.POST("/api/get-user-by-token", server.getUserByToken)
 	authRoutes.POST("/api/get-user-by-email", server.getUserByEmail)
@@ -63,22 +97,108 @@ func (server *Server) setupRouter() {
 	authRoutes.POST("/api/get-users-count-by-account-id-and-role", server.getUsersCountByAccountIdAndRole)
 
 	// account apis
-	authRoutes.POST("/api/create-account", server.createAccount)
-	authRoutes.POST("/api/delete-account", server.deleteAccount)
+	mutatingRoutes.POST("/api/create-account", server.createAccount)
+	mutatingRoutes.POST("/api/delete-account", server.deleteAccount)
 	authRoutes.POST("/api/get-account", server.getAccount)
 	authRoutes.POST("/api/get-accounts", server.getAccounts)
 	authRoutes.POST("/api/get-accounts-count", server.getAccountsCount)
-	authRoutes.POST("/api/update-account-name", server.updateAccountName)
-	authRoutes.POST("/api/add-account-manager", server.addAccountManager)
-	authRoutes.POST("/api/delete-account-manager", server.deleteAccountManager)
+	mutatingRoutes.POST("/api/update-account-name", server.updateAccountName)
+	mutatingRoutes.POST("/api/add-account-manager", server.addAccountManager)
+	mutatingRoutes.POST("/api/delete-account-manager", server.deleteAccountManager)
+	authRoutes.POST("/api/get-dashboard", server.getDashboard)
+	authRoutes.POST("/api/subscribe-account-events", server.subscribeAccountEvents)
+	authRoutes.POST("/api/export-account-backup", server.exportAccountBackup)
+	mutatingRoutes.POST("/api/restore-account", server.restoreAccount)
+
+	// webhook apis
+	mutatingRoutes.POST("/api/create-webhook", server.createWebhook)
+	authRoutes.POST("/api/get-webhooks", server.getWebhooks)
+	mutatingRoutes.POST("/api/update-webhook", server.updateWebhook)
+	mutatingRoutes.POST("/api/delete-webhook", server.deleteWebhook)
+	authRoutes.POST("/api/get-webhook-deliveries", server.getWebhookDeliveries)
+	mutatingRoutes.POST("/api/redeliver-webhook-delivery", server.redeliverWebhookDelivery)
+
+	// notification apis
+	authRoutes.POST("/api/get-notifications", server.getNotifications)
+	authRoutes.POST("/api/get-unread-notifications-count", server.getUnreadNotificationsCount)
+	mutatingRoutes.POST("/api/mark-notification-read", server.markNotificationRead)
+	mutatingRoutes.POST("/api/mark-all-notifications-read", server.markAllNotificationsRead)
+	authRoutes.POST("/api/get-notification-preferences", server.getNotificationPreferences)
+	mutatingRoutes.POST("/api/update-notification-preference", server.updateNotificationPreference)
+
+	// digest apis
+	authRoutes.POST("/api/get-digest-subscription", server.getDigestSubscription)
+	mutatingRoutes.POST("/api/update-digest-subscription", server.updateDigestSubscription)
+	mutatingRoutes.POST("/api/delete-digest-subscription", server.deleteDigestSubscription)
 
 	// record apis
-	authRoutes.POST("/api/create-record", server.createRecord)
-	authRoutes.POST("/api/delete-record", server.deleteRecord)
+	mutatingRoutes.POST("/api/create-record", server.createRecord)
+	mutatingRoutes.POST("/api/delete-record", server.deleteRecord)
 	authRoutes.POST("/api/get-records-by-account-id", server.getRecordsByAccountId)
 	authRoutes.POST("/api/get-records-count-by-account-id", server.getRecordsCountByAccountId)
 	authRoutes.POST("/api/get-records-amount-sum-by-account-id", server.getRecordsAmountSumByAccountId)
-	authRoutes.POST("/api/update-record", server.updateRecord)
+	mutatingRoutes.POST("/api/update-record", server.updateRecord)
+	authRoutes.POST("/api/search-records", server.searchRecords)
+	mutatingRoutes.POST("/api/bulk-create-records", server.bulkCreateRecords)
+	mutatingRoutes.POST("/api/bulk-update-records", server.bulkUpdateRecords)
+	mutatingRoutes.POST("/api/bulk-delete-records", server.bulkDeleteRecords)
+	authRoutes.POST("/api/get-records-statistics", server.getRecordsStatistics)
+	authRoutes.POST("/api/export-records-csv", server.exportRecordsCSV)
+	authRoutes.POST("/api/export-records-xlsx", server.exportRecordsXLSX)
+	authRoutes.POST("/api/export-records-journal", server.exportRecordsJournal)
+	authRoutes.POST("/api/export-statement-pdf", server.exportStatementPDF)
+	mutatingRoutes.POST("/api/import-records-csv", server.importRecordsCSV)
+	mutatingRoutes.POST("/api/import-records-statement", server.importRecordsStatement)
+
+	// attachment apis
+	mutatingRoutes.POST("/api/upload-attachment", server.uploadAttachment)
+	authRoutes.POST("/api/get-attachments-by-record-id", server.getAttachmentsByRecordId)
+	authRoutes.POST("/api/download-attachment", server.downloadAttachment)
+	mutatingRoutes.POST("/api/delete-attachment", server.deleteAttachment)
+
+	// comment apis
+	mutatingRoutes.POST("/api/create-record-comment", server.createRecordComment)
+	authRoutes.POST("/api/get-record-comments", server.getRecordComments)
+	mutatingRoutes.POST("/api/update-record-comment", server.updateRecordComment)
+	mutatingRoutes.POST("/api/delete-record-comment", server.deleteRecordComment)
+	authRoutes.POST("/api/get-unread-comments-counts", server.getUnreadCommentsCounts)
+	mutatingRoutes.POST("/api/mark-comments-read", server.markCommentsRead)
+
+	// period closing apis
+	mutatingRoutes.POST("/api/close-period", server.closePeriod)
+	mutatingRoutes.POST("/api/reopen-period", server.reopenPeriod)
+	authRoutes.POST("/api/get-period-closings", server.getPeriodClosings)
+
+	// approval apis
+	mutatingRoutes.POST("/api/create-approval-rule", server.createApprovalRule)
+	authRoutes.POST("/api/get-approval-rules", server.getApprovalRules)
+	mutatingRoutes.POST("/api/update-approval-rule", server.updateApprovalRule)
+	mutatingRoutes.POST("/api/delete-approval-rule", server.deleteApprovalRule)
+	authRoutes.POST("/api/get-pending-records", server.getPendingRecords)
+	mutatingRoutes.POST("/api/approve-record", server.approveRecord)
+	mutatingRoutes.POST("/api/reject-record", server.rejectRecord)
+	authRoutes.POST("/api/get-record-approvals", server.getRecordApprovals)
+
+	// reconciliation apis
+	mutatingRoutes.POST("/api/set-opening-balance", server.setOpeningBalance)
+	mutatingRoutes.POST("/api/delete-opening-balance", server.deleteOpeningBalance)
+	mutatingRoutes.POST("/api/create-balance-checkpoint", server.createBalanceCheckpoint)
+	authRoutes.POST("/api/get-balance-checkpoints", server.getBalanceCheckpoints)
+	mutatingRoutes.POST("/api/delete-balance-checkpoint", server.deleteBalanceCheckpoint)
+	mutatingRoutes.POST("/api/reconcile-balance-checkpoint", server.reconcileBalanceCheckpoint)
+	authRoutes.POST("/api/get-running-balances", server.getRunningBalances)
+	mutatingRoutes.POST("/api/update-records-clear-status", server.updateRecordsClearStatus)
+
+	// sync apis
+	authRoutes.POST("/api/sync-changes", server.syncChanges)
+	mutatingRoutes.POST("/api/sync-push", server.syncPush)
+
+	// budget apis
+	mutatingRoutes.POST("/api/create-budget", server.createBudget)
+	mutatingRoutes.POST("/api/update-budget", server.updateBudget)
+	mutatingRoutes.POST("/api/delete-budget", server.deleteBudget)
+	authRoutes.POST("/api/get-budget-progress", server.getBudgetProgress)
+	authRoutes.POST("/api/get-budget-alerts", server.getBudgetAlerts)
 
 	server.router = router
 }
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/exporter"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 月度对账单的排版参数, 单位为point
+ */
+const (
+	statementMargin     = 40.0
+	statementLineHeight = 16.0
+	statementFontSize   = 9.0
+	statementChartH     = 120.0
+	maxStatementMembers = 50
+)
+
+/**
+ * 记录表格各列的左侧位置和最大宽度, 金额列右对齐到页面右边距
+ */
+var statementColumns = []struct {
+	title string
+	x     float64
+	width float64
+}{
+	{"Date", statementMargin, 64},
+	{"Name", statementMargin + 70, 184},
+	{"Category", statementMargin + 260, 74},
+	{"Creator", statementMargin + 340, 90},
+	{"Amount", exporter.PageWidth - statementMargin, 0},
+}
+
+/**
+ * 排版时的当前位置, 超出页面底部时自动换页
+ */
+type statementLayout struct {
+	pdf *exporter.PDF
+	y   float64
+}
+
+func (layout *statementLayout) ensure(height float64) bool {
+	if layout.y+height <= exporter.PageHeight-statementMargin {
+		return false
+	}
+	layout.pdf.AddPage()
+	layout.y = statementMargin
+	return true
+}
+
+func (layout *statementLayout) heading(s string) {
+	layout.ensure(statementLineHeight * 3)
+	layout.y += statementLineHeight
+	layout.pdf.Text(statementMargin, layout.y, 12, true, s)
+	layout.y += 6
+	layout.pdf.Line(statementMargin, layout.y, exporter.PageWidth-statementMargin, layout.y, 0.5)
+	layout.y += statementLineHeight
+}
+
+func (layout *statementLayout) recordsHeader() {
+	for i, column := range statementColumns {
+		if i == len(statementColumns)-1 {
+			layout.pdf.TextRight(column.x, layout.y, statementFontSize, true, column.title)
+		} else {
+			layout.pdf.Text(column.x, layout.y, statementFontSize, true, column.title)
+		}
+	}
+	layout.y += statementLineHeight
+}
+
+type exportStatementPDFRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+	Year      int   `json:"year" binding:"required,min=1970,max=9999"`
+	Month     int   `json:"month" binding:"required,min=1,max=12"`
+}
+
+/**
+ * 生成账单某个月的PDF对账单
+ * 1. 标题: 账单名称, 月份和成员
+ * 2. 按日期排序的记录表格, 表头在每页重复
+ * 3. 按类型汇总的收入, 支出和净额
+ * 4. 每日支出的柱状图
+ */
+func (server *Server) exportStatementPDF(ctx *gin.Context) {
+	var req exportStatementPDFRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var account db.Account
+	account, err = server.db.GetAccount(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	members := []string{}
+	for _, role := range []util.AccountRole{util.AccountRoleOwner, util.AccountRoleManager} {
+		var users []db.User
+		users, err = server.db.GetUsersByAccountIdAndRole(ctx, req.AccountId, role, 0, maxStatementMembers)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		for _, user := range users {
+			members = append(members, user.Name)
+		}
+	}
+
+	startDate := time.Date(req.Year, time.Month(req.Month), 1, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 1, -1)
+
+	var records []db.Record
+	records, err = server.loadRecordsForExport(ctx, req.AccountId, startDate, endDate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var names map[int64]string
+	names, err = server.userNames(ctx, records)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	pdf := exporter.NewPDF(server.statementFont)
+	pdf.AddPage()
+	layout := &statementLayout{pdf: pdf, y: statementMargin}
+
+	layout.y += 18
+	pdf.Text(statementMargin, layout.y, 18, true, pdf.TruncateText(account.Name, 18, exporter.PageWidth-statementMargin*2))
+	layout.y += statementLineHeight * 1.5
+	pdf.Text(statementMargin, layout.y, 11, false, "Statement "+startDate.Format("2006-01"))
+	layout.y += statementLineHeight
+	pdf.Text(statementMargin, layout.y, statementFontSize, false, pdf.TruncateText(
+		"Members: "+strings.Join(members, ", "),
+		statementFontSize,
+		exporter.PageWidth-statementMargin*2,
+	))
+	layout.y += statementLineHeight
+
+	layout.heading("Records")
+	layout.recordsHeader()
+
+	income := map[string]*big.Rat{}
+	expense := map[string]*big.Rat{}
+	daily := map[string]*big.Rat{}
+	for _, record := range records {
+		if layout.ensure(statementLineHeight) {
+			layout.recordsHeader()
+		}
+
+		values := []string{
+			record.Date.Format(util.DateFormat),
+			record.Name,
+			util.RecordTypeName(record.Type),
+			names[record.CreateUserID],
+		}
+		for i, value := range values {
+			column := statementColumns[i]
+			pdf.Text(column.x, layout.y, statementFontSize, false, pdf.TruncateText(value, statementFontSize, column.width))
+		}
+		pdf.TextRight(statementColumns[len(statementColumns)-1].x, layout.y, statementFontSize, false, record.Amount)
+		layout.y += statementLineHeight
+
+		// 等待审批和被驳回的记录只列出, 不计入汇总
+		if record.ApprovalStatus != util.ApprovalStatusApproved {
+			continue
+		}
+
+		category := util.RecordTypeName(record.Type)
+		// 金额为负的记录是收入, 汇总中收入和支出都以正数表示
+		if strings.HasPrefix(record.Amount, "-") {
+			subAmount(income, category, record.Amount)
+			subAmount(income, "", record.Amount)
+		} else {
+			addAmount(expense, category, record.Amount)
+			addAmount(daily, record.Date.Format(util.DateFormat), record.Amount)
+			addAmount(expense, "", record.Amount)
+		}
+	}
+	if len(records) == 0 {
+		pdf.Text(statementMargin, layout.y, statementFontSize, false, "No records")
+		layout.y += statementLineHeight
+	}
+
+	layout.heading("Category Subtotals")
+	subtotalColumns := []float64{statementMargin, 300, 420, exporter.PageWidth - statementMargin}
+	pdf.Text(subtotalColumns[0], layout.y, statementFontSize, true, "Category")
+	pdf.TextRight(subtotalColumns[1], layout.y, statementFontSize, true, "Income")
+	pdf.TextRight(subtotalColumns[2], layout.y, statementFontSize, true, "Expense")
+	pdf.TextRight(subtotalColumns[3], layout.y, statementFontSize, true, "Net")
+	layout.y += statementLineHeight
+
+	for recordType := util.RecordType(util.RecordTypeFood); recordType <= util.RecordTypeGift; recordType++ {
+		category := util.RecordTypeName(recordType)
+		if income[category] == nil && expense[category] == nil {
+			continue
+		}
+		layout.subtotal(subtotalColumns, category, income, expense, false)
+	}
+	layout.subtotal(subtotalColumns, "", income, expense, true)
+
+	layout.heading("Daily Spending")
+	layout.dailyChart(startDate, endDate, daily)
+
+	for i := 0; i < pdf.PageCount(); i++ {
+		pdf.SetPage(i)
+		pdf.TextRight(
+			exporter.PageWidth-statementMargin,
+			exporter.PageHeight-statementMargin/2,
+			8,
+			false,
+			fmt.Sprintf("Page %d / %d", i+1, pdf.PageCount()),
+		)
+	}
+
+	setAttachmentHeaders(ctx, "application/pdf", fmt.Sprintf("statement-%d-%s.pdf", req.AccountId, startDate.Format("2006-01")))
+	ctx.Status(http.StatusOK)
+
+	err = pdf.Write(ctx.Writer)
+	if err != nil {
+		_ = ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+/**
+ * category为空时绘制合计行
+ */
+func (layout *statementLayout) subtotal(
+	columns []float64,
+	category string,
+	income map[string]*big.Rat,
+	expense map[string]*big.Rat,
+	total bool,
+) {
+	layout.ensure(statementLineHeight)
+
+	net := new(big.Rat)
+	if income[category] != nil {
+		net.Add(net, income[category])
+	}
+	if expense[category] != nil {
+		net.Sub(net, expense[category])
+	}
+
+	title := category
+	if total {
+		title = "Total"
+		layout.pdf.Line(statementMargin, layout.y-statementLineHeight+4, exporter.PageWidth-statementMargin, layout.y-statementLineHeight+4, 0.5)
+	}
+
+	layout.pdf.Text(columns[0], layout.y, statementFontSize, total, title)
+	layout.pdf.TextRight(columns[1], layout.y, statementFontSize, total, sumOf(income, category))
+	layout.pdf.TextRight(columns[2], layout.y, statementFontSize, total, sumOf(expense, category))
+	layout.pdf.TextRight(columns[3], layout.y, statementFontSize, total, net.FloatString(2))
+	layout.y += statementLineHeight
+}
+
+/**
+ * 每日支出的柱状图, 纵轴从0到当月最大的单日支出
+ */
+func (layout *statementLayout) dailyChart(startDate time.Time, endDate time.Time, daily map[string]*big.Rat) {
+	layout.ensure(statementChartH + statementLineHeight*2)
+
+	days := []time.Time{}
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	spending := make([]float64, len(days))
+	maxSpending := 0.0
+	for i, day := range days {
+		if amount := daily[day.Format(util.DateFormat)]; amount != nil {
+			spending[i], _ = new(big.Rat).Abs(amount).Float64()
+		}
+		maxSpending = max(maxSpending, spending[i])
+	}
+
+	left := statementMargin + 50
+	width := exporter.PageWidth - statementMargin - left
+	top := layout.y
+	bottom := top + statementChartH
+	slot := width / float64(len(days))
+
+	layout.pdf.Line(left, bottom, left+width, bottom, 0.5)
+	layout.pdf.Line(left, top, left, bottom, 0.5)
+	layout.pdf.TextRight(left-4, top+statementFontSize, statementFontSize, false, fmt.Sprintf("%.2f", maxSpending))
+	layout.pdf.TextRight(left-4, bottom, statementFontSize, false, "0")
+
+	for i, day := range days {
+		x := left + float64(i)*slot
+		if maxSpending > 0 && spending[i] > 0 {
+			height := spending[i] / maxSpending * statementChartH
+			layout.pdf.FillRect(x+slot*0.15, bottom-height, slot*0.7, height, 0.4)
+		}
+		if i == 0 || (i+1)%5 == 0 {
+			label := fmt.Sprint(day.Day())
+			layout.pdf.Text(x+(slot-layout.pdf.TextWidth(label, 7))/2, bottom+10, 7, false, label)
+		}
+	}
+
+	layout.y = bottom + statementLineHeight*2
+}
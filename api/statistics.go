@@ -0,0 +1,209 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+const (
+	statisticsIntervalDay   = "day"
+	statisticsIntervalWeek  = "week"
+	statisticsIntervalMonth = "month"
+	statisticsIntervalYear  = "year"
+
+	maxStatisticsBuckets = 1000
+)
+
+var (
+	errInvalidDateRange = errors.New("end date is before start date")
+	errTooManyBuckets   = errors.New("too many buckets, use a larger interval or a shorter date range")
+)
+
+/**
+ * 按IANA时区名加载时区, 为空时使用服务器所在时区
+ */
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+/**
+ * 把时间点换算为指定时区下的日期, 以UTC零点表示, 与数据库中date类型的取值一致
+ */
+func dateIn(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+/**
+ * 与postgres的date_trunc保持一致, 一周从周一开始
+ */
+func truncateDate(date time.Time, interval string) time.Time {
+	switch interval {
+	case statisticsIntervalWeek:
+		return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+	case statisticsIntervalMonth:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case statisticsIntervalYear:
+		return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+func nextDate(date time.Time, interval string) time.Time {
+	switch interval {
+	case statisticsIntervalWeek:
+		return date.AddDate(0, 0, 7)
+	case statisticsIntervalMonth:
+		return date.AddDate(0, 1, 0)
+	case statisticsIntervalYear:
+		return date.AddDate(1, 0, 0)
+	}
+	return date.AddDate(0, 0, 1)
+}
+
+type getRecordsStatisticsRequest struct {
+	AccountId int64     `json:"account_id" binding:"required,min=1"`
+	StartDate util.Date `json:"start_date" binding:"required"`
+	EndDate   util.Date `json:"end_date" binding:"required"`
+	Interval  string    `json:"interval" binding:"required,oneof=day week month year"`
+	GroupBy   string    `json:"group_by" binding:"omitempty,oneof=category creator"`
+	// IANA时区名, 默认使用服务器所在时区
+	TimeZone string `json:"time_zone"`
+}
+
+type statisticsBucket struct {
+	Start     string `json:"start"`
+	StartTime int64  `json:"start_time"`
+	Income    string `json:"income"`
+	Expense   string `json:"expense"`
+	Count     int64  `json:"count"`
+}
+
+/**
+ * Key在group_by为category时是记录类型, 为creator时是用户id, 不分组时为0
+ */
+type statisticsSeries struct {
+	Key     int64              `json:"key"`
+	Buckets []statisticsBucket `json:"buckets"`
+}
+
+type getRecordsStatisticsResponse struct {
+	Interval string             `json:"interval"`
+	GroupBy  string             `json:"group_by"`
+	TimeZone string             `json:"time_zone"`
+	Series   []statisticsSeries `json:"series"`
+}
+
+func (server *Server) getRecordsStatistics(ctx *gin.Context) {
+	var req getRecordsStatisticsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var loc *time.Location
+	loc, err = loadLocation(req.TimeZone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	startDate := dateIn(time.Time(req.StartDate), loc)
+	endDate := dateIn(time.Time(req.EndDate), loc)
+	if endDate.Before(startDate) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidDateRange))
+		return
+	}
+
+	buckets := []time.Time{}
+	for bucket := truncateDate(startDate, req.Interval); !bucket.After(endDate); bucket = nextDate(bucket, req.Interval) {
+		if len(buckets) == maxStatisticsBuckets {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errTooManyBuckets))
+			return
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleManager)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var stats []db.RecordsStatistic
+	stats, err = server.db.GetRecordsStatistics(
+		ctx,
+		req.AccountId,
+		req.Interval,
+		req.GroupBy,
+		startDate,
+		endDate,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// 没有分组时总是返回一个序列
+	byKey := map[int64]map[string]db.RecordsStatistic{}
+	if req.GroupBy == "" {
+		byKey[0] = map[string]db.RecordsStatistic{}
+	}
+	for _, stat := range stats {
+		if byKey[stat.GroupKey] == nil {
+			byKey[stat.GroupKey] = map[string]db.RecordsStatistic{}
+		}
+		byKey[stat.GroupKey][stat.Bucket.Format(util.DateFormat)] = stat
+	}
+
+	keys := []int64{}
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	resp := getRecordsStatisticsResponse{
+		Interval: req.Interval,
+		GroupBy:  req.GroupBy,
+		TimeZone: loc.String(),
+		Series:   []statisticsSeries{},
+	}
+	for _, key := range keys {
+		series := statisticsSeries{Key: key, Buckets: []statisticsBucket{}}
+		for _, bucket := range buckets {
+			start := bucket.Format(util.DateFormat)
+			item := statisticsBucket{
+				Start:     start,
+				StartTime: time.Date(bucket.Year(), bucket.Month(), bucket.Day(), 0, 0, 0, 0, loc).Unix(),
+				Income:    "0",
+				Expense:   "0",
+			}
+			if stat, ok := byKey[key][start]; ok {
+				item.Income = stat.Income
+				item.Expense = stat.Expense
+				item.Count = stat.Count
+			}
+			series.Buckets = append(series.Buckets, item)
+		}
+		resp.Series = append(resp.Series, series)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
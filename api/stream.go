@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/broker"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 与数据库的监听连接重连后发送, 期间的事件可能丢失, 客户端应重新拉取数据
+ */
+const eventResync = "resync"
+
+/**
+ * 没有事件时定期发送注释行, 防止连接被代理关闭
+ */
+const streamKeepAliveInterval = 30 * time.Second
+
+/**
+ * 监听所有服务实例发布的事件并分发给本实例的订阅者, 直到ctx取消
+ */
+func (server *Server) listenAccountEvents(ctx context.Context, dataSource string) {
+	err := db.ListenAccountEvents(ctx, dataSource, func(payload string) {
+		if payload == "" {
+			server.broker.Publish(broker.Event{Name: eventResync, Data: []byte("{}")})
+			return
+		}
+
+		var event accountEvent
+		err := json.Unmarshal([]byte(payload), &event)
+		if err != nil {
+			log.Println("cannot decode account event: ", err)
+			return
+		}
+
+		server.broker.Publish(broker.Event{
+			AccountId: event.AccountId,
+			Name:      event.Event,
+			Data:      []byte(payload),
+		})
+	})
+	if err != nil {
+		log.Println("cannot listen account events: ", err)
+	}
+}
+
+type subscribeAccountEventsRequest struct {
+	AccountIds []int64 `json:"account_ids" binding:"required,min=1,max=50,dive,min=1"`
+}
+
+/**
+ * 以Server-Sent Events推送订阅账单中的事件
+ * 令牌过期, 当前用户被移出账单或订阅者跟不上事件时关闭连接, 由客户端重新订阅
+ */
+func (server *Server) subscribeAccountEvents(ctx *gin.Context) {
+	var req subscribeAccountEventsRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var denied map[int64]bool
+	denied, err = server.checkAccountAccessRules(ctx, authPayload.UserId, req.AccountIds, util.AccountRoleManager)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if len(denied) > 0 {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccessDenied))
+		return
+	}
+
+	sub := server.broker.Subscribe(req.AccountIds)
+	defer sub.Close()
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	expired := time.NewTimer(time.Until(authPayload.ExpiredAt))
+	defer expired.Stop()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-expired.C:
+			return false
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return false
+			}
+
+			ctx.SSEvent(event.Name, string(event.Data))
+			return !isRemovedMember(event, authPayload.UserId)
+		}
+	})
+}
+
+/**
+ * 当前用户被移出账单后不再推送该账单的事件
+ */
+func isRemovedMember(event broker.Event, userId int64) bool {
+	if event.Name != eventMemberRemoved {
+		return false
+	}
+
+	var payload struct {
+		Data memberEventData `json:"data"`
+	}
+	err := json.Unmarshal(event.Data, &payload)
+	return err == nil && payload.Data.UserId == userId
+}
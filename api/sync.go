@@ -0,0 +1,513 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+var (
+	errInvalidChangeToken = errors.New("invalid change token")
+)
+
+const defaultSyncPageSize = 500
+
+/**
+ * 同步进度的有效期, 比墓碑的保留期短一天, 避免清理墓碑时删除了客户端还没有同步的删除
+ * 过期的同步进度需要重新完整同步
+ */
+const syncChangeTokenMaxAge = syncTombstoneRetention - 24*time.Hour
+
+/**
+ * 同步进度, 记录每个账单已同步到的change_seq, 以及这些进度中最早的同步时间
+ * 对客户端不透明, 以base64编码的json传递, 新加入的账单不在其中, 从头开始同步
+ */
+type changeToken struct {
+	Time time.Time
+	Seqs map[int64]int64
+}
+
+type changeTokenJSON struct {
+	Time int64            `json:"time"`
+	Seqs map[string]int64 `json:"seqs"`
+}
+
+func encodeChangeToken(t changeToken) string {
+	m := changeTokenJSON{
+		Time: t.Time.Unix(),
+		Seqs: make(map[string]int64, len(t.Seqs)),
+	}
+	for accountId, seq := range t.Seqs {
+		m.Seqs[strconv.FormatInt(accountId, 10)] = seq
+	}
+
+	data, _ := json.Marshal(m)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeChangeToken(s string) (changeToken, error) {
+	t := changeToken{Seqs: map[int64]int64{}}
+	if s == "" {
+		return t, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, errInvalidChangeToken
+	}
+
+	var m changeTokenJSON
+	err = json.Unmarshal(data, &m)
+	if err != nil || m.Time < 1 {
+		return t, errInvalidChangeToken
+	}
+	t.Time = time.Unix(m.Time, 0)
+
+	for key, seq := range m.Seqs {
+		var accountId int64
+		accountId, err = strconv.ParseInt(key, 10, 64)
+		if err != nil || accountId < 1 || seq < 0 {
+			return t, errInvalidChangeToken
+		}
+		t.Seqs[accountId] = seq
+	}
+
+	return t, nil
+}
+
+type syncChangesRequest struct {
+	// 为空时表示首次同步
+	ChangeToken string `json:"change_token"`
+	PageSize    int64  `json:"page_size" binding:"omitempty,min=1,max=1000"`
+}
+
+type syncAccount struct {
+	ID   int64            `json:"id"`
+	Name string           `json:"name"`
+	Role util.AccountRole `json:"role"`
+}
+
+type syncMember struct {
+	AccountId int64            `json:"account_id"`
+	UserId    int64            `json:"user_id"`
+	Role      util.AccountRole `json:"role"`
+}
+
+type syncDeletedRecord struct {
+	ID        int64  `json:"id"`
+	AccountId int64  `json:"account_id"`
+	ClientId  string `json:"client_id,omitempty"`
+}
+
+type syncCategory struct {
+	Type util.RecordType `json:"type"`
+	Name string          `json:"name"`
+}
+
+/**
+ * HasMore为true时应使用ChangeToken继续同步, 直到HasMore为false
+ * Reset为true时同步进度已过期, 客户端应丢弃本地数据, 从本次响应开始重新完整同步
+ */
+type syncChangesResponse struct {
+	Accounts          []syncAccount       `json:"accounts"`
+	RemovedAccountIds []int64             `json:"removed_account_ids"`
+	Members           []syncMember        `json:"members"`
+	RemovedMembers    []syncMember        `json:"removed_members"`
+	Records           []db.Record         `json:"records"`
+	DeletedRecords    []syncDeletedRecord `json:"deleted_records"`
+	// 记录类型是固定的, 只在首次同步和重新完整同步时返回
+	Categories  []syncCategory `json:"categories,omitempty"`
+	ChangeToken string         `json:"change_token"`
+	HasMore     bool           `json:"has_more"`
+	Reset       bool           `json:"reset"`
+}
+
+func newSyncChangesResponse() syncChangesResponse {
+	return syncChangesResponse{
+		Accounts:          []syncAccount{},
+		RemovedAccountIds: []int64{},
+		Members:           []syncMember{},
+		RemovedMembers:    []syncMember{},
+		Records:           []db.Record{},
+		DeletedRecords:    []syncDeletedRecord{},
+	}
+}
+
+func (resp *syncChangesResponse) addChanges(changes db.AccountChanges, role util.AccountRole) {
+	for _, change := range changes.Changes {
+		if !change.Deleted {
+			continue
+		}
+
+		switch change.Entity {
+		case db.SyncEntityRecord:
+			resp.DeletedRecords = append(resp.DeletedRecords, syncDeletedRecord{
+				ID:        change.EntityID,
+				AccountId: change.AccountID,
+				ClientId:  change.ClientID,
+			})
+		case db.SyncEntityMember:
+			resp.RemovedMembers = append(resp.RemovedMembers, syncMember{
+				AccountId: change.AccountID,
+				UserId:    change.EntityID,
+			})
+		}
+	}
+
+	resp.Records = append(resp.Records, changes.Records...)
+	for _, rule := range changes.Members {
+		resp.Members = append(resp.Members, syncMember{
+			AccountId: rule.AccountID,
+			UserId:    rule.UserID,
+			Role:      rule.Role,
+		})
+	}
+
+	if changes.Account != nil {
+		resp.Accounts = append(resp.Accounts, syncAccount{
+			ID:   changes.Account.ID,
+			Name: changes.Account.Name,
+			Role: role,
+		})
+	}
+}
+
+func syncCategories() []syncCategory {
+	categories := []syncCategory{}
+	for t := util.RecordType(util.RecordTypeFood); t <= util.RecordTypeGift; t++ {
+		categories = append(categories, syncCategory{Type: t, Name: util.RecordTypeName(t)})
+	}
+
+	return categories
+}
+
+/**
+ * 返回当前用户可以访问的所有账单自上次同步以来的变更
+ * 1. 同步进度过期时返回reset, 从头开始同步所有账单
+ * 2. 不再能访问的账单在removed_account_ids中返回
+ * 3. 按账单id的顺序依次读取每个账单的变更, 总数达到page_size时返回has_more
+ * 4. 全部同步完成时同步时间更新为当前时间, 分页过程中保留开始分页时的时间
+ */
+func (server *Server) syncChanges(ctx *gin.Context) {
+	var req syncChangesRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.PageSize == 0 {
+		req.PageSize = defaultSyncPageSize
+	}
+
+	var since changeToken
+	since, err = decodeChangeToken(req.ChangeToken)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var rules []db.AccountAccessRule
+	rules, err = server.db.GetAccountAccessRulesByUserId(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	now := time.Now()
+	resp := newSyncChangesResponse()
+	if req.ChangeToken != "" && since.Time.Before(now.Add(-syncChangeTokenMaxAge)) {
+		resp.Reset = true
+		since = changeToken{Seqs: map[int64]int64{}}
+	}
+	if since.Time.IsZero() {
+		since.Time = now
+		resp.Categories = syncCategories()
+	}
+
+	accessible := map[int64]bool{}
+	for _, rule := range rules {
+		accessible[rule.AccountID] = true
+	}
+	for accountId := range since.Seqs {
+		if !accessible[accountId] {
+			resp.RemovedAccountIds = append(resp.RemovedAccountIds, accountId)
+		}
+	}
+	sort.Slice(resp.RemovedAccountIds, func(i, j int) bool {
+		return resp.RemovedAccountIds[i] < resp.RemovedAccountIds[j]
+	})
+
+	next := changeToken{Time: since.Time, Seqs: map[int64]int64{}}
+	remaining := req.PageSize
+	for _, rule := range rules {
+		seq, ok := since.Seqs[rule.AccountID]
+		if remaining == 0 {
+			resp.HasMore = true
+			if ok {
+				next.Seqs[rule.AccountID] = seq
+			}
+			continue
+		}
+
+		var changes db.AccountChanges
+		changes, err = server.db.GetAccountChangesSince(ctx, rule.AccountID, seq, remaining+1)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if int64(len(changes.Changes)) > remaining {
+			changes.Changes = changes.Changes[:remaining]
+			resp.HasMore = true
+		}
+		if len(changes.Changes) > 0 {
+			seq = changes.Changes[len(changes.Changes)-1].ChangeSeq
+		}
+
+		next.Seqs[rule.AccountID] = seq
+		remaining -= int64(len(changes.Changes))
+		resp.addChanges(trimAccountChanges(changes), rule.Role)
+	}
+
+	if !resp.HasMore {
+		next.Time = now
+	}
+	resp.ChangeToken = encodeChangeToken(next)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+/**
+ * 分页截断变更后, 去掉不在本页变更中的实体
+ */
+func trimAccountChanges(changes db.AccountChanges) db.AccountChanges {
+	records := map[int64]bool{}
+	members := map[int64]bool{}
+	accountChanged := false
+	for _, change := range changes.Changes {
+		switch change.Entity {
+		case db.SyncEntityRecord:
+			records[change.EntityID] = true
+		case db.SyncEntityMember:
+			members[change.EntityID] = true
+		case db.SyncEntityAccount:
+			accountChanged = true
+		}
+	}
+
+	trimmed := db.AccountChanges{Changes: changes.Changes}
+	for _, record := range changes.Records {
+		if records[record.ID] {
+			trimmed.Records = append(trimmed.Records, record)
+		}
+	}
+	for _, rule := range changes.Members {
+		if members[rule.UserID] {
+			trimmed.Members = append(trimmed.Members, rule)
+		}
+	}
+	if accountChanged {
+		trimmed.Account = changes.Account
+	}
+
+	return trimmed
+}
+
+/**
+ * 客户端离线时的一次修改
+ * create需要client_id和所有字段, update和delete由id或client_id确定记录
+ * client_time是修改发生时客户端的毫秒时间戳, 晚于服务器时间时按服务器时间处理
+ */
+type syncMutationRequest struct {
+	Op         string           `json:"op" binding:"required,oneof=create update delete"`
+	AccountId  int64            `json:"account_id" binding:"required,min=1"`
+	ID         int64            `json:"id" binding:"omitempty,min=1"`
+	ClientId   string           `json:"client_id" binding:"max=64"`
+	ClientTime int64            `json:"client_time" binding:"required,min=1"`
+	Name       *string          `json:"name" binding:"omitempty,max=15"`
+	RecordType *util.RecordType `json:"record_type" binding:"omitempty,min=1,max=7"`
+	Date       *util.Date       `json:"date"`
+	Amount     *string          `json:"amount" binding:"omitempty,numeric"`
+}
+
+func (req syncMutationRequest) validate() error {
+	switch req.Op {
+	case db.SyncOpCreate:
+		if req.ClientId == "" {
+			return errors.New("client_id is required")
+		}
+		if req.Name == nil || req.RecordType == nil || req.Date == nil || req.Amount == nil {
+			return errors.New("name, record_type, date and amount are required")
+		}
+	case db.SyncOpUpdate:
+		if req.ID == 0 && req.ClientId == "" {
+			return errors.New("id or client_id is required")
+		}
+		if req.Name == nil && req.RecordType == nil && req.Date == nil && req.Amount == nil {
+			return errors.New("no field to update")
+		}
+	case db.SyncOpDelete:
+		if req.ID == 0 && req.ClientId == "" {
+			return errors.New("id or client_id is required")
+		}
+	}
+
+	return nil
+}
+
+func (req syncMutationRequest) mutation(now time.Time) db.SyncMutation {
+	clientTime := time.UnixMilli(req.ClientTime)
+	if clientTime.After(now) {
+		clientTime = now
+	}
+
+	mutation := db.SyncMutation{
+		Op:         req.Op,
+		AccountId:  req.AccountId,
+		Id:         req.ID,
+		ClientId:   req.ClientId,
+		ClientTime: clientTime,
+		Name:       req.Name,
+		RecordType: req.RecordType,
+		Amount:     req.Amount,
+	}
+	if req.Date != nil {
+		date := time.Time(*req.Date)
+		mutation.Date = &date
+	}
+
+	return mutation
+}
+
+type syncPushRequest struct {
+	Mutations []syncMutationRequest `json:"mutations" binding:"required,min=1,max=200,dive"`
+}
+
+type syncPushResponse struct {
+	Results []syncPushResult `json:"results"`
+}
+
+type syncPushResult struct {
+	Index     int        `json:"index"`
+	Status    string     `json:"status,omitempty"`
+	Record    *db.Record `json:"record,omitempty"`
+	Conflicts []string   `json:"conflicts,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+/**
+ * 按顺序在一个事务中写入客户端的修改, 逐条返回结果
+ * 冲突按字段以修改时间较晚者为准, 被覆盖的字段在conflicts中返回
+ */
+func (server *Server) syncPush(ctx *gin.Context) {
+	var req syncPushRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	accountIds := []int64{}
+	for i, item := range req.Mutations {
+		err = item.validate()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("mutations[%d]: %w", i, err)))
+			return
+		}
+		accountIds = append(accountIds, item.AccountId)
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	var denied map[int64]bool
+	denied, err = server.checkAccountAccessRules(ctx, authPayload.UserId, accountIds, util.AccountRoleManager)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if len(denied) > 0 {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccessDenied))
+		return
+	}
+
+	now := time.Now()
+	mutations := make([]db.SyncMutation, len(req.Mutations))
+	for i, item := range req.Mutations {
+		mutations[i] = item.mutation(now)
+	}
+
+	var results []db.SyncResult
+	err = server.execWithEvents(ctx, func(store *db.DB) ([]accountEvent, error) {
+		var err error
+		results, err = store.ApplySyncMutations(ctx, mutations, authPayload.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		events := []accountEvent{}
+		for i, result := range results {
+			if result.Err == nil && result.Changed {
+				events = append(events, syncPushEvent(ctx, mutations[i].Op, *result.Record))
+			}
+		}
+		return events, nil
+	})
+	if err != nil {
+		if err == db.ErrPeriodClosed || err == db.ErrRecordReconciled {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	changed := []db.Record{}
+	for _, result := range results {
+		if result.Err == nil && result.Changed && !result.Deleted {
+			changed = append(changed, *result.Record)
+		}
+	}
+	server.checkRecordsBudgetAlerts(ctx, changed)
+
+	resp := make([]syncPushResult, len(results))
+	for i, result := range results {
+		resp[i] = syncPushResult{
+			Index:     i,
+			Status:    result.Status,
+			Record:    result.Record,
+			Conflicts: result.Conflicts,
+			Deleted:   result.Deleted,
+		}
+		if result.Err != nil {
+			resp[i].Error = result.Err.Error()
+		}
+	}
+
+	ctx.JSON(http.StatusOK, syncPushResponse{Results: resp})
+}
+
+func syncPushEvent(ctx *gin.Context, op string, record db.Record) accountEvent {
+	event := eventRecordUpdated
+	switch op {
+	case db.SyncOpCreate:
+		event = eventRecordCreated
+	case db.SyncOpDelete:
+		event = eventRecordDeleted
+	}
+
+	return newAccountEvent(ctx, event, record.AccountID, record)
+}
@@ -27,6 +27,26 @@ func newUserResponse(user db.User) userResponse {
 	}
 }
 
+func newUserResponses(users []db.User) []userResponse {
+	resp := []userResponse{}
+	for i := range users {
+		resp = append(resp, newUserResponse(users[i]))
+	}
+
+	return resp
+}
+
+func newUserPageResponse(users []db.User, pageSize int64, kind cursorKind) pageResponse[userResponse] {
+	page := newPageResponse(users, pageSize, kind, func(user db.User) pageCursor {
+		return pageCursor{ID: user.ID}
+	})
+
+	return pageResponse[userResponse]{
+		Items:      newUserResponses(page.Items),
+		NextCursor: page.NextCursor,
+	}
+}
+
 type createUserRequest struct {
 	Name     string `json:"name" binding:"required,max=15"`
 	Email    string `json:"email" binding:"required,email"`
@@ -107,16 +127,20 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, resp)
 }
 
+/**
+ * 申请注销用户, 宽限期结束后由后台任务执行注销, 宽限期内可以取消
+ */
 func (server *Server) deleteUser(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	err := server.db.DeleteUser(ctx, authPayload.UserId)
+	eraseTime := time.Now().Add(userErasureGracePeriod)
+	erasure, err := server.db.CreateUserErasure(ctx, authPayload.UserId, eraseTime)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, nil)
+	ctx.JSON(http.StatusOK, erasure)
 }
 
 type updateUserNameRequest struct {
@@ -250,7 +274,9 @@ func (server *Server) getUserByEmail(ctx *gin.Context) {
 type getUsersByNameRequest struct {
 	Name     string `json:"name" binding:"required,max=15"`
 	PageSize int64  `json:"page_size" binding:"required,min=5,max=20"`
-	PageId   int64  `json:"page_id" binding:"required,min=1"`
+	Cursor   string `json:"cursor"`
+	// Deprecated: 使用cursor分页
+	PageId int64 `json:"page_id" binding:"omitempty,min=1"`
 }
 
 func (server *Server) getUsersByName(ctx *gin.Context) {
@@ -262,30 +288,46 @@ func (server *Server) getUsersByName(ctx *gin.Context) {
 	}
 
 	var users []db.User
-	offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
-	users, err = server.db.GetUsersByName(ctx, req.Name, offset, limit)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+	if req.PageId != 0 {
+		setDeprecatedPagination(ctx)
+		offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
+		users, err = server.db.GetUsersByName(ctx, req.Name, offset, limit)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+
+		ctx.JSON(http.StatusOK, newUserResponses(users))
 		return
 	}
 
-	resp := []userResponse{}
-	for i := range users {
-		resp = append(resp, newUserResponse(users[i]))
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorUsersByName)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	users, err = server.db.GetUsersByNameAfterCursor(ctx, req.Name, cursor.ID, req.PageSize+1)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserPageResponse(users, req.PageSize, cursorUsersByName))
 }
 
 type getUsersByAccountIdAndRoleRequest struct {
 	AccountId int64            `json:"account_id" binding:"required,min=1"`
 	Role      util.AccountRole `json:"role" binding:"required,min=1,max=2"`
 	PageSize  int64            `json:"page_size" binding:"required,min=5,max=20"`
-	PageId    int64            `json:"page_id" binding:"required,min=1"`
+	Cursor    string           `json:"cursor"`
+	// Deprecated: 使用cursor分页
+	PageId int64 `json:"page_id" binding:"omitempty,min=1"`
 }
 
 func (server *Server) getUsersByAccountIdAndRole(ctx *gin.Context) {
@@ -309,23 +351,43 @@ func (server *Server) getUsersByAccountIdAndRole(ctx *gin.Context) {
 	}
 
 	var users []db.User
-	offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
-	users, err = server.db.GetUsersByAccountIdAndRole(ctx, req.AccountId, req.Role, offset, limit)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+	if req.PageId != 0 {
+		setDeprecatedPagination(ctx)
+		offset, limit := (req.PageId-1)*req.PageSize, req.PageSize
+		users, err = server.db.GetUsersByAccountIdAndRole(ctx, req.AccountId, req.Role, offset, limit)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+
+		ctx.JSON(http.StatusOK, newUserResponses(users))
 		return
 	}
 
-	resp := []userResponse{}
-	for i := range users {
-		resp = append(resp, newUserResponse(users[i]))
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorUsersByAccount)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, resp)
+	users, err = server.db.GetUsersByAccountIdAndRoleAfterCursor(
+		ctx,
+		req.AccountId,
+		req.Role,
+		cursor.ID,
+		req.PageSize+1,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserPageResponse(users, req.PageSize, cursorUsersByAccount))
 }
 
 type getUsersCountByAccountIdAndRoleRequest struct {
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 申请注销到实际注销之间的宽限期
+ */
+const userErasureGracePeriod = 14 * 24 * time.Hour
+
+/**
+ * 个人数据导出的格式, 包含用户信息, 所属账单及角色, 用户创建的所有账单记录和评论
+ */
+type userDataExport struct {
+	ExportedAt  time.Time            `json:"exported_at"`
+	User        userResponse         `json:"user"`
+	Memberships []userDataMembership `json:"memberships"`
+	Records     []db.Record          `json:"records"`
+	Comments    []commentResponse    `json:"comments"`
+	Erasure     *db.UserErasure      `json:"erasure,omitempty"`
+}
+
+type userDataMembership struct {
+	AccountId   int64            `json:"account_id"`
+	AccountName string           `json:"account_name"`
+	Role        util.AccountRole `json:"role"`
+}
+
+func newUserDataExport(data db.UserData) userDataExport {
+	accountNames := make(map[int64]string, len(data.Accounts))
+	for _, account := range data.Accounts {
+		accountNames[account.ID] = account.Name
+	}
+
+	memberships := []userDataMembership{}
+	for _, rule := range data.AccountAccessRules {
+		memberships = append(memberships, userDataMembership{
+			AccountId:   rule.AccountID,
+			AccountName: accountNames[rule.AccountID],
+			Role:        rule.Role,
+		})
+	}
+
+	records := data.Records
+	if records == nil {
+		records = []db.Record{}
+	}
+
+	return userDataExport{
+		ExportedAt:  time.Now(),
+		User:        newUserResponse(data.User),
+		Memberships: memberships,
+		Records:     records,
+		Comments:    newCommentResponses(data.Comments),
+	}
+}
+
+func (server *Server) exportUserData(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	data, err := server.db.GetUserData(ctx, authPayload.UserId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserDataExport(data)
+
+	var erasure db.UserErasure
+	erasure, err = server.db.GetUserErasure(ctx, authPayload.UserId)
+	if err == nil {
+		resp.Erasure = &erasure
+	} else if err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-%s.json", data.User.ID, resp.ExportedAt.Format("20060102"))
+	setAttachmentHeaders(ctx, "application/json; charset=utf-8", filename)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) getUserErasure(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	erasure, err := server.db.GetUserErasure(ctx, authPayload.UserId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, erasure)
+}
+
+func (server *Server) cancelUserErasure(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.db.DeleteUserErasure(ctx, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
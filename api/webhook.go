@@ -0,0 +1,369 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/token"
+	"github.com/timelyrain/star-account/util"
+	"github.com/timelyrain/star-account/webhook"
+)
+
+var (
+	errInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+	errWebhookInactive   = errors.New("webhook is inactive")
+)
+
+/**
+ * 签名密钥只在创建时返回一次
+ */
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	AccountId  int64     `json:"account_id"`
+	Url        string    `json:"url"`
+	Events     []string  `json:"events"`
+	Active     bool      `json:"active"`
+	CreateTime time.Time `json:"create_time"`
+	Secret     string    `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(hook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:         hook.ID,
+		AccountId:  hook.AccountID,
+		Url:        hook.Url,
+		Events:     hook.Events,
+		Active:     hook.Active,
+		CreateTime: hook.CreateTime,
+	}
+}
+
+type webhookDeliveryResponse struct {
+	ID              int64      `json:"id"`
+	WebhookId       int64      `json:"webhook_id"`
+	Event           string     `json:"event"`
+	Payload         string     `json:"payload"`
+	Status          int32      `json:"status"`
+	Attempts        int32      `json:"attempts"`
+	NextAttemptTime time.Time  `json:"next_attempt_time"`
+	ResponseStatus  int32      `json:"response_status"`
+	Error           string     `json:"error"`
+	DeliveredTime   *time.Time `json:"delivered_time"`
+	CreateTime      time.Time  `json:"create_time"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:              delivery.ID,
+		WebhookId:       delivery.WebhookID,
+		Event:           delivery.Event,
+		Payload:         delivery.Payload,
+		Status:          delivery.Status,
+		Attempts:        delivery.Attempts,
+		NextAttemptTime: delivery.NextAttemptTime,
+		ResponseStatus:  delivery.ResponseStatus,
+		Error:           delivery.Error,
+		CreateTime:      delivery.CreateTime,
+	}
+	if delivery.DeliveredTime.Valid {
+		resp.DeliveredTime = &delivery.DeliveredTime.Time
+	}
+
+	return resp
+}
+
+/**
+ * 地址是IP或localhost时在创建时拒绝非公网地址, 域名在每次投递连接时检查解析结果
+ */
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errInvalidWebhookURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return webhook.ErrForbiddenAddress
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err == nil && !webhook.IsPublicAddr(addr) {
+		return webhook.ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+/**
+ * 查询webhook并检查当前用户是否为其账单的拥有者
+ * 出错时已经写入响应, 返回false
+ */
+func (server *Server) getOwnedWebhook(ctx *gin.Context, id int64) (db.Webhook, bool) {
+	hook, err := server.db.GetWebhook(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return hook, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, hook.AccountID, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return hook, false
+	}
+
+	return hook, true
+}
+
+type createWebhookRequest struct {
+	AccountId int64    `json:"account_id" binding:"required,min=1"`
+	Url       string   `json:"url" binding:"required,max=2048"`
+	Events    []string `json:"events" binding:"required,min=1,max=6,dive,oneof=record.created record.updated record.deleted member.added member.removed account.renamed"`
+}
+
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = validateWebhookURL(req.Url)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var secret string
+	secret, err = webhook.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var hook db.Webhook
+	hook, err = server.db.CreateWebhook(ctx, req.AccountId, req.Url, secret, req.Events, authPayload.UserId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newWebhookResponse(hook)
+	resp.Secret = hook.Secret
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type getWebhooksRequest struct {
+	AccountId int64 `json:"account_id" binding:"required,min=1"`
+}
+
+func (server *Server) getWebhooks(ctx *gin.Context) {
+	var req getWebhooksRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err = server.checkAccountAccessRule(ctx, authPayload.UserId, req.AccountId, util.AccountRoleOwner)
+	if err != nil {
+		if err == errAccessDenied {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	var hooks []db.Webhook
+	hooks, err = server.db.GetWebhooksByAccountId(ctx, req.AccountId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := []webhookResponse{}
+	for _, hook := range hooks {
+		resp = append(resp, newWebhookResponse(hook))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type updateWebhookRequest struct {
+	ID     int64    `json:"id" binding:"required,min=1"`
+	Url    string   `json:"url" binding:"required,max=2048"`
+	Events []string `json:"events" binding:"required,min=1,max=6,dive,oneof=record.created record.updated record.deleted member.added member.removed account.renamed"`
+	Active bool     `json:"active"`
+}
+
+func (server *Server) updateWebhook(ctx *gin.Context) {
+	var req updateWebhookRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err = validateWebhookURL(req.Url)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getOwnedWebhook(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	var hook db.Webhook
+	hook, err = server.db.UpdateWebhook(ctx, req.ID, req.Url, req.Events, req.Active)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookResponse(hook))
+}
+
+type deleteWebhookRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteWebhook(ctx *gin.Context) {
+	var req deleteWebhookRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getOwnedWebhook(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	err = server.db.DeleteWebhook(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, nil)
+}
+
+type getWebhookDeliveriesRequest struct {
+	WebhookId int64  `json:"webhook_id" binding:"required,min=1"`
+	PageSize  int64  `json:"page_size" binding:"required,min=5,max=20"`
+	Cursor    string `json:"cursor"`
+}
+
+func (server *Server) getWebhookDeliveries(ctx *gin.Context) {
+	var req getWebhookDeliveriesRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var cursor pageCursor
+	cursor, err = decodeCursor(req.Cursor, cursorWebhookDeliveries)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, ok := server.getOwnedWebhook(ctx, req.WebhookId)
+	if !ok {
+		return
+	}
+
+	var deliveries []db.WebhookDelivery
+	deliveries, err = server.db.GetWebhookDeliveriesByWebhookIdAfterCursor(ctx, req.WebhookId, cursor.ID, req.PageSize+1)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	page := newPageResponse(deliveries, req.PageSize, cursorWebhookDeliveries, func(delivery db.WebhookDelivery) pageCursor {
+		return pageCursor{ID: delivery.ID}
+	})
+
+	resp := pageResponse[webhookDeliveryResponse]{
+		Items:      []webhookDeliveryResponse{},
+		NextCursor: page.NextCursor,
+	}
+	for _, delivery := range page.Items {
+		resp.Items = append(resp.Items, newWebhookDeliveryResponse(delivery))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type redeliverWebhookDeliveryRequest struct {
+	ID int64 `json:"id" binding:"required,min=1"`
+}
+
+func (server *Server) redeliverWebhookDelivery(ctx *gin.Context) {
+	var req redeliverWebhookDeliveryRequest
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var delivery db.WebhookDelivery
+	delivery, err = server.db.GetWebhookDelivery(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	_, ok := server.getOwnedWebhook(ctx, delivery.WebhookID)
+	if !ok {
+		return
+	}
+
+	delivery, err = server.db.RedeliverWebhookDelivery(ctx, req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookDeliveryResponse(delivery))
+}
@@ -0,0 +1,378 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/mail"
+	"github.com/timelyrain/star-account/util"
+	"github.com/timelyrain/star-account/webhook"
+)
+
+const (
+	userErasureInterval  = time.Hour
+	userErasureBatchSize = 100
+	// 注销失败达到该次数后不再重试, 错误保留在user_erasures中等待人工处理
+	userErasureMaxAttempts = 5
+
+	idempotencyKeyCleanupInterval  = time.Hour
+	idempotencyKeyCleanupBatchSize = 1000
+
+	syncTombstoneCleanupInterval  = time.Hour
+	syncTombstoneCleanupBatchSize = 1000
+	// 墓碑的保留期, 超过保留期没有同步的客户端需要重新完整同步, 见syncChangeTokenMaxAge
+	syncTombstoneRetention = 90 * 24 * time.Hour
+
+	webhookDeliveryInterval  = 10 * time.Second
+	webhookDeliveryBatchSize = 50
+	// 领取的投递在租约期间内不会被其他实例重复领取, 一批投递逐个发送, 需大于一批投递的最长耗时
+	webhookDeliveryLease = webhookDeliveryBatchSize*webhookTimeout + time.Minute
+
+	blobDeletionInterval  = time.Minute
+	blobDeletionBatchSize = 100
+
+	notificationEmailInterval    = 30 * time.Second
+	notificationEmailBatchSize   = 50
+	notificationEmailLease       = 5 * time.Minute
+	notificationEmailRetryDelay  = 10 * time.Minute
+	notificationEmailMaxAttempts = 5
+
+	digestInterval  = 5 * time.Minute
+	digestBatchSize = 20
+	// 发送失败的摘要在租约到期后重试
+	digestLease = 30 * time.Minute
+)
+
+/**
+ * 启动后台任务, ctx取消时所有任务退出
+ * dataSource用于建立监听账单事件的数据库连接
+ */
+func (server *Server) StartWorkers(ctx context.Context, dataSource string) {
+	go server.listenAccountEvents(ctx, dataSource)
+	go runPeriodically(ctx, userErasureInterval, server.eraseDueUsers)
+	go runPeriodically(ctx, idempotencyKeyCleanupInterval, server.deleteExpiredIdempotencyKeys)
+	go runPeriodically(ctx, syncTombstoneCleanupInterval, server.deleteSyncTombstones)
+	go runPeriodically(ctx, webhookDeliveryInterval, server.deliverWebhooks)
+	go runPeriodically(ctx, blobDeletionInterval, server.deleteBlobs)
+	go runPeriodically(ctx, notificationEmailInterval, server.sendNotificationEmails)
+	go runPeriodically(ctx, digestInterval, server.sendDigests)
+}
+
+/**
+ * 立即执行一次fn, 之后每隔interval执行一次, 直到ctx取消
+ */
+func runPeriodically(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/**
+ * 注销宽限期已结束的用户, 一批处理满时继续处理下一批
+ * 注销失败的用户会被跳过, 留到下一次运行时重试, 不会阻塞之后的用户
+ */
+func (server *Server) eraseDueUsers(ctx context.Context) {
+	now := time.Now()
+	var cursorTime time.Time
+	var cursorId int64
+
+	for {
+		erasures, err := server.db.GetDueUserErasuresAfterCursor(ctx, now, userErasureMaxAttempts, cursorTime, cursorId, userErasureBatchSize)
+		if err != nil {
+			log.Println("cannot get due user erasures: ", err)
+			return
+		}
+
+		for _, erasure := range erasures {
+			err = server.db.EraseUser(ctx, erasure.UserID)
+			if err == nil {
+				continue
+			}
+
+			log.Println("cannot erase user: ", erasure.UserID, err)
+			if erasure.Attempts+1 >= userErasureMaxAttempts {
+				log.Println("giving up erasing user: ", erasure.UserID)
+			}
+			err = server.db.UpdateUserErasureAttempt(ctx, erasure.UserID, err.Error())
+			if err != nil {
+				log.Println("cannot update user erasure: ", err)
+			}
+		}
+
+		if len(erasures) < userErasureBatchSize {
+			return
+		}
+
+		last := erasures[len(erasures)-1]
+		cursorTime, cursorId = last.EraseTime, last.UserID
+	}
+}
+
+/**
+ * 删除已经过期的幂等键记录, 一批删除满时继续删除下一批
+ */
+func (server *Server) deleteExpiredIdempotencyKeys(ctx context.Context) {
+	for {
+		n, err := server.db.DeleteExpiredIdempotencyKeys(ctx, idempotencyKeyCleanupBatchSize)
+		if err != nil {
+			log.Println("cannot delete expired idempotency keys: ", err)
+			return
+		}
+
+		if n < idempotencyKeyCleanupBatchSize {
+			return
+		}
+	}
+}
+
+/**
+ * 删除超过保留期的同步墓碑, 一批删除满时继续删除下一批
+ */
+func (server *Server) deleteSyncTombstones(ctx context.Context) {
+	before := time.Now().Add(-syncTombstoneRetention)
+	for {
+		n, err := server.db.DeleteSyncTombstonesBefore(ctx, before, syncTombstoneCleanupBatchSize)
+		if err != nil {
+			log.Println("cannot delete sync tombstones: ", err)
+			return
+		}
+
+		if n < syncTombstoneCleanupBatchSize {
+			return
+		}
+	}
+}
+
+/**
+ * 投递到期的webhook请求, 一批处理满时继续处理下一批
+ */
+func (server *Server) deliverWebhooks(ctx context.Context) {
+	for {
+		now := time.Now()
+		leaseUntil := now.Add(webhookDeliveryLease)
+		deliveries, err := server.db.ClaimWebhookDeliveries(ctx, now, leaseUntil, webhookDeliveryBatchSize)
+		if err != nil {
+			log.Println("cannot claim webhook deliveries: ", err)
+			return
+		}
+
+		if len(deliveries) == 0 {
+			return
+		}
+
+		ids := make([]int64, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.WebhookID
+		}
+
+		var hooks []db.Webhook
+		hooks, err = server.db.GetWebhooksByIds(ctx, ids)
+		if err != nil {
+			log.Println("cannot get webhooks: ", err)
+			return
+		}
+
+		hookById := make(map[int64]db.Webhook, len(hooks))
+		for _, hook := range hooks {
+			hookById[hook.ID] = hook
+		}
+
+		for _, delivery := range deliveries {
+			// 租约可能在发送期间到期时留给租约到期后重新领取, 避免与其他实例重复投递
+			if time.Now().Add(webhookTimeout).After(leaseUntil) {
+				return
+			}
+			server.deliverWebhook(ctx, hookById[delivery.WebhookID], delivery)
+		}
+
+		if len(deliveries) < webhookDeliveryBatchSize {
+			return
+		}
+	}
+}
+
+/**
+ * 发送一次投递并记录结果, 失败时按指数退避安排下一次投递
+ */
+func (server *Server) deliverWebhook(ctx context.Context, hook db.Webhook, delivery db.WebhookDelivery) {
+	var statusCode int
+	var err error
+	if hook.Active {
+		statusCode, err = server.webhookSender.Send(
+			ctx,
+			hook.Url,
+			hook.Secret,
+			delivery.ID,
+			delivery.Event,
+			[]byte(delivery.Payload),
+		)
+	} else {
+		err = errWebhookInactive
+	}
+
+	now := time.Now()
+	status := int32(util.WebhookDeliveryStatusDelivered)
+	nextAttemptTime := now
+	deliveredTime := now
+	errMsg := ""
+	if err != nil {
+		attempts := delivery.Attempts + 1
+		status = util.WebhookDeliveryStatusPending
+		nextAttemptTime = now.Add(webhook.Backoff(attempts))
+		deliveredTime = time.Time{}
+		errMsg = err.Error()
+		if attempts >= webhook.MaxAttempts || !hook.Active {
+			status = util.WebhookDeliveryStatusFailed
+		}
+	}
+
+	err = server.db.UpdateWebhookDeliveryAttempt(
+		ctx,
+		delivery.ID,
+		status,
+		nextAttemptTime,
+		int32(statusCode),
+		errMsg,
+		deliveredTime,
+	)
+	if err != nil {
+		log.Println("cannot update webhook delivery: ", err)
+	}
+}
+
+/**
+ * 从BlobStore中删除已删除附件的内容, 一批处理满时继续处理下一批
+ * 有删除失败时留到下一次执行, 避免反复重试同一批
+ */
+func (server *Server) deleteBlobs(ctx context.Context) {
+	for {
+		deletions, err := server.db.GetBlobDeletions(ctx, blobDeletionBatchSize)
+		if err != nil {
+			log.Println("cannot get blob deletions: ", err)
+			return
+		}
+
+		failed := false
+		for _, deletion := range deletions {
+			err = server.blobStore.Delete(ctx, deletion.BlobKey)
+			if err == nil {
+				err = server.db.DeleteBlobDeletion(ctx, deletion.BlobKey)
+			}
+			if err != nil {
+				log.Println("cannot delete blob: ", err)
+				failed = true
+			}
+		}
+
+		if failed || len(deletions) < blobDeletionBatchSize {
+			return
+		}
+	}
+}
+
+/**
+ * 发送到期的通知邮件, 一批处理满时继续处理下一批
+ */
+func (server *Server) sendNotificationEmails(ctx context.Context) {
+	for {
+		now := time.Now()
+		notifications, err := server.db.ClaimNotificationEmails(ctx, now, now.Add(notificationEmailLease), notificationEmailBatchSize)
+		if err != nil {
+			log.Println("cannot claim notification emails: ", err)
+			return
+		}
+
+		if len(notifications) == 0 {
+			return
+		}
+
+		var users []db.User
+		users, err = server.db.GetUsersByIds(ctx, notificationUserIds(notifications))
+		if err != nil {
+			log.Println("cannot get users: ", err)
+			return
+		}
+
+		userById := make(map[int64]db.User, len(users))
+		for _, user := range users {
+			userById[user.ID] = user
+		}
+
+		for _, notification := range notifications {
+			server.sendNotificationEmail(ctx, notification, userById)
+		}
+
+		if len(notifications) < notificationEmailBatchSize {
+			return
+		}
+	}
+}
+
+/**
+ * 发送一封通知邮件并记录结果, 失败时间隔固定时间重试
+ */
+func (server *Server) sendNotificationEmail(ctx context.Context, notification db.Notification, userById map[int64]db.User) {
+	var data notificationData
+	err := json.Unmarshal(notification.Data, &data)
+
+	var msg mail.Message
+	if err == nil {
+		msg, err = newNotificationEmail(notification, userById[notification.UserID], userById[data.ActorUserId].Name)
+	}
+	if err == nil {
+		err = server.mailSender.Send(ctx, msg)
+	}
+
+	now := time.Now()
+	status := int32(util.NotificationEmailStatusSent)
+	errMsg := ""
+	if err != nil {
+		status = util.NotificationEmailStatusPending
+		errMsg = err.Error()
+		if notification.EmailAttempts+1 >= notificationEmailMaxAttempts {
+			status = util.NotificationEmailStatusFailed
+		}
+	}
+
+	err = server.db.UpdateNotificationEmailAttempt(ctx, notification.ID, status, now.Add(notificationEmailRetryDelay), errMsg)
+	if err != nil {
+		log.Println("cannot update notification email: ", err)
+	}
+}
+
+/**
+ * 发送到期的摘要邮件, 一批处理满时继续处理下一批
+ */
+func (server *Server) sendDigests(ctx context.Context) {
+	for {
+		now := time.Now()
+		subs, err := server.db.ClaimDueDigestSubscriptions(ctx, now, now.Add(digestLease), digestBatchSize)
+		if err != nil {
+			log.Println("cannot claim digest subscriptions: ", err)
+			return
+		}
+
+		for _, sub := range subs {
+			err = server.sendDigest(ctx, sub, now)
+			if err != nil {
+				log.Println("cannot send digest: ", err)
+			}
+		}
+
+		if len(subs) < digestBatchSize {
+			return
+		}
+	}
+}
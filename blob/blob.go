@@ -0,0 +1,19 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+/**
+ * 附件等二进制内容的存储, key由调用者生成, 只包含字母, 数字, '-'和'/'
+ * 删除不存在的key视为删除成功
+ */
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,108 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errInvalidKey = errors.New("invalid blob key")
+
+/**
+ * 保存在本地目录中的BlobStore, 适合单实例部署和开发环境
+ */
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) (*LocalStore, error) {
+	err := os.MkdirAll(root, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{root: root}, nil
+}
+
+/**
+ * key不能跳出根目录
+ */
+func (store *LocalStore) path(key string) (string, error) {
+	if key == "" || !fs.ValidPath(key) || strings.Contains(key, `\`) {
+		return "", errInvalidKey
+	}
+
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
+
+/**
+ * 先写入临时文件再重命名, 读取者不会看到写了一半的内容
+ */
+func (store *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return err
+	}
+
+	var tmp *os.File
+	tmp, err = os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (store *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var file *os.File
+	file, err = os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = ErrNotFound
+		}
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (store *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
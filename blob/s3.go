@@ -0,0 +1,232 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	s3Service       = "s3"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3DateFormat    = "20060102"
+	s3TimeFormat    = "20060102T150405Z"
+	s3SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+)
+
+/**
+ * 错误响应体最多读取的字节数
+ */
+const s3MaxErrorBodySize = 1 << 10
+
+/**
+ * 兼容S3接口的对象存储, 如AWS S3, MinIO和各云厂商的对象存储
+ * 使用路径风格的地址 {Endpoint}/{Bucket}/{key}, 请求以Signature V4签名
+ */
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyId     string
+	SecretAccessKey string
+}
+
+type S3Store struct {
+	config S3Config
+	client *http.Client
+}
+
+func NewS3Store(config S3Config, timeout time.Duration) (*S3Store, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", config.Endpoint)
+	}
+
+	if config.Bucket == "" || config.Region == "" {
+		return nil, fmt.Errorf("s3 bucket and region are required")
+	}
+
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+
+	return &S3Store{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (store *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := store.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	var res *http.Response
+	res, err = store.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return s3Error(res)
+	}
+
+	return nil
+}
+
+func (store *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := store.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *http.Response
+	res, err = store.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, s3Error(res)
+	}
+
+	return res.Body, nil
+}
+
+/**
+ * S3删除不存在的对象也返回204
+ */
+func (store *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := store.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	var res *http.Response
+	res, err = store.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return s3Error(res)
+	}
+
+	return nil
+}
+
+func (store *S3Store) newRequest(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return nil, errInvalidKey
+	}
+
+	path := "/" + escapePath(store.config.Bucket) + "/" + escapePath(key)
+	req, err := http.NewRequestWithContext(ctx, method, store.config.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	store.sign(req, path, body, time.Now().UTC())
+	return req, nil
+}
+
+/**
+ * 按Signature V4签名请求, 签名覆盖host, 请求体摘要和请求时间
+ * 查询参数需要调用方按规范排序和编码
+ * https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+ */
+func (store *S3Store) sign(req *http.Request, path string, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzTime := now.Format(s3TimeFormat)
+	date := now.Format(s3DateFormat)
+
+	req.Header.Set("X-Amz-Date", amzTime)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzTime,
+		"",
+		s3SignedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + store.config.Region + "/" + s3Service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		amzTime,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+store.config.SecretAccessKey), date)
+	key = hmacSHA256(key, store.config.Region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm,
+		store.config.AccessKeyId,
+		scope,
+		s3SignedHeaders,
+		signature,
+	))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+/**
+ * 按S3的规则编码路径, 除了非保留字符和'/'以外都要编码
+ */
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func s3Error(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, s3MaxErrorBodySize))
+	return fmt.Errorf("s3 %s %s: %s: %s", res.Request.Method, res.Request.URL.Path, res.Status, bytes.TrimSpace(body))
+}
@@ -0,0 +1,96 @@
+package broker
+
+import "sync"
+
+/**
+ * 订阅者缓冲的事件数, 缓冲满时订阅被关闭, 由客户端重新订阅
+ */
+const subscriptionBufferSize = 64
+
+/**
+ * AccountId为0的事件发送给所有订阅者
+ */
+type Event struct {
+	AccountId int64
+	Name      string
+	Data      []byte
+}
+
+/**
+ * 进程内的事件分发, 每个订阅者只接收其订阅账单的事件
+ */
+type Broker struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+}
+
+func New() *Broker {
+	return &Broker{subscriptions: map[*Subscription]struct{}{}}
+}
+
+type Subscription struct {
+	broker     *Broker
+	accountIds map[int64]bool
+	events     chan Event
+}
+
+func (broker *Broker) Subscribe(accountIds []int64) *Subscription {
+	sub := &Subscription{
+		broker:     broker,
+		accountIds: make(map[int64]bool, len(accountIds)),
+		events:     make(chan Event, subscriptionBufferSize),
+	}
+	for _, id := range accountIds {
+		sub.accountIds[id] = true
+	}
+
+	broker.mu.Lock()
+	broker.subscriptions[sub] = struct{}{}
+	broker.mu.Unlock()
+
+	return sub
+}
+
+/**
+ * 非阻塞地分发事件, 跟不上的订阅者会被关闭
+ */
+func (broker *Broker) Publish(event Event) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for sub := range broker.subscriptions {
+		if event.AccountId != 0 && !sub.accountIds[event.AccountId] {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			broker.remove(sub)
+		}
+	}
+}
+
+/**
+ * 调用者需持有锁
+ */
+func (broker *Broker) remove(sub *Subscription) {
+	if _, ok := broker.subscriptions[sub]; ok {
+		delete(broker.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+/**
+ * 订阅的事件, 订阅关闭后channel被关闭
+ */
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+func (sub *Subscription) Close() {
+	sub.broker.mu.Lock()
+	defer sub.broker.mu.Unlock()
+
+	sub.broker.remove(sub)
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/timelyrain/star-account/db/sqlc"
 )
@@ -35,13 +36,77 @@ func (db *DB) CreateAccount(ctx context.Context, name string, userId int64) (Acc
 }
 
 /**
- * 1. 删除账单中的所有账单记录
- * 2. 删除账单中的所有权限信息
- * 3. 删除账单信息
+ * 1. 删除账单中的所有附件信息, 评论, 审批规则, 审批历史, 关账记录, 期初余额, 余额检查点和账单记录, 附件内容由后台任务删除
+ *    先删除关账记录, 使关账期间内的记录可以删除
+ * 2. 删除账单中的所有预算和预算提醒
+ * 3. 删除账单中的所有webhook和投递记录
+ * 4. 删除账单中的所有权限信息
+ * 5. 删除账单的同步变更记录, 包括前面的删除产生的墓碑
+ * 6. 删除账单信息
  */
 func (db *DB) DeleteAccount(ctx context.Context, id int64) error {
 	return db.execTx(ctx, func(q *sqlc.Queries) error {
-		err := q.DeleteRecordsByAccountId(ctx, id)
+		err := q.DeleteAttachmentsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordCommentsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteCommentReadsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordApprovalsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteApprovalRulesByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeletePeriodClosingsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteOpeningBalance(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteBalanceCheckpointsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteBudgetAlertsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteBudgetsByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteWebhookDeliveriesByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteWebhooksByAccountId(ctx, id)
 		if err != nil {
 			return err
 		}
@@ -51,6 +116,11 @@ func (db *DB) DeleteAccount(ctx context.Context, id int64) error {
 			return err
 		}
 
+		err = q.DeleteSyncChangesByAccountId(ctx, id)
+		if err != nil {
+			return err
+		}
+
 		return q.DeleteAccount(ctx, id)
 	})
 }
@@ -67,6 +137,18 @@ func (db *DB) GetAccount(ctx context.Context, id int64) (Account, error) {
 	return res, err
 }
 
+func (db *DB) GetAccountsByIds(ctx context.Context, ids []int64) ([]Account, error) {
+	var res []Account
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetAccountsByIds(ctx, ids)
+		return err
+	})
+
+	return res, err
+}
+
 func (db *DB) GetAccountsCountByUserIdAndRole(ctx context.Context, userId int64, role int32) (int64, error) {
 	var res int64
 
@@ -114,6 +196,34 @@ func (db *DB) GetAccountsByUserIdAndRole(
 	return res, err
 }
 
+/**
+ * 按id DESC的顺序查询游标之后的账单, cursorId为0时从头开始
+ */
+func (db *DB) GetAccountsByUserIdAndRoleAfterCursor(
+	ctx context.Context,
+	userId int64,
+	role int32,
+	cursorId int64,
+	limit int64,
+) ([]Account, error) {
+	var res []Account
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetAccountsByUserIdAndRoleAfterCursorParams{
+			UserID:    userId,
+			Role:      role,
+			CursorID:  nullInt64(cursorId),
+			PageLimit: limit,
+		}
+
+		res, err = q.GetAccountsByUserIdAndRoleAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
 func (db *DB) UpdateAccountName(ctx context.Context, id int64, name string) error {
 	return db.exec(ctx, func(q *sqlc.Queries) error {
 		arg := sqlc.UpdateAccountNameParams{
@@ -123,3 +233,35 @@ func (db *DB) UpdateAccountName(ctx context.Context, id int64, name string) erro
 		return q.UpdateAccountName(ctx, arg)
 	})
 }
+
+type AccountSummary = sqlc.GetAccountSummariesByUserIdRow
+
+/**
+ * 一次查询用户能访问的所有账单的概况
+ * 月份的边界由调用方按用户所在时区计算
+ * 最近活动时间取记录的创建, 修改和删除(sync_changes)以及评论中最晚的时间
+ */
+func (db *DB) GetAccountSummariesByUserId(
+	ctx context.Context,
+	userId int64,
+	lastMonthStart time.Time,
+	thisMonthStart time.Time,
+	nextMonthStart time.Time,
+) ([]AccountSummary, error) {
+	var res []AccountSummary
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetAccountSummariesByUserIdParams{
+			UserID:         userId,
+			ThisMonthStart: thisMonthStart,
+			NextMonthStart: nextMonthStart,
+			LastMonthStart: lastMonthStart,
+		}
+
+		res, err = q.GetAccountSummariesByUserId(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
@@ -57,3 +57,30 @@ func (db *DB) GetAccountAccessRuleByUserIdAndAccountId(
 
 	return res, err
 }
+
+/**
+ * 用户可以访问的所有账单的权限信息, 按账单id排序
+ */
+func (db *DB) GetAccountAccessRulesByUserId(ctx context.Context, userId int64) ([]AccountAccessRule, error) {
+	var res []AccountAccessRule
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetAccountAccessRulesByUserId(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetAccountAccessRulesByAccountId(ctx context.Context, accountId int64) ([]AccountAccessRule, error) {
+	var res []AccountAccessRule
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetAccountAccessRulesByAccountId(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
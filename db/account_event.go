@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+/**
+ * 账单事件的NOTIFY频道, 所有服务实例都监听该频道
+ */
+const accountEventsChannel = "account_events"
+
+/**
+ * 监听连接断开后的重连间隔
+ */
+const (
+	listenerMinReconnectInterval = 10 * time.Second
+	listenerMaxReconnectInterval = time.Minute
+)
+
+/**
+ * 账单中发生的事件, Payload是已经序列化的事件内容
+ */
+type AccountEvent struct {
+	AccountId int64
+	Event     string
+	Payload   string
+}
+
+/**
+ * 在一个事务中执行fn并发布fn返回的事件, fn通过tx调用的方法都在该事务中执行
+ * 写入和事件一起提交, 不会出现写入成功而事件丢失的情况
+ */
+func (db *DB) ExecWithEvents(ctx context.Context, fn func(tx *DB) ([]AccountEvent, error)) error {
+	if db.tx != nil {
+		return errors.New("ExecWithEvents cannot be nested")
+	}
+
+	sqlTx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var events []AccountEvent
+	events, err = fn(&DB{conn: db.conn, tx: sqlTx})
+	if err == nil {
+		err = translateError(publishAccountEvents(ctx, sqlc.New(sqlTx), events))
+	}
+	if err != nil {
+		rbErr := sqlTx.Rollback()
+		if rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+
+		return err
+	}
+
+	return sqlTx.Commit()
+}
+
+/**
+ * 在写入的事务中发布事件
+ * 1. 为每个订阅了事件的webhook创建一条待投递的记录
+ * 2. 通过NOTIFY通知所有服务实例, 通知在事务提交后才会送达
+ */
+func publishAccountEvents(ctx context.Context, q *sqlc.Queries, events []AccountEvent) error {
+	for _, event := range events {
+		deliveriesArg := sqlc.CreateWebhookDeliveriesParams{
+			Event:     event.Event,
+			Payload:   event.Payload,
+			AccountID: event.AccountId,
+		}
+
+		err := q.CreateWebhookDeliveries(ctx, deliveriesArg)
+		if err != nil {
+			return err
+		}
+
+		notifyArg := sqlc.NotifyAccountEventParams{
+			Channel: accountEventsChannel,
+			Payload: event.Payload,
+		}
+
+		err = q.NotifyAccountEvent(ctx, notifyArg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+ * 监听所有服务实例发布的事件, 对每个事件调用handle, 直到ctx取消
+ * 连接断开期间的事件会丢失, 重连后调用handle("")通知调用者
+ */
+func ListenAccountEvents(ctx context.Context, dataSource string, handle func(payload string)) error {
+	listener := pq.NewListener(dataSource, listenerMinReconnectInterval, listenerMaxReconnectInterval, nil)
+	defer listener.Close()
+
+	err := listener.Listen(accountEventsChannel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// 重连后会收到nil
+			if n == nil {
+				handle("")
+				continue
+			}
+			handle(n.Extra)
+		case <-time.After(listenerMaxReconnectInterval):
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type Attachment = sqlc.Attachment
+
+type BlobDeletion = sqlc.BlobDeletion
+
+var (
+	ErrAttachmentQuotaExceeded = errors.New("attachment quota exceeded")
+	ErrTooManyAttachments      = errors.New("too many attachments")
+)
+
+/**
+ * 1. 锁定账单记录和账单, 同一账单的上传串行地检查配额
+ * 2. 检查记录的附件数量和账单的附件总大小
+ * 3. 写入附件信息
+ * 记录不存在时返回sql.ErrNoRows
+ */
+func (db *DB) CreateAttachment(
+	ctx context.Context,
+	recordId int64,
+	blobKey string,
+	filename string,
+	contentType string,
+	size int64,
+	createUserId int64,
+	maxCount int64,
+	quota int64,
+) (Attachment, error) {
+	var res Attachment
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		record, err := q.GetRecordForUpdate(ctx, recordId)
+		if err != nil {
+			return err
+		}
+
+		err = q.LockAccount(ctx, record.AccountID)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		count, err = q.GetAttachmentsCountByRecordId(ctx, recordId)
+		if err != nil {
+			return err
+		}
+
+		if count >= maxCount {
+			return ErrTooManyAttachments
+		}
+
+		var used int64
+		used, err = q.GetAttachmentsSizeSumByAccountId(ctx, record.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if used+size > quota {
+			return ErrAttachmentQuotaExceeded
+		}
+
+		arg := sqlc.CreateAttachmentParams{
+			RecordID:     recordId,
+			AccountID:    record.AccountID,
+			BlobKey:      blobKey,
+			Filename:     filename,
+			ContentType:  contentType,
+			Size:         size,
+			CreateUserID: createUserId,
+		}
+
+		res, err = q.CreateAttachment(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetAttachment(ctx context.Context, id int64) (Attachment, error) {
+	var res Attachment
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetAttachment(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetAttachmentsByRecordId(ctx context.Context, recordId int64) ([]Attachment, error) {
+	var res []Attachment
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetAttachmentsByRecordId(ctx, recordId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetAttachmentsSizeSumByAccountId(ctx context.Context, accountId int64) (int64, error) {
+	var res int64
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetAttachmentsSizeSumByAccountId(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 删除附件信息, 内容由后台任务从BlobStore中删除
+ */
+func (db *DB) DeleteAttachment(ctx context.Context, id int64) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteAttachment(ctx, id)
+	})
+}
+
+/**
+ * 登记没有对应附件信息的内容, 如写入附件信息失败后的上传
+ */
+func (db *DB) CreateBlobDeletion(ctx context.Context, blobKey string) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.CreateBlobDeletion(ctx, blobKey)
+	})
+}
+
+func (db *DB) GetBlobDeletions(ctx context.Context, limit int32) ([]BlobDeletion, error) {
+	var res []BlobDeletion
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetBlobDeletions(ctx, limit)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) DeleteBlobDeletion(ctx context.Context, blobKey string) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteBlobDeletion(ctx, blobKey)
+	})
+}
@@ -0,0 +1,269 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+	"github.com/timelyrain/star-account/util"
+)
+
+const restoreRecordsBatchSize = 1000
+
+/**
+ * 账单及与其关联的所有数据, 用于备份
+ */
+type AccountBackup struct {
+	Account       Account
+	AccessRules   []AccountAccessRule
+	Users         []User
+	Records       []Record
+	Budgets       []Budget
+	BudgetAlerts  []BudgetAlert
+	ApprovalRules []ApprovalRule
+}
+
+/**
+ * 在一个事务中读取账单, 成员, 记录, 预算, 预算提醒和审批规则
+ */
+func (db *DB) GetAccountBackup(ctx context.Context, accountId int64) (AccountBackup, error) {
+	var res AccountBackup
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res.Account, err = q.GetAccount(ctx, accountId)
+		if err != nil {
+			return err
+		}
+
+		res.AccessRules, err = q.GetAccountAccessRulesByAccountId(ctx, accountId)
+		if err != nil {
+			return err
+		}
+
+		// 记录的创建者可能已经不是账单的成员, 同样需要查询
+		ids := map[int64]bool{}
+		for _, rule := range res.AccessRules {
+			ids[rule.UserID] = true
+		}
+
+		res.Records = []Record{}
+		arg := sqlc.GetRecordsForExportParams{AccountID: accountId, PageLimit: iterateRecordsPageSize}
+		for {
+			var records []Record
+			records, err = q.GetRecordsForExport(ctx, arg)
+			if err != nil {
+				return err
+			}
+
+			for _, record := range records {
+				ids[record.CreateUserID] = true
+				ids[record.LastModifiedUserID] = true
+			}
+			res.Records = append(res.Records, records...)
+
+			if len(records) < iterateRecordsPageSize {
+				break
+			}
+			last := records[len(records)-1]
+			arg.CursorDate = nullTime(last.Date)
+			arg.CursorID = last.ID
+		}
+
+		res.Budgets, err = q.GetBudgetsByAccountId(ctx, accountId)
+		if err != nil {
+			return err
+		}
+		for _, budget := range res.Budgets {
+			if budget.UserID.Valid {
+				ids[budget.UserID.Int64] = true
+			}
+		}
+
+		res.BudgetAlerts, err = q.GetBudgetAlertsByAccountId(ctx, accountId)
+		if err != nil {
+			return err
+		}
+
+		res.ApprovalRules, err = q.GetApprovalRulesByAccountId(ctx, accountId)
+		if err != nil {
+			return err
+		}
+
+		userIds := []int64{}
+		for id := range ids {
+			userIds = append(userIds, id)
+		}
+		res.Users, err = q.GetUsersByIds(ctx, userIds)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 恢复的记录, 用户id已经映射为当前部署中的用户
+ */
+type RestoredRecord struct {
+	NewRecord
+	CreateUserId       int64
+	LastModifiedUserId int64
+	CreateTime         time.Time
+	ApprovalStatus     util.ApprovalStatus
+}
+
+/**
+ * Ref是预算在备份中的编号, 预算提醒通过Ref引用预算
+ */
+type RestoredBudget struct {
+	Ref        int64
+	RecordType int32
+	UserId     int64
+	Period     int32
+	Amount     string
+	Rollover   bool
+	Thresholds []int32
+}
+
+type RestoredBudgetAlert struct {
+	BudgetRef   int64
+	PeriodStart time.Time
+	Threshold   int32
+	Spent       string
+	LimitAmount string
+}
+
+type RestoredApprovalRule struct {
+	MinAmount string
+	Types     []int32
+}
+
+/**
+ * 恢复账单的用户是唯一的成员和拥有者
+ */
+type AccountRestore struct {
+	Name          string
+	OwnerId       int64
+	Records       []RestoredRecord
+	Budgets       []RestoredBudget
+	BudgetAlerts  []RestoredBudgetAlert
+	ApprovalRules []RestoredApprovalRule
+}
+
+/**
+ * 在一个事务中恢复账单, 所有数据使用新的id
+ * 1. 创建账单和拥有者的权限信息
+ * 2. 分批写入记录, 保留原来的创建时间
+ * 3. 拥有者写入的记录会被触发器判定为已通过, 再恢复待审批和已驳回记录的审批状态
+ * 4. 创建预算, 再按新的预算id创建预算提醒
+ * 5. 创建审批规则, 创建者为拥有者
+ */
+func (db *DB) RestoreAccount(ctx context.Context, restore AccountRestore) (Account, error) {
+	var res Account
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.CreateAccount(ctx, restore.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateAccountAccessRule(ctx, sqlc.CreateAccountAccessRuleParams{
+			UserID:    restore.OwnerId,
+			AccountID: res.ID,
+			Role:      util.AccountRoleOwner,
+		})
+		if err != nil {
+			return err
+		}
+
+		for start := 0; start < len(restore.Records); start += restoreRecordsBatchSize {
+			end := min(start+restoreRecordsBatchSize, len(restore.Records))
+			arg := sqlc.RestoreRecordsParams{AccountID: res.ID}
+			for _, record := range restore.Records[start:end] {
+				arg.Names = append(arg.Names, record.Name)
+				arg.Types = append(arg.Types, record.RecordType)
+				arg.Dates = append(arg.Dates, record.Date)
+				arg.Amounts = append(arg.Amounts, record.Amount)
+				arg.CreateUserIds = append(arg.CreateUserIds, record.CreateUserId)
+				arg.LastModifiedUserIds = append(arg.LastModifiedUserIds, record.LastModifiedUserId)
+				arg.CreateTimes = append(arg.CreateTimes, record.CreateTime)
+				arg.ExternalIds = append(arg.ExternalIds, record.ExternalId)
+			}
+
+			var ids []int64
+			ids, err = q.RestoreRecords(ctx, arg)
+			if err != nil {
+				return err
+			}
+
+			statusArg := sqlc.UpdateRecordsApprovalStatusParams{}
+			for i, record := range restore.Records[start:end] {
+				if record.ApprovalStatus != 0 && record.ApprovalStatus != util.ApprovalStatusApproved {
+					statusArg.Ids = append(statusArg.Ids, ids[i])
+					statusArg.ApprovalStatuses = append(statusArg.ApprovalStatuses, record.ApprovalStatus)
+				}
+			}
+			if len(statusArg.Ids) > 0 {
+				err = q.UpdateRecordsApprovalStatus(ctx, statusArg)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		budgetIds := map[int64]int64{}
+		for _, budget := range restore.Budgets {
+			var created Budget
+			created, err = q.CreateBudget(ctx, sqlc.CreateBudgetParams{
+				AccountID:  res.ID,
+				Type:       nullInt32(budget.RecordType),
+				UserID:     nullInt64(budget.UserId),
+				Period:     budget.Period,
+				Amount:     budget.Amount,
+				Rollover:   budget.Rollover,
+				Thresholds: budget.Thresholds,
+			})
+			if err != nil {
+				return err
+			}
+			budgetIds[budget.Ref] = created.ID
+		}
+
+		for _, alert := range restore.BudgetAlerts {
+			budgetId, ok := budgetIds[alert.BudgetRef]
+			if !ok {
+				continue
+			}
+
+			_, err = q.CreateBudgetAlert(ctx, sqlc.CreateBudgetAlertParams{
+				BudgetID:    budgetId,
+				AccountID:   res.ID,
+				PeriodStart: alert.PeriodStart,
+				Threshold:   alert.Threshold,
+				Spent:       alert.Spent,
+				LimitAmount: alert.LimitAmount,
+			})
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		for _, rule := range restore.ApprovalRules {
+			_, err = q.CreateApprovalRule(ctx, sqlc.CreateApprovalRuleParams{
+				AccountID:    res.ID,
+				MinAmount:    rule.MinAmount,
+				Types:        rule.Types,
+				CreateUserID: restore.OwnerId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return res, err
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type Budget = sqlc.Budget
+type BudgetAlert = sqlc.BudgetAlert
+type BudgetProgress = sqlc.GetBudgetProgressesRow
+
+/**
+ * recordType为0表示预算覆盖所有类型, userId为0表示预算覆盖所有成员
+ */
+func (db *DB) CreateBudget(
+	ctx context.Context,
+	accountId int64,
+	recordType int32,
+	userId int64,
+	period int32,
+	amount string,
+	rollover bool,
+	thresholds []int32,
+) (Budget, error) {
+	var res Budget
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.CreateBudgetParams{
+			AccountID:  accountId,
+			Type:       nullInt32(recordType),
+			UserID:     nullInt64(userId),
+			Period:     period,
+			Amount:     amount,
+			Rollover:   rollover,
+			Thresholds: thresholds,
+		}
+
+		res, err = q.CreateBudget(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetBudget(ctx context.Context, id int64) (Budget, error) {
+	var res Budget
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetBudget(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetBudgetsByAccountId(ctx context.Context, accountId int64) ([]Budget, error) {
+	var res []Budget
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetBudgetsByAccountId(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpdateBudget(
+	ctx context.Context,
+	id int64,
+	amount string,
+	rollover bool,
+	thresholds []int32,
+) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.UpdateBudgetParams{
+			ID:         id,
+			Amount:     amount,
+			Rollover:   rollover,
+			Thresholds: thresholds,
+		}
+		return q.UpdateBudget(ctx, arg)
+	})
+}
+
+/**
+ * 1. 删除预算的所有提醒
+ * 2. 删除预算
+ */
+func (db *DB) DeleteBudget(ctx context.Context, id int64) error {
+	return db.execTx(ctx, func(q *sqlc.Queries) error {
+		err := q.DeleteBudgetAlertsByBudgetId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteBudget(ctx, id)
+	})
+}
+
+/**
+ * 查询账单中所有预算在date所在周期内的进度
+ * 结转的预算只加上上一个周期的结余, 不跨多个周期累计
+ */
+func (db *DB) GetBudgetProgresses(ctx context.Context, accountId int64, date time.Time) ([]BudgetProgress, error) {
+	var res []BudgetProgress
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetBudgetProgressesParams{
+			Date:      date,
+			AccountID: accountId,
+		}
+
+		res, err = q.GetBudgetProgresses(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 1. 查询覆盖该记录的预算在date所在周期内的进度
+ * 2. 对每个已达到的阈值创建提醒, 同一周期内同一阈值只提醒一次
+ * 返回本次新产生的提醒
+ */
+func (db *DB) CheckBudgetAlerts(
+	ctx context.Context,
+	accountId int64,
+	recordType int32,
+	userId int64,
+	date time.Time,
+) ([]BudgetAlert, error) {
+	res := []BudgetAlert{}
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.GetBudgetProgressesParams{
+			Date:         date,
+			AccountID:    accountId,
+			RecordType:   nullInt32(recordType),
+			RecordUserID: nullInt64(userId),
+		}
+
+		progresses, err := q.GetBudgetProgresses(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		for _, progress := range progresses {
+			for _, threshold := range progress.Thresholds {
+				if progress.Percent < threshold {
+					continue
+				}
+
+				alertArg := sqlc.CreateBudgetAlertParams{
+					BudgetID:    progress.BudgetID,
+					AccountID:   accountId,
+					PeriodStart: progress.PeriodStart,
+					Threshold:   threshold,
+					Spent:       progress.Spent,
+					LimitAmount: progress.LimitAmount,
+				}
+
+				var alert BudgetAlert
+				alert, err = q.CreateBudgetAlert(ctx, alertArg)
+				if err == sql.ErrNoRows {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+
+				res = append(res, alert)
+			}
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+func (db *DB) GetBudgetAlertsByAccountIdAfterCursor(
+	ctx context.Context,
+	accountId int64,
+	cursorId int64,
+	limit int64,
+) ([]BudgetAlert, error) {
+	var res []BudgetAlert
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetBudgetAlertsByAccountIdAfterCursorParams{
+			AccountID: accountId,
+			CursorID:  nullInt64(cursorId),
+			PageLimit: limit,
+		}
+
+		res, err = q.GetBudgetAlertsByAccountIdAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
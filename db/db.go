@@ -4,12 +4,31 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/timelyrain/star-account/db/sqlc"
 )
 
+/**
+ * 数据库触发器通过自定义错误码拒绝的操作
+ */
+const (
+	periodClosedErrorCode     = "SA001"
+	recordReconciledErrorCode = "SA002"
+)
+
+var (
+	ErrPeriodClosed     = errors.New("record date is on or before the closed date of the account")
+	ErrRecordReconciled = errors.New("amount and date of a reconciled record cannot be changed")
+)
+
+/**
+ * tx不为空时所有方法都在该事务中执行, 由ExecWithEvents创建
+ */
 type DB struct {
 	conn *sql.DB
+	tx   *sql.Tx
 }
 
 func NewDB(conn *sql.DB) *DB {
@@ -17,18 +36,26 @@ func NewDB(conn *sql.DB) *DB {
 }
 
 func (db *DB) exec(ctx context.Context, fn func(*sqlc.Queries) error) error {
+	if db.tx != nil {
+		return translateError(fn(sqlc.New(db.tx)))
+	}
+
 	q := sqlc.New(db.conn)
-	return fn(q)
+	return translateError(fn(q))
 }
 
 func (db *DB) execTx(ctx context.Context, fn func(*sqlc.Queries) error) error {
+	if db.tx != nil {
+		return db.execSavepoint(ctx, fn)
+	}
+
 	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	q := sqlc.New(tx)
-	err = fn(q)
+	err = translateError(fn(q))
 	if err != nil {
 		rbErr := tx.Rollback()
 		if rbErr != nil {
@@ -40,3 +67,61 @@ func (db *DB) execTx(ctx context.Context, fn func(*sqlc.Queries) error) error {
 
 	return tx.Commit()
 }
+
+/**
+ * 在外层事务中执行, 失败时只回滚到保存点, 外层事务仍然可以继续使用
+ */
+func (db *DB) execSavepoint(ctx context.Context, fn func(*sqlc.Queries) error) error {
+	_, err := db.tx.ExecContext(ctx, "SAVEPOINT nested")
+	if err != nil {
+		return err
+	}
+
+	err = translateError(fn(sqlc.New(db.tx)))
+	if err != nil {
+		_, rbErr := db.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT nested")
+		if rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+
+		return err
+	}
+
+	_, err = db.tx.ExecContext(ctx, "RELEASE SAVEPOINT nested")
+	return err
+}
+
+/**
+ * 把触发器抛出的错误转换为对应的业务错误, 其他错误原样返回
+ */
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case periodClosedErrorCode:
+			return ErrPeriodClosed
+		case recordReconciledErrorCode:
+			return ErrRecordReconciled
+		}
+	}
+	return err
+}
+
+/**
+ * 可选参数的转换, 零值表示未设置
+ */
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt32(i int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: i, Valid: i != 0}
+}
+
+func nullInt64(i int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: i, Valid: i != 0}
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type DigestSubscription = sqlc.DigestSubscription
+
+/**
+ * 订阅或修改摘要邮件的设置, nextSendTime按新的设置计算
+ */
+func (db *DB) UpsertDigestSubscription(
+	ctx context.Context,
+	userId int64,
+	frequency int32,
+	timeZone string,
+	nextSendTime time.Time,
+) (DigestSubscription, error) {
+	var res DigestSubscription
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.UpsertDigestSubscriptionParams{
+			UserID:       userId,
+			Frequency:    frequency,
+			TimeZone:     timeZone,
+			NextSendTime: nextSendTime,
+		}
+
+		res, err = q.UpsertDigestSubscription(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetDigestSubscription(ctx context.Context, userId int64) (DigestSubscription, error) {
+	var res DigestSubscription
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetDigestSubscription(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) DeleteDigestSubscription(ctx context.Context, userId int64) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteDigestSubscription(ctx, userId)
+	})
+}
+
+/**
+ * 领取到期的摘要订阅, 并把下次发送时间推迟到leaseUntil
+ * 发送失败或领取者崩溃时, 订阅在leaseUntil之后被重新领取
+ */
+func (db *DB) ClaimDueDigestSubscriptions(
+	ctx context.Context,
+	now time.Time,
+	leaseUntil time.Time,
+	limit int64,
+) ([]DigestSubscription, error) {
+	var res []DigestSubscription
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.ClaimDueDigestSubscriptionsParams{
+			LeaseUntil: leaseUntil,
+			Now:        now,
+			PageLimit:  limit,
+		}
+
+		res, err = q.ClaimDueDigestSubscriptions(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpdateDigestSubscriptionSent(
+	ctx context.Context,
+	userId int64,
+	nextSendTime time.Time,
+	sentTime time.Time,
+) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.UpdateDigestSubscriptionSentParams{
+			UserID:       userId,
+			NextSendTime: nextSendTime,
+			LastSentTime: nullTime(sentTime),
+		}
+
+		return q.UpdateDigestSubscriptionSent(ctx, arg)
+	})
+}
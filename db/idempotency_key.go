@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type IdempotencyKey = sqlc.IdempotencyKey
+
+/**
+ * 1. 删除同一用户同一key下已经过期的记录
+ * 2. 插入新的记录, key已存在时返回已有的记录, 此时created为false
+ */
+func (db *DB) CreateIdempotencyKey(
+	ctx context.Context,
+	userId int64,
+	key string,
+	requestHash string,
+	ttl time.Duration,
+) (IdempotencyKey, bool, error) {
+	var res IdempotencyKey
+	var created bool
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		deleteArg := sqlc.DeleteExpiredIdempotencyKeyParams{
+			UserID: userId,
+			Key:    key,
+		}
+
+		err = q.DeleteExpiredIdempotencyKey(ctx, deleteArg)
+		if err != nil {
+			return err
+		}
+
+		arg := sqlc.CreateIdempotencyKeyParams{
+			UserID:      userId,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpireTime:  time.Now().Add(ttl),
+		}
+
+		res, err = q.CreateIdempotencyKey(ctx, arg)
+		if err == nil {
+			created = true
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		getArg := sqlc.GetIdempotencyKeyParams{
+			UserID: userId,
+			Key:    key,
+		}
+		res, err = q.GetIdempotencyKey(ctx, getArg)
+		return err
+	})
+
+	return res, created, err
+}
+
+func (db *DB) UpdateIdempotencyKeyResponse(
+	ctx context.Context,
+	id int64,
+	statusCode int32,
+	contentType string,
+	responseBody []byte,
+) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.UpdateIdempotencyKeyResponseParams{
+			ID:           id,
+			StatusCode:   statusCode,
+			ContentType:  contentType,
+			ResponseBody: responseBody,
+		}
+		return q.UpdateIdempotencyKeyResponse(ctx, arg)
+	})
+}
+
+func (db *DB) DeleteIdempotencyKey(ctx context.Context, id int64) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteIdempotencyKey(ctx, id)
+	})
+}
+
+/**
+ * 删除最多limit条已经过期的记录, 返回删除的条数
+ */
+func (db *DB) DeleteExpiredIdempotencyKeys(ctx context.Context, limit int64) (int64, error) {
+	var res int64
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.DeleteExpiredIdempotencyKeys(ctx, limit)
+		return err
+	})
+
+	return res, err
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "records_account_id_date_idx";
+DROP INDEX IF EXISTS "records_to_tsvector_idx";
+DROP TEXT SEARCH CONFIGURATION IF EXISTS star_account_search;
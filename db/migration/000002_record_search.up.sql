@@ -0,0 +1,10 @@
+-- 全文检索使用的配置, 默认复制自simple
+-- 部署了中文分词插件(如zhparser)时可以按如下方式替换解析器:
+--   DROP TEXT SEARCH CONFIGURATION star_account_search;
+--   CREATE TEXT SEARCH CONFIGURATION star_account_search (PARSER = zhparser);
+--   ALTER TEXT SEARCH CONFIGURATION star_account_search ADD MAPPING FOR n,v,a,i,e,l WITH simple;
+CREATE TEXT SEARCH CONFIGURATION star_account_search (COPY = simple);
+
+CREATE INDEX ON "records" USING GIN (to_tsvector('star_account_search', "name"));
+
+CREATE INDEX ON "records" ("account_id", "date");
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "account_access_rules_account_id_role_user_id_idx";
+DROP INDEX IF EXISTS "account_access_rules_user_id_role_account_id_idx";
+DROP INDEX IF EXISTS "records_account_id_date_id_idx";
+
+CREATE INDEX ON "records" ("account_id", "date");
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "records_account_id_date_idx";
+
+CREATE INDEX ON "records" ("account_id", "date", "id");
+
+CREATE INDEX ON "account_access_rules" ("user_id", "role", "account_id");
+
+CREATE INDEX ON "account_access_rules" ("account_id", "role", "user_id");
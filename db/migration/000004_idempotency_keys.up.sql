@@ -0,0 +1,14 @@
+CREATE TABLE "idempotency_keys" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "key" varchar NOT NULL,
+  "request_hash" varchar NOT NULL,
+  "status_code" integer NOT NULL DEFAULT 0,
+  "response_body" bytea NOT NULL DEFAULT '',
+  "create_time" timestamptz NOT NULL DEFAULT (now()),
+  "expire_time" timestamptz NOT NULL
+);
+
+CREATE UNIQUE INDEX ON "idempotency_keys" ("user_id", "key");
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
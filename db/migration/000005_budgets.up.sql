@@ -0,0 +1,34 @@
+CREATE TABLE "budgets" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "type" integer,
+  "user_id" bigint,
+  "period" integer NOT NULL,
+  "amount" numeric NOT NULL,
+  "rollover" boolean NOT NULL DEFAULT false,
+  "thresholds" integer[] NOT NULL DEFAULT '{80,100}',
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "budget_alerts" (
+  "id" bigserial PRIMARY KEY,
+  "budget_id" bigint NOT NULL,
+  "account_id" bigint NOT NULL,
+  "period_start" date NOT NULL,
+  "threshold" integer NOT NULL,
+  "spent" numeric NOT NULL,
+  "limit_amount" numeric NOT NULL,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "budgets" ("account_id");
+
+CREATE INDEX ON "budget_alerts" ("account_id");
+
+CREATE UNIQUE INDEX ON "budget_alerts" ("budget_id", "period_start", "threshold");
+
+ALTER TABLE "budgets" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "budget_alerts" ADD FOREIGN KEY ("budget_id") REFERENCES "budgets" ("id");
+
+ALTER TABLE "budget_alerts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
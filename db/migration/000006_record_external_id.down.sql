@@ -0,0 +1 @@
+ALTER TABLE "records" DROP COLUMN IF EXISTS "external_id";
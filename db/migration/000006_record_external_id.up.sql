@@ -0,0 +1,4 @@
+-- 从外部账单导入的记录在来源平台的交易号, 手动创建的记录为空
+ALTER TABLE "records" ADD COLUMN "external_id" varchar NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX ON "records" ("account_id", "external_id") WHERE "external_id" <> '';
@@ -0,0 +1,10 @@
+-- 用户申请注销后, 在erase_time之前可以取消, 之后由后台任务执行注销
+CREATE TABLE "user_erasures" (
+  "user_id" bigint PRIMARY KEY,
+  "erase_time" timestamptz NOT NULL,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "user_erasures" ("erase_time");
+
+ALTER TABLE "user_erasures" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
@@ -0,0 +1,40 @@
+CREATE TABLE "webhooks" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "url" varchar NOT NULL,
+  "secret" varchar NOT NULL,
+  "events" varchar[] NOT NULL,
+  "active" boolean NOT NULL DEFAULT true,
+  "create_user_id" bigint NOT NULL,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- status: 1 等待投递, 2 投递成功, 3 重试次数用尽
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "webhook_id" bigint NOT NULL,
+  "account_id" bigint NOT NULL,
+  "event" varchar NOT NULL,
+  "payload" text NOT NULL,
+  "status" integer NOT NULL DEFAULT 1,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_time" timestamptz NOT NULL DEFAULT (now()),
+  "response_status" integer NOT NULL DEFAULT 0,
+  "error" varchar NOT NULL DEFAULT '',
+  "delivered_time" timestamptz,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhooks" ("account_id");
+
+CREATE INDEX ON "webhook_deliveries" ("webhook_id", "id");
+
+CREATE INDEX ON "webhook_deliveries" ("account_id");
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_time") WHERE "status" = 1;
+
+ALTER TABLE "webhooks" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("webhook_id") REFERENCES "webhooks" ("id");
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS records_field_times ON "records";
+DROP TRIGGER IF EXISTS accounts_sync_change ON "accounts";
+DROP TRIGGER IF EXISTS account_access_rules_sync_change ON "account_access_rules";
+DROP TRIGGER IF EXISTS records_sync_change ON "records";
+
+DROP FUNCTION IF EXISTS record_field_times();
+DROP FUNCTION IF EXISTS account_sync_change();
+DROP FUNCTION IF EXISTS member_sync_change();
+DROP FUNCTION IF EXISTS record_sync_change();
+
+DROP SEQUENCE IF EXISTS "sync_change_seq";
+
+DROP TABLE IF EXISTS "sync_changes";
+
+ALTER TABLE "records" DROP COLUMN IF EXISTS "field_times";
+ALTER TABLE "records" DROP COLUMN IF EXISTS "client_id";
@@ -0,0 +1,125 @@
+-- 客户端离线创建记录时生成的id, 服务端创建的记录为空
+ALTER TABLE "records" ADD COLUMN "client_id" varchar NOT NULL DEFAULT '';
+
+-- 各字段最后一次修改的时间(毫秒时间戳), 同步推送时按字段进行后写者胜出的冲突处理
+-- 缺少的字段视为在记录创建时修改
+ALTER TABLE "records" ADD COLUMN "field_times" jsonb NOT NULL DEFAULT '{}';
+
+CREATE UNIQUE INDEX ON "records" ("account_id", "client_id") WHERE "client_id" <> '';
+
+-- 每个账单中记录, 成员和账单本身的最新变更, 删除的记录和成员保留为墓碑
+-- entity: record(entity_id为记录id), member(entity_id为用户id), account(entity_id为账单id)
+CREATE TABLE "sync_changes" (
+  "account_id" bigint NOT NULL,
+  "entity" varchar NOT NULL,
+  "entity_id" bigint NOT NULL,
+  "deleted" boolean NOT NULL DEFAULT false,
+  "client_id" varchar NOT NULL DEFAULT '',
+  "change_seq" bigint NOT NULL,
+  PRIMARY KEY ("account_id", "entity", "entity_id")
+);
+
+CREATE INDEX ON "sync_changes" ("account_id", "change_seq");
+
+ALTER TABLE "sync_changes" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE SEQUENCE "sync_change_seq";
+
+-- 同一账单的变更持有该账单的事务级咨询锁, 使change_seq的顺序与提交顺序一致
+-- 客户端按账单记录已同步到的change_seq, 不会漏掉并发事务中较早分配的序号
+CREATE FUNCTION record_sync_change() RETURNS trigger AS $$
+DECLARE
+  r records;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  PERFORM pg_advisory_xact_lock(r.account_id);
+  INSERT INTO sync_changes (account_id, entity, entity_id, deleted, client_id, change_seq)
+  VALUES (r.account_id, 'record', r.id, TG_OP = 'DELETE', r.client_id, nextval('sync_change_seq'))
+  ON CONFLICT (account_id, entity, entity_id) DO UPDATE
+  SET deleted=EXCLUDED.deleted, client_id=EXCLUDED.client_id, change_seq=EXCLUDED.change_seq;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION member_sync_change() RETURNS trigger AS $$
+DECLARE
+  r account_access_rules;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  PERFORM pg_advisory_xact_lock(r.account_id);
+  INSERT INTO sync_changes (account_id, entity, entity_id, deleted, change_seq)
+  VALUES (r.account_id, 'member', r.user_id, TG_OP = 'DELETE', nextval('sync_change_seq'))
+  ON CONFLICT (account_id, entity, entity_id) DO UPDATE
+  SET deleted=EXCLUDED.deleted, change_seq=EXCLUDED.change_seq;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION account_sync_change() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_advisory_xact_lock(NEW.id);
+  INSERT INTO sync_changes (account_id, entity, entity_id, change_seq)
+  VALUES (NEW.id, 'account', NEW.id, nextval('sync_change_seq'))
+  ON CONFLICT (account_id, entity, entity_id) DO UPDATE
+  SET change_seq=EXCLUDED.change_seq;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- 没有显式设置field_times的修改, 把变化的字段的修改时间记为当前时间
+CREATE FUNCTION record_field_times() RETURNS trigger AS $$
+DECLARE
+  now_ms bigint := (extract(epoch FROM clock_timestamp()) * 1000)::bigint;
+BEGIN
+  IF NEW.field_times IS DISTINCT FROM OLD.field_times THEN
+    RETURN NEW;
+  END IF;
+
+  IF NEW.name IS DISTINCT FROM OLD.name THEN
+    NEW.field_times := NEW.field_times || jsonb_build_object('name', now_ms);
+  END IF;
+  IF NEW.type IS DISTINCT FROM OLD.type THEN
+    NEW.field_times := NEW.field_times || jsonb_build_object('type', now_ms);
+  END IF;
+  IF NEW.date IS DISTINCT FROM OLD.date THEN
+    NEW.field_times := NEW.field_times || jsonb_build_object('date', now_ms);
+  END IF;
+  IF NEW.amount IS DISTINCT FROM OLD.amount THEN
+    NEW.field_times := NEW.field_times || jsonb_build_object('amount', now_ms);
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+-- 账单删除时sync_changes随账单一起删除, 因此accounts只在插入和修改时记录变更
+CREATE TRIGGER records_sync_change AFTER INSERT OR UPDATE OR DELETE ON "records"
+FOR EACH ROW EXECUTE FUNCTION record_sync_change();
+
+CREATE TRIGGER account_access_rules_sync_change AFTER INSERT OR UPDATE OR DELETE ON "account_access_rules"
+FOR EACH ROW EXECUTE FUNCTION member_sync_change();
+
+CREATE TRIGGER accounts_sync_change AFTER INSERT OR UPDATE ON "accounts"
+FOR EACH ROW EXECUTE FUNCTION account_sync_change();
+
+CREATE TRIGGER records_field_times BEFORE UPDATE ON "records"
+FOR EACH ROW EXECUTE FUNCTION record_field_times();
+
+-- 为已有的数据补充变更记录
+INSERT INTO sync_changes (account_id, entity, entity_id, change_seq)
+SELECT id, 'account', id, nextval('sync_change_seq') FROM accounts ORDER BY id;
+
+INSERT INTO sync_changes (account_id, entity, entity_id, change_seq)
+SELECT account_id, 'member', user_id, nextval('sync_change_seq') FROM account_access_rules ORDER BY id;
+
+INSERT INTO sync_changes (account_id, entity, entity_id, change_seq)
+SELECT account_id, 'record', id, nextval('sync_change_seq') FROM records ORDER BY id;
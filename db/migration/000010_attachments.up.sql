@@ -0,0 +1,27 @@
+CREATE TABLE "attachments" (
+  "id" bigserial PRIMARY KEY,
+  "record_id" bigint NOT NULL,
+  "account_id" bigint NOT NULL,
+  "blob_key" varchar NOT NULL UNIQUE,
+  "filename" varchar NOT NULL,
+  "content_type" varchar NOT NULL,
+  "size" bigint NOT NULL,
+  "create_user_id" bigint NOT NULL,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- 附件行删除后待从BlobStore中删除的内容
+CREATE TABLE "blob_deletions" (
+  "blob_key" varchar PRIMARY KEY,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "attachments" ("record_id");
+
+CREATE INDEX ON "attachments" ("account_id");
+
+ALTER TABLE "attachments" ADD FOREIGN KEY ("record_id") REFERENCES "records" ("id");
+
+ALTER TABLE "attachments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "attachments" ADD FOREIGN KEY ("create_user_id") REFERENCES "users" ("id");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "comment_reads";
+DROP TABLE IF EXISTS "record_comments";
@@ -0,0 +1,39 @@
+-- parent_id为空时是顶层评论; 删除后保留行以维持回复的结构, 内容被清空
+CREATE TABLE "record_comments" (
+  "id" bigserial PRIMARY KEY,
+  "record_id" bigint NOT NULL,
+  "account_id" bigint NOT NULL,
+  "parent_id" bigint,
+  "user_id" bigint NOT NULL,
+  "content" varchar NOT NULL,
+  "mention_user_ids" bigint[] NOT NULL DEFAULT '{}',
+  "create_time" timestamptz NOT NULL DEFAULT (now()),
+  "update_time" timestamptz,
+  "delete_time" timestamptz
+);
+
+-- 用户在账单中已读到的最后一条评论
+CREATE TABLE "comment_reads" (
+  "user_id" bigint NOT NULL,
+  "account_id" bigint NOT NULL,
+  "last_read_comment_id" bigint NOT NULL,
+  PRIMARY KEY ("user_id", "account_id")
+);
+
+CREATE INDEX ON "record_comments" ("record_id", "id");
+
+CREATE INDEX ON "record_comments" ("account_id", "id");
+
+CREATE INDEX ON "record_comments" ("user_id");
+
+ALTER TABLE "record_comments" ADD FOREIGN KEY ("record_id") REFERENCES "records" ("id");
+
+ALTER TABLE "record_comments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "record_comments" ADD FOREIGN KEY ("parent_id") REFERENCES "record_comments" ("id");
+
+ALTER TABLE "record_comments" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "comment_reads" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "comment_reads" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
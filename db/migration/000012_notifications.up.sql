@@ -0,0 +1,32 @@
+-- email_status: 0 不发送邮件, 1 等待发送, 2 发送成功, 3 重试次数用尽
+-- in_app为false的通知只用于发送邮件, 不出现在收件箱中
+CREATE TABLE "notifications" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "type" varchar NOT NULL,
+  "data" jsonb NOT NULL,
+  "in_app" boolean NOT NULL,
+  "read_time" timestamptz,
+  "email_status" integer NOT NULL DEFAULT 0,
+  "email_attempts" integer NOT NULL DEFAULT 0,
+  "email_next_attempt_time" timestamptz NOT NULL DEFAULT (now()),
+  "email_error" varchar NOT NULL DEFAULT '',
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- 没有设置的类型使用默认值
+CREATE TABLE "notification_preferences" (
+  "user_id" bigint NOT NULL,
+  "type" varchar NOT NULL,
+  "in_app" boolean NOT NULL,
+  "email" boolean NOT NULL,
+  PRIMARY KEY ("user_id", "type")
+);
+
+CREATE INDEX ON "notifications" ("user_id", "id");
+
+CREATE INDEX ON "notifications" ("email_next_attempt_time") WHERE "email_status" = 1;
+
+ALTER TABLE "notifications" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "notification_preferences" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
@@ -0,0 +1,13 @@
+-- frequency: 1 每周, 2 每月; 存在记录表示用户订阅了摘要邮件
+CREATE TABLE "digest_subscriptions" (
+  "user_id" bigint PRIMARY KEY,
+  "frequency" integer NOT NULL,
+  "time_zone" varchar NOT NULL,
+  "next_send_time" timestamptz NOT NULL,
+  "last_sent_time" timestamptz,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "digest_subscriptions" ("next_send_time");
+
+ALTER TABLE "digest_subscriptions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS records_approval_history ON "records";
+DROP TRIGGER IF EXISTS records_approval_status ON "records";
+
+DROP FUNCTION IF EXISTS record_approval_history();
+DROP FUNCTION IF EXISTS record_approval_status();
+
+DROP TABLE IF EXISTS "record_approvals";
+DROP TABLE IF EXISTS "approval_rules";
+
+ALTER TABLE "records" DROP COLUMN IF EXISTS "approval_status";
@@ -0,0 +1,96 @@
+-- approval_status: 1 等待审批, 2 已通过, 3 已驳回, 只有已通过的记录计入金额统计
+ALTER TABLE "records" ADD COLUMN "approval_status" integer NOT NULL DEFAULT 2;
+
+-- 管理员创建或修改的记录金额绝对值不低于min_amount, 且类型在types中(为空表示所有类型)时需要审批
+CREATE TABLE "approval_rules" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "min_amount" numeric NOT NULL,
+  "types" integer[] NOT NULL DEFAULT '{}',
+  "create_user_id" bigint NOT NULL,
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- 记录的审批历史, status为这次变更后的审批状态, 提交审批和自动重新判定时reason为空
+CREATE TABLE "record_approvals" (
+  "id" bigserial PRIMARY KEY,
+  "record_id" bigint NOT NULL,
+  "account_id" bigint NOT NULL,
+  "status" integer NOT NULL,
+  "user_id" bigint NOT NULL,
+  "reason" varchar NOT NULL DEFAULT '',
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "records" ("account_id", "id") WHERE "approval_status" = 1;
+
+CREATE INDEX ON "approval_rules" ("account_id");
+
+CREATE INDEX ON "record_approvals" ("record_id", "id");
+
+CREATE INDEX ON "record_approvals" ("account_id");
+
+ALTER TABLE "approval_rules" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "approval_rules" ADD FOREIGN KEY ("create_user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "record_approvals" ADD FOREIGN KEY ("record_id") REFERENCES "records" ("id");
+
+ALTER TABLE "record_approvals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "record_approvals" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+-- 记录被插入或金额, 类型发生变化时重新判定审批状态
+-- 所有者创建或修改的记录不需要审批, 不再匹配规则的记录直接通过
+CREATE FUNCTION record_approval_status() RETURNS trigger AS $$
+DECLARE
+  actor bigint;
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    actor := NEW.create_user_id;
+  ELSIF NEW.amount IS NOT DISTINCT FROM OLD.amount AND NEW.type IS NOT DISTINCT FROM OLD.type THEN
+    RETURN NEW;
+  ELSE
+    actor := NEW.last_modified_user_id;
+  END IF;
+
+  IF EXISTS (
+    SELECT 1 FROM account_access_rules
+    WHERE account_id=NEW.account_id AND user_id=actor AND role=1
+  ) AND EXISTS (
+    SELECT 1 FROM approval_rules
+    WHERE account_id=NEW.account_id
+      AND abs(NEW.amount)>=min_amount
+      AND (cardinality(types)=0 OR NEW.type=ANY(types))
+  ) THEN
+    NEW.approval_status := 1;
+  ELSE
+    NEW.approval_status := 2;
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+-- 把重新判定的结果记入审批历史, 所有者审批时由应用写入历史
+CREATE FUNCTION record_approval_history() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    IF NEW.approval_status <> 1 THEN
+      RETURN NULL;
+    END IF;
+    INSERT INTO record_approvals (record_id, account_id, status, user_id)
+    VALUES (NEW.id, NEW.account_id, NEW.approval_status, NEW.create_user_id);
+  ELSIF (NEW.amount IS DISTINCT FROM OLD.amount OR NEW.type IS DISTINCT FROM OLD.type)
+    AND (NEW.approval_status = 1 OR NEW.approval_status IS DISTINCT FROM OLD.approval_status) THEN
+    INSERT INTO record_approvals (record_id, account_id, status, user_id)
+    VALUES (NEW.id, NEW.account_id, NEW.approval_status, NEW.last_modified_user_id);
+  END IF;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER records_approval_status BEFORE INSERT OR UPDATE ON "records"
+FOR EACH ROW EXECUTE FUNCTION record_approval_status();
+
+CREATE TRIGGER records_approval_history AFTER INSERT OR UPDATE ON "records"
+FOR EACH ROW EXECUTE FUNCTION record_approval_history();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS records_closed_period_check ON "records";
+
+DROP FUNCTION IF EXISTS record_closed_period_check();
+
+DROP TABLE IF EXISTS "period_closings";
@@ -0,0 +1,46 @@
+-- 账单关账和重新开放的审计记录, 每个账单最新一条的closed_until为当前的关账日期, 为空表示没有关账
+-- action: 1 关账, 2 重新开放
+CREATE TABLE "period_closings" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "action" integer NOT NULL,
+  "closed_until" date,
+  "previous_closed_until" date,
+  "user_id" bigint NOT NULL,
+  "reason" varchar NOT NULL DEFAULT '',
+  "create_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "period_closings" ("account_id", "id");
+
+ALTER TABLE "period_closings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "period_closings" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+-- 日期在关账日期及之前的记录不能创建, 修改和删除, 修改时新旧日期都要检查
+-- 共享锁定账单行, 与关账时对账单行的锁互斥, 避免检查之后关账的事务先提交
+-- 错误码SA001由应用转换为ErrPeriodClosed
+CREATE FUNCTION record_closed_period_check() RETURNS trigger AS $$
+DECLARE
+  r records;
+  closed date;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  PERFORM 1 FROM accounts WHERE id=r.account_id FOR SHARE;
+  SELECT closed_until INTO closed FROM period_closings
+  WHERE account_id=r.account_id ORDER BY id DESC LIMIT 1;
+
+  IF closed IS NOT NULL AND (r.date<=closed OR (TG_OP = 'UPDATE' AND OLD.date<=closed)) THEN
+    RAISE EXCEPTION 'records dated on or before % are closed', closed USING ERRCODE = 'SA001';
+  END IF;
+  RETURN r;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER records_closed_period_check BEFORE INSERT OR UPDATE OR DELETE ON "records"
+FOR EACH ROW EXECUTE FUNCTION record_closed_period_check();
@@ -0,0 +1,30 @@
+CREATE OR REPLACE FUNCTION record_closed_period_check() RETURNS trigger AS $$
+DECLARE
+  r records;
+  closed date;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  PERFORM 1 FROM accounts WHERE id=r.account_id FOR SHARE;
+  SELECT closed_until INTO closed FROM period_closings
+  WHERE account_id=r.account_id ORDER BY id DESC LIMIT 1;
+
+  IF closed IS NOT NULL AND (r.date<=closed OR (TG_OP = 'UPDATE' AND OLD.date<=closed)) THEN
+    RAISE EXCEPTION 'records dated on or before % are closed', closed USING ERRCODE = 'SA001';
+  END IF;
+  RETURN r;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS records_clear_status ON "records";
+
+DROP FUNCTION IF EXISTS record_clear_status();
+
+DROP TABLE IF EXISTS "balance_checkpoints";
+DROP TABLE IF EXISTS "opening_balances";
+
+ALTER TABLE "records" DROP COLUMN IF EXISTS "clear_status";
@@ -0,0 +1,76 @@
+-- clear_status: 0 未核对, 1 已核对(与银行流水或现金一致), 2 已对账(所在的余额检查点已经对平)
+ALTER TABLE "records" ADD COLUMN "clear_status" integer NOT NULL DEFAULT 0;
+
+-- 账单的期初余额, 为date当天记录发生之前的余额, date之前的记录不计入余额
+CREATE TABLE "opening_balances" (
+  "account_id" bigint PRIMARY KEY,
+  "amount" numeric NOT NULL,
+  "date" date NOT NULL,
+  "update_user_id" bigint NOT NULL,
+  "update_time" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- 某一天结束时实际的余额, 用于与记录计算出的余额比较
+CREATE TABLE "balance_checkpoints" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "date" date NOT NULL,
+  "balance" numeric NOT NULL,
+  "note" varchar NOT NULL DEFAULT '',
+  "create_user_id" bigint NOT NULL,
+  "create_time" timestamptz NOT NULL DEFAULT (now()),
+  "reconcile_time" timestamptz
+);
+
+CREATE UNIQUE INDEX ON "balance_checkpoints" ("account_id", "date");
+
+ALTER TABLE "opening_balances" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "opening_balances" ADD FOREIGN KEY ("update_user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "balance_checkpoints" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "balance_checkpoints" ADD FOREIGN KEY ("create_user_id") REFERENCES "users" ("id");
+
+-- 金额或日期变化后记录需要重新核对
+CREATE FUNCTION record_clear_status() RETURNS trigger AS $$
+BEGIN
+  IF NEW.clear_status IS NOT DISTINCT FROM OLD.clear_status
+    AND (NEW.amount IS DISTINCT FROM OLD.amount OR NEW.date IS DISTINCT FROM OLD.date) THEN
+    NEW.clear_status := 0;
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER records_clear_status BEFORE UPDATE ON "records"
+FOR EACH ROW EXECUTE FUNCTION record_clear_status();
+
+-- 只修改核对状态不影响金额, 关账期间内的记录也可以核对
+CREATE OR REPLACE FUNCTION record_closed_period_check() RETURNS trigger AS $$
+DECLARE
+  r records;
+  closed date;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  IF TG_OP = 'UPDATE'
+    AND (NEW.name, NEW.type, NEW.date, NEW.amount, NEW.approval_status)
+      IS NOT DISTINCT FROM (OLD.name, OLD.type, OLD.date, OLD.amount, OLD.approval_status) THEN
+    RETURN r;
+  END IF;
+
+  PERFORM 1 FROM accounts WHERE id=r.account_id FOR SHARE;
+  SELECT closed_until INTO closed FROM period_closings
+  WHERE account_id=r.account_id ORDER BY id DESC LIMIT 1;
+
+  IF closed IS NOT NULL AND (r.date<=closed OR (TG_OP = 'UPDATE' AND OLD.date<=closed)) THEN
+    RAISE EXCEPTION 'records dated on or before % are closed', closed USING ERRCODE = 'SA001';
+  END IF;
+  RETURN r;
+END
+$$ LANGUAGE plpgsql;
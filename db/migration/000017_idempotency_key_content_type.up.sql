@@ -0,0 +1,5 @@
+-- 重放响应时使用原来的Content-Type
+ALTER TABLE "idempotency_keys" ADD COLUMN "content_type" varchar NOT NULL DEFAULT '';
+
+-- 定期清理过期的键
+CREATE INDEX ON "idempotency_keys" ("expire_time");
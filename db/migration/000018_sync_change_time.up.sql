@@ -0,0 +1,56 @@
+-- 墓碑在保留期之后被清理, 超过保留期没有同步的客户端需要重新完整同步
+-- 已有的变更从迁移时开始计算保留期
+ALTER TABLE "sync_changes" ADD COLUMN "change_time" timestamptz NOT NULL DEFAULT (now());
+
+CREATE INDEX ON "sync_changes" ("change_time") WHERE "deleted";
+
+-- 变更时同时更新change_time
+-- 一个事务修改多个账单时应先按账单id升序加锁, 避免事务之间互相等待
+CREATE OR REPLACE FUNCTION record_sync_change() RETURNS trigger AS $$
+DECLARE
+  r records;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  PERFORM pg_advisory_xact_lock(r.account_id);
+  INSERT INTO sync_changes (account_id, entity, entity_id, deleted, client_id, change_seq)
+  VALUES (r.account_id, 'record', r.id, TG_OP = 'DELETE', r.client_id, nextval('sync_change_seq'))
+  ON CONFLICT (account_id, entity, entity_id) DO UPDATE
+  SET deleted=EXCLUDED.deleted, client_id=EXCLUDED.client_id, change_seq=EXCLUDED.change_seq, change_time=EXCLUDED.change_time;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION member_sync_change() RETURNS trigger AS $$
+DECLARE
+  r account_access_rules;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  PERFORM pg_advisory_xact_lock(r.account_id);
+  INSERT INTO sync_changes (account_id, entity, entity_id, deleted, change_seq)
+  VALUES (r.account_id, 'member', r.user_id, TG_OP = 'DELETE', nextval('sync_change_seq'))
+  ON CONFLICT (account_id, entity, entity_id) DO UPDATE
+  SET deleted=EXCLUDED.deleted, change_seq=EXCLUDED.change_seq, change_time=EXCLUDED.change_time;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION account_sync_change() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_advisory_xact_lock(NEW.id);
+  INSERT INTO sync_changes (account_id, entity, entity_id, change_seq)
+  VALUES (NEW.id, 'account', NEW.id, nextval('sync_change_seq'))
+  ON CONFLICT (account_id, entity, entity_id) DO UPDATE
+  SET change_seq=EXCLUDED.change_seq, change_time=EXCLUDED.change_time;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,37 @@
+CREATE OR REPLACE FUNCTION record_clear_status() RETURNS trigger AS $$
+BEGIN
+  IF NEW.clear_status IS NOT DISTINCT FROM OLD.clear_status
+    AND (NEW.amount IS DISTINCT FROM OLD.amount OR NEW.date IS DISTINCT FROM OLD.date) THEN
+    NEW.clear_status := 0;
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_closed_period_check() RETURNS trigger AS $$
+DECLARE
+  r records;
+  closed date;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  IF TG_OP = 'UPDATE'
+    AND (NEW.name, NEW.type, NEW.date, NEW.amount, NEW.approval_status)
+      IS NOT DISTINCT FROM (OLD.name, OLD.type, OLD.date, OLD.amount, OLD.approval_status) THEN
+    RETURN r;
+  END IF;
+
+  PERFORM 1 FROM accounts WHERE id=r.account_id FOR SHARE;
+  SELECT closed_until INTO closed FROM period_closings
+  WHERE account_id=r.account_id ORDER BY id DESC LIMIT 1;
+
+  IF closed IS NOT NULL AND (r.date<=closed OR (TG_OP = 'UPDATE' AND OLD.date<=closed)) THEN
+    RAISE EXCEPTION 'records dated on or before % are closed', closed USING ERRCODE = 'SA001';
+  END IF;
+  RETURN r;
+END
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,45 @@
+-- 金额或日期变化后已核对的记录需要重新核对
+-- 已对账的记录决定了检查点的余额, 不能修改金额和日期
+CREATE OR REPLACE FUNCTION record_clear_status() RETURNS trigger AS $$
+BEGIN
+  IF NEW.amount IS NOT DISTINCT FROM OLD.amount AND NEW.date IS NOT DISTINCT FROM OLD.date THEN
+    RETURN NEW;
+  END IF;
+
+  IF OLD.clear_status = 2 THEN
+    RAISE EXCEPTION 'record % is reconciled', OLD.id USING ERRCODE = 'SA002';
+  END IF;
+  IF NEW.clear_status IS NOT DISTINCT FROM OLD.clear_status THEN
+    NEW.clear_status := 0;
+  END IF;
+  RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+-- 只修改核对状态不影响金额, 关账期间内的记录也可以核对
+-- 比较除clear_status之外的所有列, 以后新增的列也受关账限制
+CREATE OR REPLACE FUNCTION record_closed_period_check() RETURNS trigger AS $$
+DECLARE
+  r records;
+  closed date;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    r := OLD;
+  ELSE
+    r := NEW;
+  END IF;
+
+  IF TG_OP = 'UPDATE' AND to_jsonb(NEW) - 'clear_status' = to_jsonb(OLD) - 'clear_status' THEN
+    RETURN r;
+  END IF;
+
+  PERFORM 1 FROM accounts WHERE id=r.account_id FOR SHARE;
+  SELECT closed_until INTO closed FROM period_closings
+  WHERE account_id=r.account_id ORDER BY id DESC LIMIT 1;
+
+  IF closed IS NOT NULL AND (r.date<=closed OR (TG_OP = 'UPDATE' AND OLD.date<=closed)) THEN
+    RAISE EXCEPTION 'records dated on or before % are closed', closed USING ERRCODE = 'SA001';
+  END IF;
+  RETURN r;
+END
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,3 @@
+ALTER TABLE "user_erasures" DROP COLUMN IF EXISTS "error";
+
+ALTER TABLE "user_erasures" DROP COLUMN IF EXISTS "attempts";
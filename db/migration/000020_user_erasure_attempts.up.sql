@@ -0,0 +1,4 @@
+-- 注销失败时记录尝试次数和最后一次的错误, 达到上限后不再自动重试, 需要人工处理
+ALTER TABLE "user_erasures" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0;
+
+ALTER TABLE "user_erasures" ADD COLUMN "error" varchar NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE "budgets" DROP CONSTRAINT IF EXISTS "budgets_amount_positive";
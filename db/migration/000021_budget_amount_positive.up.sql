@@ -0,0 +1,2 @@
+-- 之前的接口没有限制预算金额, 已有的非正数金额不做校验, 修改预算时才需要满足约束
+ALTER TABLE "budgets" ADD CONSTRAINT "budgets_amount_positive" CHECK ("amount" > 0) NOT VALID;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "records_to_tsvector_idx";
+DROP TEXT SEARCH CONFIGURATION IF EXISTS star_account_search;
+CREATE TEXT SEARCH CONFIGURATION star_account_search (COPY = simple);
+CREATE INDEX "records_to_tsvector_idx" ON "records" USING GIN (to_tsvector('star_account_search', "name"));
@@ -0,0 +1,14 @@
+-- 数据库中已经安装了zhparser扩展(CREATE EXTENSION zhparser)时, 全文检索改用中文分词
+-- 没有安装时保持复制自simple的配置, 之后安装了扩展可以回滚并重新执行本迁移来切换
+-- 更换配置后需要重建依赖它的索引
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM pg_ts_parser WHERE prsname = 'zhparser') THEN
+    DROP INDEX IF EXISTS "records_to_tsvector_idx";
+    DROP TEXT SEARCH CONFIGURATION star_account_search;
+    CREATE TEXT SEARCH CONFIGURATION star_account_search (PARSER = zhparser);
+    ALTER TEXT SEARCH CONFIGURATION star_account_search ADD MAPPING FOR n,v,a,i,e,l WITH simple;
+    CREATE INDEX "records_to_tsvector_idx" ON "records" USING GIN (to_tsvector('star_account_search', "name"));
+  END IF;
+END
+$$;
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+	"github.com/timelyrain/star-account/util"
+)
+
+type Notification = sqlc.Notification
+
+type NotificationPreference = sqlc.NotificationPreference
+
+/**
+ * 待写入的通知, InApp和Email由接收者的偏好决定, 都为false时不写入
+ */
+type NewNotification struct {
+	UserId int64
+	Type   string
+	Data   json.RawMessage
+	InApp  bool
+	Email  bool
+}
+
+func (db *DB) CreateNotifications(ctx context.Context, notifications []NewNotification) error {
+	return db.execTx(ctx, func(q *sqlc.Queries) error {
+		for _, notification := range notifications {
+			if !notification.InApp && !notification.Email {
+				continue
+			}
+
+			emailStatus := int32(util.NotificationEmailStatusNone)
+			if notification.Email {
+				emailStatus = util.NotificationEmailStatusPending
+			}
+
+			arg := sqlc.CreateNotificationParams{
+				UserID:      notification.UserId,
+				Type:        notification.Type,
+				Data:        notification.Data,
+				InApp:       notification.InApp,
+				EmailStatus: emailStatus,
+			}
+
+			_, err := q.CreateNotification(ctx, arg)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *DB) GetNotificationsByUserIdAfterCursor(
+	ctx context.Context,
+	userId int64,
+	unreadOnly bool,
+	cursorId int64,
+	limit int64,
+) ([]Notification, error) {
+	var res []Notification
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetNotificationsByUserIdAfterCursorParams{
+			UserID:     userId,
+			UnreadOnly: unreadOnly,
+			CursorID:   nullInt64(cursorId),
+			PageLimit:  limit,
+		}
+
+		res, err = q.GetNotificationsByUserIdAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetUnreadNotificationsCountByUserId(ctx context.Context, userId int64) (int64, error) {
+	var res int64
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetUnreadNotificationsCountByUserId(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 通知不属于userId时返回sql.ErrNoRows
+ */
+func (db *DB) MarkNotificationRead(ctx context.Context, id int64, userId int64) (Notification, error) {
+	var res Notification
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.MarkNotificationReadParams{
+			ID:     id,
+			UserID: userId,
+		}
+
+		res, err = q.MarkNotificationRead(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) MarkAllNotificationsRead(ctx context.Context, userId int64) (int64, error) {
+	var res int64
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.MarkAllNotificationsRead(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 领取待发送的通知邮件, 并把下次发送时间推迟到leaseUntil
+ * 领取者崩溃时, 通知在leaseUntil之后可以被重新领取
+ */
+func (db *DB) ClaimNotificationEmails(
+	ctx context.Context,
+	now time.Time,
+	leaseUntil time.Time,
+	limit int64,
+) ([]Notification, error) {
+	var res []Notification
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.ClaimNotificationEmailsParams{
+			LeaseUntil: leaseUntil,
+			Now:        now,
+			PageLimit:  limit,
+		}
+
+		res, err = q.ClaimNotificationEmails(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpdateNotificationEmailAttempt(
+	ctx context.Context,
+	id int64,
+	status int32,
+	nextAttemptTime time.Time,
+	errMsg string,
+) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.UpdateNotificationEmailAttemptParams{
+			ID:                   id,
+			EmailStatus:          status,
+			EmailNextAttemptTime: nextAttemptTime,
+			EmailError:           errMsg,
+		}
+
+		return q.UpdateNotificationEmailAttempt(ctx, arg)
+	})
+}
+
+/**
+ * 只返回用户设置过的类型
+ */
+func (db *DB) GetNotificationPreferencesByUserId(ctx context.Context, userId int64) ([]NotificationPreference, error) {
+	var res []NotificationPreference
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetNotificationPreferencesByUserId(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetNotificationPreferencesByTypeAndUserIds(
+	ctx context.Context,
+	notificationType string,
+	userIds []int64,
+) ([]NotificationPreference, error) {
+	var res []NotificationPreference
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetNotificationPreferencesByTypeAndUserIdsParams{
+			Type:    notificationType,
+			UserIds: userIds,
+		}
+
+		res, err = q.GetNotificationPreferencesByTypeAndUserIds(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpsertNotificationPreference(
+	ctx context.Context,
+	userId int64,
+	notificationType string,
+	inApp bool,
+	email bool,
+) (NotificationPreference, error) {
+	var res NotificationPreference
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.UpsertNotificationPreferenceParams{
+			UserID: userId,
+			Type:   notificationType,
+			InApp:  inApp,
+			Email:  email,
+		}
+
+		res, err = q.UpsertNotificationPreference(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+	"github.com/timelyrain/star-account/util"
+)
+
+type PeriodClosing = sqlc.PeriodClosing
+
+var (
+	ErrInvalidClosingDate = errors.New("closing date must be after the current closed date")
+	ErrInvalidReopenDate  = errors.New("reopen date must be before the current closed date")
+	ErrPeriodNotClosed    = errors.New("account has no closed period")
+	ErrPendingRecords     = errors.New("account has pending records on or before the closing date")
+)
+
+/**
+ * 账单当前的关账日期, 没有关账时Valid为false
+ */
+func getClosedUntil(ctx context.Context, q *sqlc.Queries, accountId int64) (sql.NullTime, error) {
+	closing, err := q.GetLatestPeriodClosing(ctx, accountId)
+	if err == sql.ErrNoRows {
+		return sql.NullTime{}, nil
+	}
+	return closing.ClosedUntil, err
+}
+
+/**
+ * 按日期所在时区的日历日期比较, 与写入date列时的转换一致
+ */
+func IsDateClosed(date time.Time, closedUntil time.Time) bool {
+	return date.Format(util.DateFormat) <= closedUntil.Format(util.DateFormat)
+}
+
+/**
+ * 日期在关账日期及之前时返回ErrPeriodClosed
+ * 触发器同样会拒绝这些修改, 提前检查使同步推送中的单条修改失败而不影响同一批的其他修改
+ */
+func checkPeriodOpen(ctx context.Context, q *sqlc.Queries, accountId int64, dates ...time.Time) error {
+	closedUntil, err := getClosedUntil(ctx, q, accountId)
+	if err != nil || !closedUntil.Valid {
+		return err
+	}
+
+	for _, date := range dates {
+		if IsDateClosed(date, closedUntil.Time) {
+			return ErrPeriodClosed
+		}
+	}
+
+	return nil
+}
+
+func (db *DB) GetClosedUntil(ctx context.Context, accountId int64) (sql.NullTime, error) {
+	var res sql.NullTime
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = getClosedUntil(ctx, q, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 1. 锁定账单, 等待正在写入记录的事务提交
+ * 2. 新的关账日期必须晚于当前的关账日期, 提前关账日期使用ReopenPeriod
+ * 3. 关账日期及之前不能有待审批的记录, 否则关账之后这些记录无法审批
+ * 4. 写入关账记录
+ */
+func (db *DB) ClosePeriod(
+	ctx context.Context,
+	accountId int64,
+	closedUntil time.Time,
+	userId int64,
+	reason string,
+) (PeriodClosing, error) {
+	var res PeriodClosing
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		err := q.LockAccount(ctx, accountId)
+		if err != nil {
+			return err
+		}
+
+		var current sql.NullTime
+		current, err = getClosedUntil(ctx, q, accountId)
+		if err != nil {
+			return err
+		}
+
+		if current.Valid && IsDateClosed(closedUntil, current.Time) {
+			return ErrInvalidClosingDate
+		}
+
+		countArg := sqlc.GetPendingRecordsCountByAccountIdUntilParams{
+			AccountID: accountId,
+			EndDate:   closedUntil,
+		}
+		var pending int64
+		pending, err = q.GetPendingRecordsCountByAccountIdUntil(ctx, countArg)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrPendingRecords
+		}
+
+		arg := sqlc.CreatePeriodClosingParams{
+			AccountID:           accountId,
+			Action:              util.PeriodClosingActionClose,
+			ClosedUntil:         nullTime(closedUntil),
+			PreviousClosedUntil: current,
+			UserID:              userId,
+			Reason:              reason,
+		}
+		res, err = q.CreatePeriodClosing(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 1. 锁定账单
+ * 2. closedUntil为零值时完全重新开放, 否则必须早于当前的关账日期
+ * 3. 写入重新开放记录
+ */
+func (db *DB) ReopenPeriod(
+	ctx context.Context,
+	accountId int64,
+	closedUntil time.Time,
+	userId int64,
+	reason string,
+) (PeriodClosing, error) {
+	var res PeriodClosing
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		err := q.LockAccount(ctx, accountId)
+		if err != nil {
+			return err
+		}
+
+		var current sql.NullTime
+		current, err = getClosedUntil(ctx, q, accountId)
+		if err != nil {
+			return err
+		}
+
+		if !current.Valid {
+			return ErrPeriodNotClosed
+		}
+
+		if !closedUntil.IsZero() && closedUntil.Format(util.DateFormat) >= current.Time.Format(util.DateFormat) {
+			return ErrInvalidReopenDate
+		}
+
+		arg := sqlc.CreatePeriodClosingParams{
+			AccountID:           accountId,
+			Action:              util.PeriodClosingActionReopen,
+			ClosedUntil:         nullTime(closedUntil),
+			PreviousClosedUntil: current,
+			UserID:              userId,
+			Reason:              reason,
+		}
+		res, err = q.CreatePeriodClosing(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetPeriodClosingsByAccountId(ctx context.Context, accountId int64) ([]PeriodClosing, error) {
+	var res []PeriodClosing
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetPeriodClosingsByAccountId(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
@@ -9,7 +9,16 @@ INSERT INTO accounts (
 SELECT * FROM accounts WHERE id=$1;
 
 -- name: GetAccountsByIds :many
-SELECT * FROM accounts WHERE id=ANY(sqlc.arg(ids)::bigint[]);
+SELECT * FROM accounts WHERE id=ANY(sqlc.arg(ids)::bigint[]) ORDER BY id DESC;
+
+-- name: GetAccountsByUserIdAndRoleAfterCursor :many
+SELECT accounts.* FROM accounts
+JOIN account_access_rules ON account_access_rules.account_id=accounts.id
+WHERE account_access_rules.user_id=sqlc.arg(user_id)
+    AND account_access_rules.role=sqlc.arg(role)
+    AND (sqlc.narg(cursor_id)::bigint IS NULL OR accounts.id<sqlc.narg(cursor_id))
+ORDER BY accounts.id DESC
+LIMIT sqlc.arg(page_limit);
 
 -- name: UpdateAccountName :exec
 UPDATE accounts SET name=$2 WHERE id=$1;
@@ -18,4 +27,55 @@ UPDATE accounts SET name=$2 WHERE id=$1;
 DELETE FROM accounts WHERE id=$1;
 
 -- name: DeleteAccountsByIds :exec
-DELETE FROM accounts WHERE id=ANY(sqlc.arg(ids)::bigint[]);
\ No newline at end of file
+DELETE FROM accounts WHERE id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: GetAccountSummariesByUserId :many
+WITH user_accounts AS (
+    SELECT account_id, role FROM account_access_rules WHERE user_id=sqlc.arg(user_id)
+), members AS (
+    SELECT account_id, COUNT(*) AS member_count FROM account_access_rules
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+), stats AS (
+    SELECT
+        account_id,
+        COUNT(*) AS record_count,
+        SUM(amount) FILTER (WHERE date>=sqlc.arg(this_month_start)::date AND date<sqlc.arg(next_month_start)::date AND approval_status=2) AS this_month_amount,
+        SUM(amount) FILTER (WHERE date>=sqlc.arg(last_month_start)::date AND date<sqlc.arg(this_month_start)::date AND approval_status=2) AS last_month_amount,
+        MAX(create_time) AS latest_record_time
+    FROM records
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+), changes AS (
+    SELECT account_id, MAX(change_time) AS latest_change_time FROM sync_changes
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+), comments AS (
+    SELECT account_id, MAX(GREATEST(create_time, update_time, delete_time)) AS latest_comment_time FROM record_comments
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+)
+SELECT
+    accounts.id AS account_id,
+    accounts.name,
+    user_accounts.role,
+    COALESCE(members.member_count, 0)::bigint AS member_count,
+    COALESCE(stats.record_count, 0)::bigint AS record_count,
+    COALESCE(stats.this_month_amount, 0)::numeric AS this_month_amount,
+    COALESCE(stats.last_month_amount, 0)::numeric AS last_month_amount,
+    GREATEST(
+        stats.latest_record_time,
+        changes.latest_change_time,
+        comments.latest_comment_time,
+        accounts.create_time
+    )::timestamptz AS latest_activity_time
+FROM user_accounts
+JOIN accounts ON accounts.id=user_accounts.account_id
+LEFT JOIN members ON members.account_id=user_accounts.account_id
+LEFT JOIN stats ON stats.account_id=user_accounts.account_id
+LEFT JOIN changes ON changes.account_id=user_accounts.account_id
+LEFT JOIN comments ON comments.account_id=user_accounts.account_id
+ORDER BY latest_activity_time DESC, accounts.id DESC;
+
+-- name: LockAccount :exec
+SELECT id FROM accounts WHERE id=$1 FOR NO KEY UPDATE;
@@ -10,9 +10,16 @@ INSERT INTO account_access_rules (
 -- name: GetAccountAccessRuleByUserIdAndAccountId :one
 SELECT * FROM account_access_rules WHERE user_id=$1 AND account_id=$2;
 
+-- name: GetAccountAccessRulesByAccountId :many
+SELECT * FROM account_access_rules WHERE account_id=$1 ORDER BY id;
+
+-- name: GetAccountAccessRulesByUserId :many
+SELECT * FROM account_access_rules WHERE user_id=$1 ORDER BY account_id;
+
 -- name: GetUserIdsByAccountIdAndRole :many
 SELECT user_id FROM account_access_rules
 WHERE account_id=$1 AND role=$2
+ORDER BY user_id
 OFFSET $3
 LIMIT $4;
 
@@ -22,6 +29,7 @@ SELECT COUNT(*) FROM account_access_rules WHERE account_id=$1 AND role=$2;
 -- name: GetAccountIdsByUserIdAndRole :many
 SELECT account_id FROM account_access_rules
 WHERE user_id=$1 AND role=$2
+ORDER BY account_id DESC
 OFFSET $3
 LIMIT $4;
 
@@ -0,0 +1,63 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (
+    record_id,
+    account_id,
+    blob_key,
+    filename,
+    content_type,
+    size,
+    create_user_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetAttachment :one
+SELECT * FROM attachments WHERE id=$1;
+
+-- name: GetAttachmentsByRecordId :many
+SELECT * FROM attachments WHERE record_id=$1 ORDER BY id;
+
+-- name: GetAttachmentsCountByRecordId :one
+SELECT COUNT(*) FROM attachments WHERE record_id=$1;
+
+-- name: GetAttachmentsSizeSumByAccountId :one
+SELECT COALESCE(SUM(size), 0)::bigint FROM attachments WHERE account_id=$1;
+
+-- name: DeleteAttachment :exec
+WITH deleted AS (
+    DELETE FROM attachments WHERE id=$1 RETURNING blob_key
+)
+INSERT INTO blob_deletions (blob_key) SELECT blob_key FROM deleted;
+
+-- name: DeleteAttachmentsByRecordId :exec
+WITH deleted AS (
+    DELETE FROM attachments WHERE record_id=$1 RETURNING blob_key
+)
+INSERT INTO blob_deletions (blob_key) SELECT blob_key FROM deleted;
+
+-- name: DeleteAttachmentsByRecordIds :exec
+WITH deleted AS (
+    DELETE FROM attachments WHERE record_id=ANY(sqlc.arg(ids)::bigint[]) RETURNING blob_key
+)
+INSERT INTO blob_deletions (blob_key) SELECT blob_key FROM deleted;
+
+-- name: DeleteAttachmentsByAccountId :exec
+WITH deleted AS (
+    DELETE FROM attachments WHERE account_id=$1 RETURNING blob_key
+)
+INSERT INTO blob_deletions (blob_key) SELECT blob_key FROM deleted;
+
+-- name: DeleteAttachmentsByAccountIds :exec
+WITH deleted AS (
+    DELETE FROM attachments WHERE account_id=ANY(sqlc.arg(ids)::bigint[]) RETURNING blob_key
+)
+INSERT INTO blob_deletions (blob_key) SELECT blob_key FROM deleted;
+
+-- name: CreateBlobDeletion :exec
+INSERT INTO blob_deletions (blob_key) VALUES ($1) ON CONFLICT DO NOTHING;
+
+-- name: GetBlobDeletions :many
+SELECT * FROM blob_deletions ORDER BY create_time LIMIT $1;
+
+-- name: DeleteBlobDeletion :exec
+DELETE FROM blob_deletions WHERE blob_key=$1;
@@ -0,0 +1,123 @@
+-- name: CreateBudget :one
+INSERT INTO budgets (
+    account_id,
+    type,
+    user_id,
+    period,
+    amount,
+    rollover,
+    thresholds
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetBudget :one
+SELECT * FROM budgets WHERE id=$1;
+
+-- name: UpdateBudget :exec
+UPDATE budgets SET amount=$2, rollover=$3, thresholds=$4 WHERE id=$1;
+
+-- name: DeleteBudget :exec
+DELETE FROM budgets WHERE id=$1;
+
+-- name: DeleteBudgetsByAccountId :exec
+DELETE FROM budgets WHERE account_id=$1;
+
+-- name: DeleteBudgetsByUserId :exec
+DELETE FROM budgets WHERE user_id=$1;
+
+-- name: DeleteBudgetsByAccountIds :exec
+DELETE FROM budgets WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: GetBudgetProgresses :many
+WITH periods AS (
+    SELECT
+        budgets.id,
+        budgets.account_id,
+        budgets.type,
+        budgets.user_id,
+        budgets.amount,
+        budgets.rollover,
+        budgets.thresholds,
+        date_trunc(units.unit, sqlc.arg(date)::date::timestamp)::date AS period_start,
+        (date_trunc(units.unit, sqlc.arg(date)::date::timestamp) + ('1 ' || units.unit)::interval)::date AS period_end,
+        (date_trunc(units.unit, sqlc.arg(date)::date::timestamp) - ('1 ' || units.unit)::interval)::date AS previous_period_start
+    FROM budgets
+    CROSS JOIN LATERAL (
+        SELECT CASE budgets.period WHEN 1 THEN 'week' WHEN 2 THEN 'month' ELSE 'year' END AS unit
+    ) AS units
+    WHERE budgets.account_id=sqlc.arg(account_id)
+        AND (sqlc.narg(record_type)::integer IS NULL OR budgets.type IS NULL OR budgets.type=sqlc.narg(record_type))
+        AND (sqlc.narg(record_user_id)::bigint IS NULL OR budgets.user_id IS NULL OR budgets.user_id=sqlc.narg(record_user_id))
+), spending AS (
+    SELECT
+        periods.id,
+        COALESCE(SUM(records.amount) FILTER (WHERE records.date>=periods.period_start), 0) AS spent,
+        COALESCE(SUM(records.amount) FILTER (WHERE records.date<periods.period_start), 0) AS previous_spent
+    FROM periods
+    LEFT JOIN records ON records.account_id=periods.account_id
+        AND records.amount>0
+        AND records.approval_status=2
+        AND records.date>=periods.previous_period_start
+        AND records.date<periods.period_end
+        AND (periods.type IS NULL OR records.type=periods.type)
+        AND (periods.user_id IS NULL OR records.create_user_id=periods.user_id)
+    GROUP BY periods.id
+), limits AS (
+    SELECT
+        periods.id,
+        (periods.amount + CASE WHEN periods.rollover THEN GREATEST(periods.amount-spending.previous_spent, 0) ELSE 0 END) AS limit_amount
+    FROM periods
+    JOIN spending ON spending.id=periods.id
+)
+SELECT
+    periods.id AS budget_id,
+    periods.period_start::date AS period_start,
+    periods.period_end::date AS period_end,
+    limits.limit_amount::numeric AS limit_amount,
+    spending.spent::numeric AS spent,
+    (CASE WHEN limits.limit_amount>0 THEN floor(spending.spent*100/limits.limit_amount) ELSE 0 END)::integer AS percent,
+    periods.thresholds
+FROM periods
+JOIN spending ON spending.id=periods.id
+JOIN limits ON limits.id=periods.id
+ORDER BY periods.id;
+
+-- name: GetBudgetsByAccountId :many
+SELECT * FROM budgets WHERE account_id=$1 ORDER BY id;
+
+-- name: CreateBudgetAlert :one
+INSERT INTO budget_alerts (
+    budget_id,
+    account_id,
+    period_start,
+    threshold,
+    spent,
+    limit_amount
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (budget_id, period_start, threshold) DO NOTHING
+RETURNING *;
+
+-- name: GetBudgetAlertsByAccountId :many
+SELECT * FROM budget_alerts WHERE account_id=$1 ORDER BY id;
+
+-- name: GetBudgetAlertsByAccountIdAfterCursor :many
+SELECT * FROM budget_alerts
+WHERE account_id=sqlc.arg(account_id)
+    AND (sqlc.narg(cursor_id)::bigint IS NULL OR id<sqlc.narg(cursor_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: DeleteBudgetAlertsByBudgetId :exec
+DELETE FROM budget_alerts WHERE budget_id=$1;
+
+-- name: DeleteBudgetAlertsByAccountId :exec
+DELETE FROM budget_alerts WHERE account_id=$1;
+
+-- name: DeleteBudgetAlertsByBudgetUserId :exec
+DELETE FROM budget_alerts WHERE budget_id IN (SELECT id FROM budgets WHERE user_id=$1);
+
+-- name: DeleteBudgetAlertsByAccountIds :exec
+DELETE FROM budget_alerts WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
@@ -0,0 +1,32 @@
+-- name: UpsertDigestSubscription :one
+INSERT INTO digest_subscriptions (
+    user_id,
+    frequency,
+    time_zone,
+    next_send_time
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (user_id) DO UPDATE
+SET frequency=EXCLUDED.frequency, time_zone=EXCLUDED.time_zone, next_send_time=EXCLUDED.next_send_time
+RETURNING *;
+
+-- name: GetDigestSubscription :one
+SELECT * FROM digest_subscriptions WHERE user_id=$1;
+
+-- name: DeleteDigestSubscription :exec
+DELETE FROM digest_subscriptions WHERE user_id=$1;
+
+-- name: ClaimDueDigestSubscriptions :many
+UPDATE digest_subscriptions SET next_send_time=sqlc.arg(lease_until)
+WHERE user_id IN (
+    SELECT user_id FROM digest_subscriptions
+    WHERE next_send_time<=sqlc.arg(now)
+    ORDER BY next_send_time
+    LIMIT sqlc.arg(page_limit)
+    FOR UPDATE SKIP LOCKED
+) RETURNING *;
+
+-- name: UpdateDigestSubscriptionSent :exec
+UPDATE digest_subscriptions SET next_send_time=$2, last_sent_time=$3
+WHERE user_id=$1;
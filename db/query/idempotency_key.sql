@@ -0,0 +1,34 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    user_id,
+    key,
+    request_hash,
+    expire_time
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (user_id, key) DO NOTHING
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys WHERE user_id=$1 AND key=$2;
+
+-- name: UpdateIdempotencyKeyResponse :exec
+UPDATE idempotency_keys SET status_code=$2, content_type=$3, response_body=$4 WHERE id=$1;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys WHERE id=$1;
+
+-- name: DeleteExpiredIdempotencyKey :exec
+DELETE FROM idempotency_keys WHERE user_id=$1 AND key=$2 AND expire_time<now();
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE id IN (
+    SELECT id FROM idempotency_keys
+    WHERE expire_time<now()
+    LIMIT $1
+);
+
+-- name: DeleteIdempotencyKeysByUserId :exec
+DELETE FROM idempotency_keys WHERE user_id=$1;
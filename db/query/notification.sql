@@ -0,0 +1,75 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (
+    user_id,
+    type,
+    data,
+    in_app,
+    email_status
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetNotificationsByUserIdAfterCursor :many
+SELECT * FROM notifications
+WHERE user_id=sqlc.arg(user_id)
+    AND in_app
+    AND (NOT sqlc.arg(unread_only)::boolean OR read_time IS NULL)
+    AND (sqlc.narg(cursor_id)::bigint IS NULL OR id<sqlc.narg(cursor_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetUnreadNotificationsCountByUserId :one
+SELECT COUNT(*) FROM notifications WHERE user_id=$1 AND in_app AND read_time IS NULL;
+
+-- name: MarkNotificationRead :one
+UPDATE notifications SET read_time=COALESCE(read_time, now())
+WHERE id=$1 AND user_id=$2 AND in_app
+RETURNING *;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_time=now()
+WHERE user_id=$1 AND in_app AND read_time IS NULL;
+
+-- name: ClaimNotificationEmails :many
+UPDATE notifications SET email_next_attempt_time=sqlc.arg(lease_until)
+WHERE id IN (
+    SELECT id FROM notifications
+    WHERE email_status=1 AND email_next_attempt_time<=sqlc.arg(now)
+    ORDER BY email_next_attempt_time
+    LIMIT sqlc.arg(page_limit)
+    FOR UPDATE SKIP LOCKED
+) RETURNING *;
+
+-- name: UpdateNotificationEmailAttempt :exec
+UPDATE notifications SET
+    email_status=$2,
+    email_attempts=email_attempts+1,
+    email_next_attempt_time=$3,
+    email_error=$4
+WHERE id=$1;
+
+-- name: DeleteNotificationsByUserId :exec
+DELETE FROM notifications WHERE user_id=$1;
+
+-- name: GetNotificationPreferencesByUserId :many
+SELECT * FROM notification_preferences WHERE user_id=$1 ORDER BY type;
+
+-- name: GetNotificationPreferencesByTypeAndUserIds :many
+SELECT * FROM notification_preferences
+WHERE type=sqlc.arg(type) AND user_id=ANY(sqlc.arg(user_ids)::bigint[]);
+
+-- name: UpsertNotificationPreference :one
+INSERT INTO notification_preferences (
+    user_id,
+    type,
+    in_app,
+    email
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (user_id, type) DO UPDATE
+SET in_app=EXCLUDED.in_app, email=EXCLUDED.email
+RETURNING *;
+
+-- name: DeleteNotificationPreferencesByUserId :exec
+DELETE FROM notification_preferences WHERE user_id=$1;
@@ -0,0 +1,23 @@
+-- name: CreatePeriodClosing :one
+INSERT INTO period_closings (
+    account_id,
+    action,
+    closed_until,
+    previous_closed_until,
+    user_id,
+    reason
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetLatestPeriodClosing :one
+SELECT * FROM period_closings WHERE account_id=$1 ORDER BY id DESC LIMIT 1;
+
+-- name: GetPeriodClosingsByAccountId :many
+SELECT * FROM period_closings WHERE account_id=$1 ORDER BY id DESC;
+
+-- name: DeletePeriodClosingsByAccountId :exec
+DELETE FROM period_closings WHERE account_id=$1;
+
+-- name: DeletePeriodClosingsByAccountIds :exec
+DELETE FROM period_closings WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
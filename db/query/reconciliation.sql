@@ -0,0 +1,89 @@
+-- name: UpsertOpeningBalance :one
+INSERT INTO opening_balances (
+    account_id,
+    amount,
+    date,
+    update_user_id
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (account_id) DO UPDATE
+SET amount=EXCLUDED.amount, date=EXCLUDED.date, update_user_id=EXCLUDED.update_user_id, update_time=now()
+RETURNING *;
+
+-- name: GetOpeningBalance :one
+SELECT * FROM opening_balances WHERE account_id=$1;
+
+-- name: DeleteOpeningBalance :exec
+DELETE FROM opening_balances WHERE account_id=$1;
+
+-- name: DeleteOpeningBalancesByAccountIds :exec
+DELETE FROM opening_balances WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: CreateBalanceCheckpoint :one
+INSERT INTO balance_checkpoints (
+    account_id,
+    date,
+    balance,
+    note,
+    create_user_id
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetBalanceCheckpoint :one
+SELECT * FROM balance_checkpoints WHERE id=$1;
+
+-- name: DeleteBalanceCheckpoint :exec
+DELETE FROM balance_checkpoints WHERE id=$1;
+
+-- name: DeleteBalanceCheckpointsByAccountId :exec
+DELETE FROM balance_checkpoints WHERE account_id=$1;
+
+-- name: DeleteBalanceCheckpointsByAccountIds :exec
+DELETE FROM balance_checkpoints WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: SetBalanceCheckpointReconciled :exec
+UPDATE balance_checkpoints SET reconcile_time=now() WHERE id=$1;
+
+-- name: GetCheckpointBalancesByAccountId :many
+SELECT
+    balance_checkpoints.id,
+    balance_checkpoints.account_id,
+    balance_checkpoints.date,
+    balance_checkpoints.balance,
+    balance_checkpoints.note,
+    balance_checkpoints.create_user_id,
+    balance_checkpoints.create_time,
+    balance_checkpoints.reconcile_time,
+    (COALESCE(opening_balances.amount, 0) - sums.total)::numeric AS computed_balance,
+    (COALESCE(opening_balances.amount, 0) - sums.cleared)::numeric AS cleared_balance,
+    (balance_checkpoints.balance - COALESCE(opening_balances.amount, 0) + sums.total)::numeric AS difference,
+    (balance_checkpoints.balance - COALESCE(opening_balances.amount, 0) + sums.cleared)::numeric AS cleared_difference
+FROM balance_checkpoints
+LEFT JOIN opening_balances ON opening_balances.account_id=balance_checkpoints.account_id
+CROSS JOIN LATERAL (
+    SELECT
+        COALESCE(SUM(records.amount), 0) AS total,
+        COALESCE(SUM(records.amount) FILTER (WHERE records.clear_status<>0), 0) AS cleared
+    FROM records
+    WHERE records.account_id=balance_checkpoints.account_id
+        AND records.approval_status=2
+        AND records.date<=balance_checkpoints.date
+        AND (opening_balances.date IS NULL OR records.date>=opening_balances.date)
+) AS sums
+WHERE balance_checkpoints.account_id=$1
+ORDER BY balance_checkpoints.date;
+
+-- name: GetAccountBalance :one
+SELECT
+    (COALESCE(opening_balances.amount, 0) - COALESCE(SUM(records.amount), 0))::numeric AS balance,
+    (COALESCE(opening_balances.amount, 0) - COALESCE(SUM(records.amount) FILTER (WHERE records.clear_status<>0), 0))::numeric AS cleared_balance,
+    COUNT(records.id) FILTER (WHERE records.clear_status=0) AS uncleared_count
+FROM accounts
+LEFT JOIN opening_balances ON opening_balances.account_id=accounts.id
+LEFT JOIN records ON records.account_id=accounts.id
+    AND records.approval_status=2
+    AND (opening_balances.date IS NULL OR records.date>=opening_balances.date)
+WHERE accounts.id=$1
+GROUP BY opening_balances.amount;
@@ -6,25 +6,155 @@ INSERT INTO records (
     amount,
     account_id,
     create_user_id,
-    last_modified_user_id
+    last_modified_user_id,
+    external_id
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $6
+    $1, $2, $3, $4, $5, $6, $6, $7
 ) RETURNING *;
 
+-- name: CreateRecords :many
+WITH input AS (
+    SELECT nextval(pg_get_serial_sequence('records', 'id')) AS id, name, type, date, amount, account_id, ord
+    FROM unnest(
+        sqlc.arg(names)::varchar[],
+        sqlc.arg(types)::integer[],
+        sqlc.arg(dates)::date[],
+        sqlc.arg(amounts)::numeric[],
+        sqlc.arg(account_ids)::bigint[]
+    ) WITH ORDINALITY AS u(name, type, date, amount, account_id, ord)
+), inserted AS (
+    INSERT INTO records (
+        id,
+        name,
+        type,
+        date,
+        amount,
+        account_id,
+        create_user_id,
+        last_modified_user_id
+    ) SELECT
+        id,
+        name,
+        type,
+        date,
+        amount,
+        account_id,
+        sqlc.arg(create_user_id)::bigint,
+        sqlc.arg(create_user_id)::bigint
+    FROM input
+    RETURNING *
+)
+SELECT inserted.* FROM inserted
+JOIN input ON input.id=inserted.id
+ORDER BY input.ord;
+
+-- name: RestoreRecords :many
+WITH input AS (
+    SELECT nextval(pg_get_serial_sequence('records', 'id')) AS id,
+        name, type, date, amount, create_user_id, last_modified_user_id, create_time, external_id, ord
+    FROM unnest(
+        sqlc.arg(names)::varchar[],
+        sqlc.arg(types)::integer[],
+        sqlc.arg(dates)::date[],
+        sqlc.arg(amounts)::numeric[],
+        sqlc.arg(create_user_ids)::bigint[],
+        sqlc.arg(last_modified_user_ids)::bigint[],
+        sqlc.arg(create_times)::timestamptz[],
+        sqlc.arg(external_ids)::varchar[]
+    ) WITH ORDINALITY AS u(name, type, date, amount, create_user_id, last_modified_user_id, create_time, external_id, ord)
+), inserted AS (
+    INSERT INTO records (
+        id,
+        name,
+        type,
+        date,
+        amount,
+        account_id,
+        create_user_id,
+        last_modified_user_id,
+        create_time,
+        external_id
+    ) SELECT
+        id,
+        name,
+        type,
+        date,
+        amount,
+        sqlc.arg(account_id)::bigint,
+        create_user_id,
+        last_modified_user_id,
+        create_time,
+        external_id
+    FROM input
+    RETURNING id
+)
+SELECT input.id FROM inserted
+JOIN input ON input.id=inserted.id
+ORDER BY input.ord;
+
 -- name: GetRecord :one
 SELECT * FROM records WHERE id=$1;
 
+-- name: GetRecordsByIds :many
+SELECT * FROM records WHERE id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: GetRecordsAccountIds :many
+SELECT DISTINCT account_id FROM records WHERE id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: GetRecordsExternalIds :many
+SELECT external_id FROM records
+WHERE account_id=sqlc.arg(account_id) AND external_id=ANY(sqlc.arg(external_ids)::varchar[]);
+
 -- name: GetRecordsByAccountId :many
 SELECT * FROM records
 WHERE account_id=$1
+ORDER BY date DESC, id DESC
 OFFSET $2
 LIMIT $3;
 
+-- name: GetRecordsByAccountIdAfterCursor :many
+SELECT * FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND (sqlc.narg(cursor_date)::date IS NULL OR (date, id)<(sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint))
+ORDER BY date DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetRecordsForExport :many
+SELECT * FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND (sqlc.narg(start_date)::date IS NULL OR date>=sqlc.narg(start_date))
+    AND (sqlc.narg(end_date)::date IS NULL OR date<=sqlc.narg(end_date))
+    AND (sqlc.narg(cursor_date)::date IS NULL OR (date, id)>(sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint))
+ORDER BY date, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetRecordsByCreateUserId :many
+SELECT * FROM records WHERE create_user_id=$1 ORDER BY date, id;
+
 -- name: GetRecordsCountByAccountId :one
 SELECT COUNT(*) FROM records WHERE account_id=$1;
 
 -- name: GetRecordsAmountSumByAccountId :one
-SELECT SUM(amount) FROM records WHERE account_id=$1;
+SELECT SUM(amount) FROM records WHERE account_id=$1 AND approval_status=2;
+
+-- name: GetRecordsStatistics :many
+SELECT
+    date_trunc(sqlc.arg(interval)::text, date::timestamp)::date AS bucket,
+    (CASE sqlc.arg(group_by)::text
+        WHEN 'category' THEN type::bigint
+        WHEN 'creator' THEN create_user_id
+        ELSE 0
+    END)::bigint AS group_key,
+    COUNT(*) AS count,
+    COALESCE(-SUM(amount) FILTER (WHERE amount<0), 0)::numeric AS income,
+    COALESCE(SUM(amount) FILTER (WHERE amount>0), 0)::numeric AS expense
+FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND date>=sqlc.arg(start_date)::date
+    AND date<=sqlc.arg(end_date)::date
+    AND approval_status=2
+GROUP BY bucket, group_key
+ORDER BY bucket, group_key;
 
 -- name: GetRecordsByAccountIdAndCreateUserId :many
 SELECT * FROM records
@@ -38,16 +168,209 @@ WHERE account_id=$1 AND last_modified_user_id=$2
 OFFSET $3
 LIMIT $4;
 
--- name: UpdateRecord :exec
+-- name: UpdateRecord :one
 UPDATE records
 SET name=$2, type=$3, date=$4, amount=$5, last_modified_user_id=$6
-WHERE id=$1;
+WHERE id=$1 RETURNING *;
+
+-- name: UpdateRecords :many
+UPDATE records
+SET name=u.name, type=u.type, date=u.date, amount=u.amount, last_modified_user_id=sqlc.arg(last_modified_user_id)
+FROM (
+    SELECT
+        unnest(sqlc.arg(ids)::bigint[]) AS id,
+        unnest(sqlc.arg(names)::varchar[]) AS name,
+        unnest(sqlc.arg(types)::integer[]) AS type,
+        unnest(sqlc.arg(dates)::date[]) AS date,
+        unnest(sqlc.arg(amounts)::numeric[]) AS amount
+) AS u
+WHERE records.id=u.id
+RETURNING records.*;
 
 -- name: DeleteRecord :exec
 DELETE FROM records WHERE id=$1;
 
+-- name: DeleteRecordsByIds :exec
+DELETE FROM records WHERE id=ANY(sqlc.arg(ids)::bigint[]);
+
 -- name: DeleteRecordsByAccountId :exec
 DELETE FROM records WHERE account_id=$1;
 
 -- name: DeleteRecordsByAccountIds :exec
-DELETE FROM records WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
\ No newline at end of file
+DELETE FROM records WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: SearchRecords :many
+SELECT * FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND (sqlc.narg(start_date)::date IS NULL OR date>=sqlc.narg(start_date))
+    AND (sqlc.narg(end_date)::date IS NULL OR date<=sqlc.narg(end_date))
+    AND (COALESCE(cardinality(sqlc.arg(types)::integer[]), 0)=0 OR type=ANY(sqlc.arg(types)::integer[]))
+    AND (sqlc.narg(min_amount)::numeric IS NULL OR amount>=sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::numeric IS NULL OR amount<=sqlc.narg(max_amount))
+    AND (sqlc.narg(create_user_id)::bigint IS NULL OR create_user_id=sqlc.narg(create_user_id))
+    AND (sqlc.narg(last_modified_user_id)::bigint IS NULL OR last_modified_user_id=sqlc.narg(last_modified_user_id))
+    AND (sqlc.narg(name)::text IS NULL OR strpos(lower(name), lower(sqlc.narg(name)))>0)
+    AND (sqlc.narg(query)::text IS NULL OR to_tsvector('star_account_search', name) @@ plainto_tsquery('star_account_search', sqlc.narg(query)))
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text='amount' AND NOT sqlc.arg(sort_desc)::boolean THEN amount END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text='amount' AND sqlc.arg(sort_desc)::boolean THEN amount END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text='create_time' AND NOT sqlc.arg(sort_desc)::boolean THEN create_time END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text='create_time' AND sqlc.arg(sort_desc)::boolean THEN create_time END DESC,
+    CASE WHEN NOT sqlc.arg(sort_desc)::boolean THEN date END ASC,
+    CASE WHEN sqlc.arg(sort_desc)::boolean THEN date END DESC,
+    CASE WHEN NOT sqlc.arg(sort_desc)::boolean THEN id END ASC,
+    id DESC
+OFFSET sqlc.arg(page_offset)
+LIMIT sqlc.arg(page_limit);
+
+-- name: SearchRecordsAfterCursor :many
+SELECT * FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND (sqlc.narg(start_date)::date IS NULL OR date>=sqlc.narg(start_date))
+    AND (sqlc.narg(end_date)::date IS NULL OR date<=sqlc.narg(end_date))
+    AND (COALESCE(cardinality(sqlc.arg(types)::integer[]), 0)=0 OR type=ANY(sqlc.arg(types)::integer[]))
+    AND (sqlc.narg(min_amount)::numeric IS NULL OR amount>=sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::numeric IS NULL OR amount<=sqlc.narg(max_amount))
+    AND (sqlc.narg(create_user_id)::bigint IS NULL OR create_user_id=sqlc.narg(create_user_id))
+    AND (sqlc.narg(last_modified_user_id)::bigint IS NULL OR last_modified_user_id=sqlc.narg(last_modified_user_id))
+    AND (sqlc.narg(name)::text IS NULL OR strpos(lower(name), lower(sqlc.narg(name)))>0)
+    AND (sqlc.narg(query)::text IS NULL OR to_tsvector('star_account_search', name) @@ plainto_tsquery('star_account_search', sqlc.narg(query)))
+    AND (sqlc.narg(cursor_date)::date IS NULL OR CASE
+        WHEN sqlc.arg(sort_by)::text='amount' AND sqlc.arg(sort_desc)::boolean
+            THEN (amount, date, id)<(sqlc.narg(cursor_amount)::numeric, sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint)
+        WHEN sqlc.arg(sort_by)::text='amount'
+            THEN (amount, date, id)>(sqlc.narg(cursor_amount)::numeric, sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint)
+        WHEN sqlc.arg(sort_by)::text='create_time' AND sqlc.arg(sort_desc)::boolean
+            THEN (create_time, date, id)<(sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint)
+        WHEN sqlc.arg(sort_by)::text='create_time'
+            THEN (create_time, date, id)>(sqlc.narg(cursor_time)::timestamptz, sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint)
+        WHEN sqlc.arg(sort_desc)::boolean
+            THEN (date, id)<(sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint)
+        ELSE (date, id)>(sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint)
+    END)
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text='amount' AND NOT sqlc.arg(sort_desc)::boolean THEN amount END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text='amount' AND sqlc.arg(sort_desc)::boolean THEN amount END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text='create_time' AND NOT sqlc.arg(sort_desc)::boolean THEN create_time END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text='create_time' AND sqlc.arg(sort_desc)::boolean THEN create_time END DESC,
+    CASE WHEN NOT sqlc.arg(sort_desc)::boolean THEN date END ASC,
+    CASE WHEN sqlc.arg(sort_desc)::boolean THEN date END DESC,
+    CASE WHEN NOT sqlc.arg(sort_desc)::boolean THEN id END ASC,
+    id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetSearchRecordsSummary :one
+SELECT COUNT(*) AS total, COALESCE(SUM(amount) FILTER (WHERE approval_status=2), 0)::numeric AS amount_sum FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND (sqlc.narg(start_date)::date IS NULL OR date>=sqlc.narg(start_date))
+    AND (sqlc.narg(end_date)::date IS NULL OR date<=sqlc.narg(end_date))
+    AND (COALESCE(cardinality(sqlc.arg(types)::integer[]), 0)=0 OR type=ANY(sqlc.arg(types)::integer[]))
+    AND (sqlc.narg(min_amount)::numeric IS NULL OR amount>=sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::numeric IS NULL OR amount<=sqlc.narg(max_amount))
+    AND (sqlc.narg(create_user_id)::bigint IS NULL OR create_user_id=sqlc.narg(create_user_id))
+    AND (sqlc.narg(last_modified_user_id)::bigint IS NULL OR last_modified_user_id=sqlc.narg(last_modified_user_id))
+    AND (sqlc.narg(name)::text IS NULL OR strpos(lower(name), lower(sqlc.narg(name)))>0)
+    AND (sqlc.narg(query)::text IS NULL OR to_tsvector('star_account_search', name) @@ plainto_tsquery('star_account_search', sqlc.narg(query)));
+
+-- name: CreateSyncRecord :one
+INSERT INTO records (
+    name,
+    type,
+    date,
+    amount,
+    account_id,
+    create_user_id,
+    last_modified_user_id,
+    client_id,
+    field_times
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $6, $7, $8
+) RETURNING *;
+
+-- name: GetRecordForUpdate :one
+SELECT * FROM records WHERE id=$1 FOR UPDATE;
+
+-- name: GetRecordByClientIdForUpdate :one
+SELECT * FROM records WHERE account_id=$1 AND client_id=$2 FOR UPDATE;
+
+-- name: UpdateSyncRecord :one
+UPDATE records SET
+    name=$2,
+    type=$3,
+    date=$4,
+    amount=$5,
+    last_modified_user_id=$6,
+    field_times=$7
+WHERE id=$1
+RETURNING *;
+
+-- name: GetTopExpensesByAccountId :many
+SELECT * FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND date>=sqlc.arg(start_date)::date
+    AND date<=sqlc.arg(end_date)::date
+    AND amount>0
+    AND approval_status=2
+ORDER BY amount DESC, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetPendingRecordsCountByAccountIdUntil :one
+SELECT COUNT(*) FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND approval_status=1
+    AND date<=sqlc.arg(end_date);
+
+-- name: GetPendingRecordsByAccountIdAfterCursor :many
+SELECT * FROM records
+WHERE account_id=sqlc.arg(account_id)
+    AND approval_status=1
+    AND (sqlc.narg(cursor_id)::bigint IS NULL OR id>sqlc.narg(cursor_id))
+ORDER BY id
+LIMIT sqlc.arg(page_limit);
+
+-- name: UpdateRecordApprovalStatus :one
+UPDATE records SET approval_status=$2
+WHERE id=$1 RETURNING *;
+
+-- name: UpdateRecordsApprovalStatus :exec
+UPDATE records SET approval_status=u.approval_status
+FROM (
+    SELECT
+        unnest(sqlc.arg(ids)::bigint[]) AS id,
+        unnest(sqlc.arg(approval_statuses)::integer[]) AS approval_status
+) AS u
+WHERE records.id=u.id;
+
+-- name: GetRunningBalancesByAccountIdAfterCursor :many
+SELECT
+    id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time,
+    external_id, client_id, field_times, approval_status, clear_status, balance, cleared_balance
+FROM (
+    SELECT
+        records.*,
+        (sqlc.arg(opening_amount)::numeric - SUM(amount) OVER (ORDER BY date, id))::numeric AS balance,
+        (sqlc.arg(opening_amount)::numeric - COALESCE(SUM(amount) FILTER (WHERE clear_status<>0) OVER (ORDER BY date, id), 0))::numeric AS cleared_balance
+    FROM records
+    WHERE account_id=sqlc.arg(account_id)
+        AND approval_status=2
+        AND (sqlc.narg(start_date)::date IS NULL OR date>=sqlc.narg(start_date))
+) AS balances
+WHERE sqlc.narg(cursor_date)::date IS NULL OR (date, id)>(sqlc.narg(cursor_date)::date, sqlc.arg(cursor_id)::bigint)
+ORDER BY date, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: UpdateRecordsClearStatus :many
+UPDATE records SET clear_status=sqlc.arg(clear_status)
+WHERE account_id=sqlc.arg(account_id)
+    AND id=ANY(sqlc.arg(ids)::bigint[])
+    AND clear_status<>2
+    AND clear_status<>sqlc.arg(clear_status)
+RETURNING *;
+
+-- name: ReconcileRecords :execrows
+UPDATE records SET clear_status=2
+WHERE account_id=sqlc.arg(account_id)
+    AND clear_status=1
+    AND approval_status=2
+    AND date<=sqlc.arg(end_date)::date
+    AND (sqlc.narg(start_date)::date IS NULL OR date>=sqlc.narg(start_date));
@@ -0,0 +1,54 @@
+-- name: CreateApprovalRule :one
+INSERT INTO approval_rules (
+    account_id,
+    min_amount,
+    types,
+    create_user_id
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetApprovalRule :one
+SELECT * FROM approval_rules WHERE id=$1;
+
+-- name: GetApprovalRulesByAccountId :many
+SELECT * FROM approval_rules WHERE account_id=$1 ORDER BY id;
+
+-- name: UpdateApprovalRule :one
+UPDATE approval_rules SET min_amount=$2, types=$3
+WHERE id=$1 RETURNING *;
+
+-- name: DeleteApprovalRule :exec
+DELETE FROM approval_rules WHERE id=$1;
+
+-- name: DeleteApprovalRulesByAccountId :exec
+DELETE FROM approval_rules WHERE account_id=$1;
+
+-- name: DeleteApprovalRulesByAccountIds :exec
+DELETE FROM approval_rules WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: CreateRecordApproval :one
+INSERT INTO record_approvals (
+    record_id,
+    account_id,
+    status,
+    user_id,
+    reason
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetRecordApprovalsByRecordId :many
+SELECT * FROM record_approvals WHERE record_id=$1 ORDER BY id;
+
+-- name: DeleteRecordApprovalsByRecordId :exec
+DELETE FROM record_approvals WHERE record_id=$1;
+
+-- name: DeleteRecordApprovalsByRecordIds :exec
+DELETE FROM record_approvals WHERE record_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: DeleteRecordApprovalsByAccountId :exec
+DELETE FROM record_approvals WHERE account_id=$1;
+
+-- name: DeleteRecordApprovalsByAccountIds :exec
+DELETE FROM record_approvals WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
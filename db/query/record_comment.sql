@@ -0,0 +1,85 @@
+-- name: CreateRecordComment :one
+INSERT INTO record_comments (
+    record_id,
+    account_id,
+    parent_id,
+    user_id,
+    content,
+    mention_user_ids
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetRecordComment :one
+SELECT * FROM record_comments WHERE id=$1;
+
+-- name: GetRecordCommentsByRecordId :many
+SELECT * FROM record_comments WHERE record_id=$1 ORDER BY id;
+
+-- name: GetRecordCommentsByUserId :many
+SELECT * FROM record_comments WHERE user_id=$1 AND delete_time IS NULL ORDER BY id;
+
+-- name: UpdateRecordComment :one
+UPDATE record_comments SET content=$2, mention_user_ids=$3, update_time=now()
+WHERE id=$1 RETURNING *;
+
+-- name: DeleteRecordComment :one
+UPDATE record_comments SET content='', mention_user_ids='{}', delete_time=now()
+WHERE id=$1 RETURNING *;
+
+-- name: EraseRecordCommentsByUserId :exec
+UPDATE record_comments SET content='', mention_user_ids='{}', delete_time=COALESCE(delete_time, now())
+WHERE user_id=$1;
+
+-- name: DeleteRecordCommentsByRecordId :exec
+DELETE FROM record_comments WHERE record_id=$1;
+
+-- name: DeleteRecordCommentsByRecordIds :exec
+DELETE FROM record_comments WHERE record_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: DeleteRecordCommentsByAccountId :exec
+DELETE FROM record_comments WHERE account_id=$1;
+
+-- name: DeleteRecordCommentsByAccountIds :exec
+DELETE FROM record_comments WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: GetUnreadCommentsCountsByUserId :many
+SELECT
+    account_access_rules.account_id,
+    COUNT(record_comments.id) AS unread_count,
+    COUNT(record_comments.id) FILTER (
+        WHERE sqlc.arg(user_id)::bigint=ANY(record_comments.mention_user_ids)
+    ) AS mention_count
+FROM account_access_rules
+LEFT JOIN comment_reads ON comment_reads.user_id=account_access_rules.user_id
+    AND comment_reads.account_id=account_access_rules.account_id
+LEFT JOIN record_comments ON record_comments.account_id=account_access_rules.account_id
+    AND record_comments.id>COALESCE(comment_reads.last_read_comment_id, 0)
+    AND record_comments.user_id<>account_access_rules.user_id
+    AND record_comments.delete_time IS NULL
+WHERE account_access_rules.user_id=sqlc.arg(user_id)
+GROUP BY account_access_rules.account_id
+ORDER BY account_access_rules.account_id;
+
+-- name: GetLastRecordCommentIdByAccountId :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM record_comments WHERE account_id=$1;
+
+-- name: UpsertCommentRead :exec
+INSERT INTO comment_reads (
+    user_id,
+    account_id,
+    last_read_comment_id
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (user_id, account_id) DO UPDATE
+SET last_read_comment_id=GREATEST(comment_reads.last_read_comment_id, EXCLUDED.last_read_comment_id);
+
+-- name: DeleteCommentReadsByUserId :exec
+DELETE FROM comment_reads WHERE user_id=$1;
+
+-- name: DeleteCommentReadsByAccountId :exec
+DELETE FROM comment_reads WHERE account_id=$1;
+
+-- name: DeleteCommentReadsByAccountIds :exec
+DELETE FROM comment_reads WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
@@ -0,0 +1,30 @@
+-- name: GetSyncChangesSince :many
+SELECT * FROM sync_changes
+WHERE account_id=sqlc.arg(account_id)
+    AND change_seq>sqlc.arg(since)::bigint
+    AND NOT (deleted AND sqlc.arg(since)::bigint=0)
+ORDER BY change_seq
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetSyncChange :one
+SELECT * FROM sync_changes WHERE account_id=$1 AND entity=$2 AND entity_id=$3;
+
+-- name: GetRecordSyncChangeByClientId :one
+SELECT * FROM sync_changes WHERE account_id=$1 AND entity='record' AND client_id=$2;
+
+-- name: DeleteSyncChangesByAccountId :exec
+DELETE FROM sync_changes WHERE account_id=$1;
+
+-- name: DeleteSyncChangesByAccountIds :exec
+DELETE FROM sync_changes WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: DeleteSyncTombstonesBefore :execrows
+DELETE FROM sync_changes
+WHERE deleted AND change_time<sqlc.arg(before) AND (account_id, entity, entity_id) IN (
+    SELECT account_id, entity, entity_id FROM sync_changes
+    WHERE deleted AND change_time<sqlc.arg(before)
+    LIMIT sqlc.arg(page_limit)
+);
+
+-- name: LockAccountSyncChanges :exec
+SELECT pg_advisory_xact_lock(sqlc.arg(account_id)::bigint);
@@ -16,11 +16,28 @@ SELECT * FROM users WHERE email=$1 LIMIT 1;
 -- name: GetUsersByName :many
 SELECT * FROM users
 WHERE name=$1
+ORDER BY id
 OFFSET $2
 LIMIT $3;
 
+-- name: GetUsersByNameAfterCursor :many
+SELECT * FROM users
+WHERE name=sqlc.arg(name)
+    AND (sqlc.narg(cursor_id)::bigint IS NULL OR id>sqlc.narg(cursor_id))
+ORDER BY id
+LIMIT sqlc.arg(page_limit);
+
 -- name: GetUsersByIds :many
-SELECT * FROM users WHERE id=ANY(sqlc.arg(ids)::bigint[]);
+SELECT * FROM users WHERE id=ANY(sqlc.arg(ids)::bigint[]) ORDER BY id;
+
+-- name: GetUsersByAccountIdAndRoleAfterCursor :many
+SELECT users.* FROM users
+JOIN account_access_rules ON account_access_rules.user_id=users.id
+WHERE account_access_rules.account_id=sqlc.arg(account_id)
+    AND account_access_rules.role=sqlc.arg(role)
+    AND (sqlc.narg(cursor_id)::bigint IS NULL OR users.id>sqlc.narg(cursor_id))
+ORDER BY users.id
+LIMIT sqlc.arg(page_limit);
 
 -- name: UpdateUserName :exec
 UPDATE users SET name=$2 WHERE id=$1;
@@ -31,5 +48,5 @@ UPDATE users SET email=$2 WHERE id=$1;
 -- name: UpdateUserPassword :exec
 UPDATE users SET hashed_password=$2 WHERE id=$1;
 
--- name: DeleteUser :exec
-DELETE FROM users WHERE id=$1;
\ No newline at end of file
+-- name: AnonymizeUser :exec
+UPDATE users SET name=$2, email=$3, hashed_password='' WHERE id=$1;
\ No newline at end of file
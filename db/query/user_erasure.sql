@@ -0,0 +1,26 @@
+-- name: CreateUserErasure :one
+INSERT INTO user_erasures (
+    user_id,
+    erase_time
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (user_id) DO UPDATE SET user_id=EXCLUDED.user_id
+RETURNING *;
+
+-- name: GetUserErasure :one
+SELECT * FROM user_erasures WHERE user_id=$1;
+
+-- name: GetDueUserErasuresAfterCursor :many
+SELECT * FROM user_erasures
+WHERE erase_time<=sqlc.arg(now)
+    AND attempts<sqlc.arg(max_attempts)
+    AND (sqlc.narg(cursor_time)::timestamptz IS NULL OR (erase_time, user_id)>(sqlc.narg(cursor_time)::timestamptz, sqlc.arg(cursor_id)::bigint))
+ORDER BY erase_time, user_id
+LIMIT sqlc.arg(page_limit);
+
+-- name: DeleteUserErasure :exec
+DELETE FROM user_erasures WHERE user_id=$1;
+
+-- name: UpdateUserErasureAttempt :exec
+UPDATE user_erasures SET attempts=attempts+1, error=$2 WHERE user_id=$1;
@@ -0,0 +1,91 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+    account_id,
+    url,
+    secret,
+    events,
+    create_user_id
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE id=$1;
+
+-- name: GetWebhooksByIds :many
+SELECT * FROM webhooks WHERE id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: GetWebhooksByAccountId :many
+SELECT * FROM webhooks WHERE account_id=$1 ORDER BY id;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks SET url=$2, events=$3, active=$4 WHERE id=$1 RETURNING *;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id=$1;
+
+-- name: DeleteWebhooksByAccountId :exec
+DELETE FROM webhooks WHERE account_id=$1;
+
+-- name: DeleteWebhooksByAccountIds :exec
+DELETE FROM webhooks WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: CreateWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (
+    webhook_id,
+    account_id,
+    event,
+    payload
+)
+SELECT id, account_id, sqlc.arg(event)::varchar, sqlc.arg(payload)::text
+FROM webhooks
+WHERE account_id=sqlc.arg(account_id)
+    AND active
+    AND sqlc.arg(event)::varchar=ANY(events);
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE id=$1;
+
+-- name: GetWebhookDeliveriesByWebhookIdAfterCursor :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id=sqlc.arg(webhook_id)
+    AND (sqlc.narg(cursor_id)::bigint IS NULL OR id<sqlc.narg(cursor_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries SET next_attempt_time=sqlc.arg(lease_until)
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status=1 AND next_attempt_time<=sqlc.arg(now)
+    ORDER BY next_attempt_time
+    LIMIT sqlc.arg(page_limit)
+    FOR UPDATE SKIP LOCKED
+) RETURNING *;
+
+-- name: UpdateWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries SET
+    status=$2,
+    attempts=attempts+1,
+    next_attempt_time=$3,
+    response_status=$4,
+    error=$5,
+    delivered_time=$6
+WHERE id=$1;
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries SET
+    status=1,
+    attempts=0,
+    next_attempt_time=now(),
+    error=''
+WHERE id=$1 RETURNING *;
+
+-- name: DeleteWebhookDeliveriesByWebhookId :exec
+DELETE FROM webhook_deliveries WHERE webhook_id=$1;
+
+-- name: DeleteWebhookDeliveriesByAccountId :exec
+DELETE FROM webhook_deliveries WHERE account_id=$1;
+
+-- name: DeleteWebhookDeliveriesByAccountIds :exec
+DELETE FROM webhook_deliveries WHERE account_id=ANY(sqlc.arg(ids)::bigint[]);
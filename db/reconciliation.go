@@ -0,0 +1,297 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/timelyrain/star-account/db/sqlc"
+	"github.com/timelyrain/star-account/util"
+)
+
+type OpeningBalance = sqlc.OpeningBalance
+
+type BalanceCheckpoint = sqlc.BalanceCheckpoint
+
+type CheckpointBalance = sqlc.GetCheckpointBalancesByAccountIdRow
+
+type AccountBalance = sqlc.GetAccountBalanceRow
+
+type RunningBalance = sqlc.GetRunningBalancesByAccountIdAfterCursorRow
+
+var (
+	ErrCheckpointNotBalanced = errors.New("cleared balance does not match the checkpoint balance")
+	ErrCheckpointExists      = errors.New("account already has a checkpoint on this date")
+)
+
+/**
+ * 账单的期初余额, 没有设置时返回sql.ErrNoRows
+ */
+func (db *DB) GetOpeningBalance(ctx context.Context, accountId int64) (OpeningBalance, error) {
+	var res OpeningBalance
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetOpeningBalance(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 设置期初余额, 已有的期初余额会被替换
+ */
+func (db *DB) SetOpeningBalance(
+	ctx context.Context,
+	accountId int64,
+	amount string,
+	date time.Time,
+	userId int64,
+) (OpeningBalance, error) {
+	var res OpeningBalance
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.UpsertOpeningBalanceParams{
+			AccountID:    accountId,
+			Amount:       amount,
+			Date:         date,
+			UpdateUserID: userId,
+		}
+
+		res, err = q.UpsertOpeningBalance(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) DeleteOpeningBalance(ctx context.Context, accountId int64) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteOpeningBalance(ctx, accountId)
+	})
+}
+
+/**
+ * 每个账单每天只能有一个检查点, 重复时返回ErrCheckpointExists
+ */
+func (db *DB) CreateBalanceCheckpoint(
+	ctx context.Context,
+	accountId int64,
+	date time.Time,
+	balance string,
+	note string,
+	createUserId int64,
+) (BalanceCheckpoint, error) {
+	var res BalanceCheckpoint
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.CreateBalanceCheckpointParams{
+			AccountID:    accountId,
+			Date:         date,
+			Balance:      balance,
+			Note:         note,
+			CreateUserID: createUserId,
+		}
+
+		res, err = q.CreateBalanceCheckpoint(ctx, arg)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrCheckpointExists
+		}
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetBalanceCheckpoint(ctx context.Context, id int64) (BalanceCheckpoint, error) {
+	var res BalanceCheckpoint
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetBalanceCheckpoint(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) DeleteBalanceCheckpoint(ctx context.Context, id int64) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteBalanceCheckpoint(ctx, id)
+	})
+}
+
+/**
+ * 按日期排序的检查点, 以及期初余额加上检查点当天及之前已通过的记录得到的余额和差额
+ */
+func (db *DB) GetCheckpointBalancesByAccountId(ctx context.Context, accountId int64) ([]CheckpointBalance, error) {
+	var res []CheckpointBalance
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetCheckpointBalancesByAccountId(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 账单当前的余额, 已核对部分的余额和未核对的记录数
+ */
+func (db *DB) GetAccountBalance(ctx context.Context, accountId int64) (AccountBalance, error) {
+	var res AccountBalance
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetAccountBalance(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 从期初余额开始按日期和id的顺序累加已通过的记录, 返回游标之后的每条记录及其之后的余额
+ * 期初日期之前的记录不计入余额, 也不在结果中
+ */
+func (db *DB) GetRunningBalancesByAccountIdAfterCursor(
+	ctx context.Context,
+	accountId int64,
+	cursorDate time.Time,
+	cursorId int64,
+	limit int64,
+) ([]RunningBalance, error) {
+	var res []RunningBalance
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.GetRunningBalancesByAccountIdAfterCursorParams{
+			OpeningAmount: "0",
+			AccountID:     accountId,
+			CursorDate:    nullTime(cursorDate),
+			CursorID:      cursorId,
+			PageLimit:     limit,
+		}
+
+		opening, err := q.GetOpeningBalance(ctx, accountId)
+		if err == nil {
+			arg.OpeningAmount = opening.Amount
+			arg.StartDate = nullTime(opening.Date)
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		res, err = q.GetRunningBalancesByAccountIdAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 已对账的记录是否会被修改金额或日期, 这样的修改会被拒绝并返回ErrRecordReconciled
+ */
+func IsReconciledRecordChanged(record Record, date time.Time, amount string) bool {
+	if record.ClearStatus != util.ClearStatusReconciled {
+		return false
+	}
+	if !record.Date.Equal(date) {
+		return true
+	}
+
+	old, ok := new(big.Rat).SetString(record.Amount)
+	changed, ok2 := new(big.Rat).SetString(amount)
+	return !ok || !ok2 || old.Cmp(changed) != 0
+}
+
+/**
+ * 修改记录的核对状态, 已对账的记录和状态没有变化的记录会被忽略, 只返回修改了的记录
+ * 只修改核对状态不受关账限制
+ */
+func (db *DB) UpdateRecordsClearStatus(
+	ctx context.Context,
+	accountId int64,
+	ids []int64,
+	status util.ClearStatus,
+) ([]Record, error) {
+	var res []Record
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.UpdateRecordsClearStatusParams{
+			ClearStatus: status,
+			AccountID:   accountId,
+			Ids:         ids,
+		}
+
+		res, err = q.UpdateRecordsClearStatus(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 1. 锁定账单, 等待正在写入记录的事务提交
+ * 2. 检查点的余额必须与已核对的记录计算出的余额一致
+ * 3. 将期初日期到检查点日期之间已核对的记录标记为已对账, 返回标记的记录数
+ * 4. 记录检查点的对账时间
+ */
+func (db *DB) ReconcileBalanceCheckpoint(ctx context.Context, id int64) (int64, error) {
+	var res int64
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		checkpoint, err := q.GetBalanceCheckpoint(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.LockAccount(ctx, checkpoint.AccountID)
+		if err != nil {
+			return err
+		}
+
+		var balances []CheckpointBalance
+		balances, err = q.GetCheckpointBalancesByAccountId(ctx, checkpoint.AccountID)
+		if err != nil {
+			return err
+		}
+
+		for _, balance := range balances {
+			if balance.ID != id {
+				continue
+			}
+			difference, ok := new(big.Rat).SetString(balance.ClearedDifference)
+			if !ok || difference.Sign() != 0 {
+				return ErrCheckpointNotBalanced
+			}
+		}
+
+		reconcileArg := sqlc.ReconcileRecordsParams{
+			AccountID: checkpoint.AccountID,
+			EndDate:   checkpoint.Date,
+		}
+		opening, err := q.GetOpeningBalance(ctx, checkpoint.AccountID)
+		if err == nil {
+			reconcileArg.StartDate = nullTime(opening.Date)
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		res, err = q.ReconcileRecords(ctx, reconcileArg)
+		if err != nil {
+			return err
+		}
+
+		return q.SetBalanceCheckpointReconciled(ctx, id)
+	})
+
+	return res, err
+}
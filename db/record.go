@@ -38,7 +38,22 @@ func (db *DB) CreateRecord(
 }
 
 func (db *DB) DeleteRecord(ctx context.Context, id int64) error {
-	return db.exec(ctx, func(q *sqlc.Queries) error {
+	return db.execTx(ctx, func(q *sqlc.Queries) error {
+		err := q.DeleteAttachmentsByRecordId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordCommentsByRecordId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordApprovalsByRecordId(ctx, id)
+		if err != nil {
+			return err
+		}
+
 		return q.DeleteRecord(ctx, id)
 	})
 }
@@ -78,6 +93,34 @@ func (db *DB) GetRecordsByAccountId(
 	return res, err
 }
 
+/**
+ * 按date DESC, id DESC的顺序查询游标之后的账单记录, cursorDate为零值时从头开始
+ */
+func (db *DB) GetRecordsByAccountIdAfterCursor(
+	ctx context.Context,
+	accountId int64,
+	cursorDate time.Time,
+	cursorId int64,
+	limit int64,
+) ([]Record, error) {
+	var res []Record
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetRecordsByAccountIdAfterCursorParams{
+			AccountID:  accountId,
+			CursorDate: nullTime(cursorDate),
+			CursorID:   cursorId,
+			PageLimit:  limit,
+		}
+
+		res, err = q.GetRecordsByAccountIdAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
 func (db *DB) GetRecordsCountByAccountId(ctx context.Context, accountId int64) (int64, error) {
 	var res int64
 
@@ -113,8 +156,10 @@ func (db *DB) UpdateRecord(
 	date time.Time,
 	amount string,
 	lastModifiedUserId int64,
-) error {
-	return db.exec(ctx, func(q *sqlc.Queries) error {
+) (Record, error) {
+	var res Record
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
 		arg := sqlc.UpdateRecordParams{
 			ID:                 id,
 			Name:               name,
@@ -123,6 +168,456 @@ func (db *DB) UpdateRecord(
 			Amount:             amount,
 			LastModifiedUserID: lastModifiedUserId,
 		}
-		return q.UpdateRecord(ctx, arg)
+
+		var err error
+		res, err = q.UpdateRecord(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+type RecordsSummary = sqlc.GetSearchRecordsSummaryRow
+
+/**
+ * 账单记录的检索条件, 除AccountId外零值的字段不参与过滤
+ * Name按子串匹配, Query按star_account_search配置做全文检索
+ * 安装了zhparser扩展时该配置使用中文分词, 否则按空白和标点切分, 见迁移000022
+ */
+type RecordFilter struct {
+	AccountId          int64
+	StartDate          time.Time
+	EndDate            time.Time
+	RecordTypes        []int32
+	MinAmount          string
+	MaxAmount          string
+	CreateUserId       int64
+	LastModifiedUserId int64
+	Name               string
+	Query              string
+}
+
+/**
+ * 1. 按条件和排序方式查询一页账单记录
+ * 2. 按同样的条件统计记录总数和金额总和
+ */
+func (db *DB) SearchRecords(
+	ctx context.Context,
+	filter RecordFilter,
+	sortBy string,
+	sortDesc bool,
+	offset int64,
+	limit int64,
+) ([]Record, RecordsSummary, error) {
+	var records []Record
+	var summary RecordsSummary
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.SearchRecordsParams{
+			AccountID:          filter.AccountId,
+			StartDate:          nullTime(filter.StartDate),
+			EndDate:            nullTime(filter.EndDate),
+			Types:              filter.RecordTypes,
+			MinAmount:          nullString(filter.MinAmount),
+			MaxAmount:          nullString(filter.MaxAmount),
+			CreateUserID:       nullInt64(filter.CreateUserId),
+			LastModifiedUserID: nullInt64(filter.LastModifiedUserId),
+			Name:               nullString(filter.Name),
+			Query:              nullString(filter.Query),
+			SortBy:             sortBy,
+			SortDesc:           sortDesc,
+			PageOffset:         offset,
+			PageLimit:          limit,
+		}
+
+		records, err = q.SearchRecords(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		summary, err = getSearchRecordsSummary(ctx, q, filter)
+		return err
 	})
+
+	return records, summary, err
+}
+
+/**
+ * 检索结果的游标, 对应上一页最后一条记录的排序键
+ * Amount和CreateTime只在按对应字段排序时使用, Date为零值表示第一页
+ */
+type RecordSearchCursor struct {
+	Date       time.Time
+	Amount     string
+	CreateTime time.Time
+	ID         int64
+}
+
+/**
+ * 和SearchRecords相同, 但按游标而不是偏移量分页
+ */
+func (db *DB) SearchRecordsAfterCursor(
+	ctx context.Context,
+	filter RecordFilter,
+	sortBy string,
+	sortDesc bool,
+	cursor RecordSearchCursor,
+	limit int64,
+) ([]Record, RecordsSummary, error) {
+	var records []Record
+	var summary RecordsSummary
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.SearchRecordsAfterCursorParams{
+			AccountID:          filter.AccountId,
+			StartDate:          nullTime(filter.StartDate),
+			EndDate:            nullTime(filter.EndDate),
+			Types:              filter.RecordTypes,
+			MinAmount:          nullString(filter.MinAmount),
+			MaxAmount:          nullString(filter.MaxAmount),
+			CreateUserID:       nullInt64(filter.CreateUserId),
+			LastModifiedUserID: nullInt64(filter.LastModifiedUserId),
+			Name:               nullString(filter.Name),
+			Query:              nullString(filter.Query),
+			CursorDate:         nullTime(cursor.Date),
+			SortBy:             sortBy,
+			SortDesc:           sortDesc,
+			CursorAmount:       nullString(cursor.Amount),
+			CursorID:           cursor.ID,
+			CursorTime:         nullTime(cursor.CreateTime),
+			PageLimit:          limit,
+		}
+
+		records, err = q.SearchRecordsAfterCursor(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		summary, err = getSearchRecordsSummary(ctx, q, filter)
+		return err
+	})
+
+	return records, summary, err
+}
+
+func getSearchRecordsSummary(ctx context.Context, q *sqlc.Queries, filter RecordFilter) (RecordsSummary, error) {
+	arg := sqlc.GetSearchRecordsSummaryParams{
+		AccountID:          filter.AccountId,
+		StartDate:          nullTime(filter.StartDate),
+		EndDate:            nullTime(filter.EndDate),
+		Types:              filter.RecordTypes,
+		MinAmount:          nullString(filter.MinAmount),
+		MaxAmount:          nullString(filter.MaxAmount),
+		CreateUserID:       nullInt64(filter.CreateUserId),
+		LastModifiedUserID: nullInt64(filter.LastModifiedUserId),
+		Name:               nullString(filter.Name),
+		Query:              nullString(filter.Query),
+	}
+
+	return q.GetSearchRecordsSummary(ctx, arg)
+}
+
+/**
+ * 批量创建账单记录时每一条记录的内容
+ */
+type NewRecord struct {
+	Name       string
+	RecordType int32
+	Date       time.Time
+	Amount     string
+	AccountId  int64
+	// 从外部账单导入时来源平台的交易号, 只在ImportRecords中使用
+	ExternalId string
+}
+
+/**
+ * 批量修改账单记录时每一条记录的内容
+ */
+type RecordChange struct {
+	ID         int64
+	Name       string
+	RecordType int32
+	Date       time.Time
+	Amount     string
+}
+
+/**
+ * 以一条多行INSERT语句创建所有记录, 返回的记录与输入顺序一致
+ */
+func (db *DB) CreateRecords(ctx context.Context, records []NewRecord, createUserId int64) ([]Record, error) {
+	var res []Record
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.CreateRecordsParams{CreateUserID: createUserId}
+		for _, record := range records {
+			arg.Names = append(arg.Names, record.Name)
+			arg.Types = append(arg.Types, record.RecordType)
+			arg.Dates = append(arg.Dates, record.Date)
+			arg.Amounts = append(arg.Amounts, record.Amount)
+			arg.AccountIds = append(arg.AccountIds, record.AccountId)
+		}
+
+		err = lockAccountsSyncChanges(ctx, q, arg.AccountIds)
+		if err != nil {
+			return err
+		}
+
+		res, err = q.CreateRecords(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetRecordsByIds(ctx context.Context, ids []int64) ([]Record, error) {
+	var res []Record
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetRecordsByIds(ctx, ids)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpdateRecords(
+	ctx context.Context,
+	changes []RecordChange,
+	lastModifiedUserId int64,
+) ([]Record, error) {
+	var res []Record
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.UpdateRecordsParams{LastModifiedUserID: lastModifiedUserId}
+		for _, change := range changes {
+			arg.Ids = append(arg.Ids, change.ID)
+			arg.Names = append(arg.Names, change.Name)
+			arg.Types = append(arg.Types, change.RecordType)
+			arg.Dates = append(arg.Dates, change.Date)
+			arg.Amounts = append(arg.Amounts, change.Amount)
+		}
+
+		err = lockRecordsAccounts(ctx, q, arg.Ids)
+		if err != nil {
+			return err
+		}
+
+		res, err = q.UpdateRecords(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) DeleteRecords(ctx context.Context, ids []int64) error {
+	return db.execTx(ctx, func(q *sqlc.Queries) error {
+		err := lockRecordsAccounts(ctx, q, ids)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteAttachmentsByRecordIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordCommentsByRecordIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordApprovalsByRecordIds(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteRecordsByIds(ctx, ids)
+	})
+}
+
+/**
+ * 按账单id升序锁定记录所在的账单, 见lockAccountsSyncChanges
+ */
+func lockRecordsAccounts(ctx context.Context, q *sqlc.Queries, ids []int64) error {
+	accountIds, err := q.GetRecordsAccountIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return lockAccountsSyncChanges(ctx, q, accountIds)
+}
+
+type RecordsStatistic = sqlc.GetRecordsStatisticsRow
+
+/**
+ * 按时间段和分组统计收入, 支出和记录数
+ * 金额为正的记录计入支出, 为负的记录计入收入, 两者都以正数返回
+ * interval为day, week, month或year, groupBy为category, creator或空
+ */
+func (db *DB) GetRecordsStatistics(
+	ctx context.Context,
+	accountId int64,
+	interval string,
+	groupBy string,
+	startDate time.Time,
+	endDate time.Time,
+) ([]RecordsStatistic, error) {
+	var res []RecordsStatistic
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetRecordsStatisticsParams{
+			Interval:  interval,
+			GroupBy:   groupBy,
+			AccountID: accountId,
+			StartDate: startDate,
+			EndDate:   endDate,
+		}
+
+		res, err = q.GetRecordsStatistics(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+const iterateRecordsPageSize = 500
+
+/**
+ * 按date, id的顺序分页遍历账单记录, 用于导出等需要读取大量记录的场景
+ * startDate, endDate为零值时不限制日期, fn返回错误时停止遍历
+ */
+func (db *DB) IterateRecordsByAccountId(
+	ctx context.Context,
+	accountId int64,
+	startDate time.Time,
+	endDate time.Time,
+	fn func(Record) error,
+) error {
+	arg := sqlc.GetRecordsForExportParams{
+		AccountID: accountId,
+		StartDate: nullTime(startDate),
+		EndDate:   nullTime(endDate),
+		PageLimit: iterateRecordsPageSize,
+	}
+
+	for {
+		var records []Record
+		err := db.exec(ctx, func(q *sqlc.Queries) error {
+			var err error
+			records, err = q.GetRecordsForExport(ctx, arg)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			err = fn(record)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(records) < iterateRecordsPageSize {
+			return nil
+		}
+
+		last := records[len(records)-1]
+		arg.CursorDate = nullTime(last.Date)
+		arg.CursorID = last.ID
+	}
+}
+
+/**
+ * 在一个事务中逐条创建导入的记录, 任意一条失败时全部回滚
+ */
+func (db *DB) ImportRecords(ctx context.Context, records []NewRecord, createUserId int64) ([]Record, error) {
+	res := []Record{}
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		accountIds := []int64{}
+		for _, record := range records {
+			accountIds = append(accountIds, record.AccountId)
+		}
+
+		err := lockAccountsSyncChanges(ctx, q, accountIds)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			arg := sqlc.CreateRecordParams{
+				Name:         record.Name,
+				Type:         record.RecordType,
+				Date:         record.Date,
+				Amount:       record.Amount,
+				AccountID:    record.AccountId,
+				CreateUserID: createUserId,
+				ExternalID:   record.ExternalId,
+			}
+
+			created, err := q.CreateRecord(ctx, arg)
+			if err != nil {
+				return err
+			}
+			res = append(res, created)
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+/**
+ * 返回账单中已经导入过的交易号
+ */
+func (db *DB) GetRecordsExternalIds(ctx context.Context, accountId int64, externalIds []string) ([]string, error) {
+	var res []string
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetRecordsExternalIdsParams{
+			AccountID:   accountId,
+			ExternalIds: externalIds,
+		}
+		res, err = q.GetRecordsExternalIds(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 日期范围内支出金额最大的记录, endDate包含在范围内
+ */
+func (db *DB) GetTopExpensesByAccountId(
+	ctx context.Context,
+	accountId int64,
+	startDate time.Time,
+	endDate time.Time,
+	limit int64,
+) ([]Record, error) {
+	var res []Record
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetTopExpensesByAccountIdParams{
+			AccountID: accountId,
+			StartDate: startDate,
+			EndDate:   endDate,
+			PageLimit: limit,
+		}
+
+		res, err = q.GetTopExpensesByAccountId(ctx, arg)
+		return err
+	})
+
+	return res, err
 }
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+	"github.com/timelyrain/star-account/util"
+)
+
+type ApprovalRule = sqlc.ApprovalRule
+
+type RecordApproval = sqlc.RecordApproval
+
+var (
+	ErrRecordNotPending = errors.New("record is not pending approval")
+)
+
+/**
+ * 规则只在记录创建和修改时生效, 修改规则不会重新判定已有的记录
+ */
+func (db *DB) CreateApprovalRule(
+	ctx context.Context,
+	accountId int64,
+	minAmount string,
+	types []int32,
+	createUserId int64,
+) (ApprovalRule, error) {
+	var res ApprovalRule
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.CreateApprovalRuleParams{
+			AccountID:    accountId,
+			MinAmount:    minAmount,
+			Types:        types,
+			CreateUserID: createUserId,
+		}
+
+		res, err = q.CreateApprovalRule(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetApprovalRule(ctx context.Context, id int64) (ApprovalRule, error) {
+	var res ApprovalRule
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetApprovalRule(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetApprovalRulesByAccountId(ctx context.Context, accountId int64) ([]ApprovalRule, error) {
+	var res []ApprovalRule
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetApprovalRulesByAccountId(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpdateApprovalRule(ctx context.Context, id int64, minAmount string, types []int32) (ApprovalRule, error) {
+	var res ApprovalRule
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.UpdateApprovalRuleParams{
+			ID:        id,
+			MinAmount: minAmount,
+			Types:     types,
+		}
+
+		res, err = q.UpdateApprovalRule(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) DeleteApprovalRule(ctx context.Context, id int64) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteApprovalRule(ctx, id)
+	})
+}
+
+func (db *DB) GetPendingRecordsByAccountIdAfterCursor(
+	ctx context.Context,
+	accountId int64,
+	cursorId int64,
+	limit int64,
+) ([]Record, error) {
+	var res []Record
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetPendingRecordsByAccountIdAfterCursorParams{
+			AccountID: accountId,
+			CursorID:  nullInt64(cursorId),
+			PageLimit: limit,
+		}
+
+		res, err = q.GetPendingRecordsByAccountIdAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 1. 锁定记录, 只有等待审批的记录可以审批
+ * 2. 修改记录的审批状态
+ * 3. 写入审批历史
+ */
+func (db *DB) ReviewRecord(
+	ctx context.Context,
+	id int64,
+	status util.ApprovalStatus,
+	userId int64,
+	reason string,
+) (Record, error) {
+	var res Record
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		record, err := q.GetRecordForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if record.ApprovalStatus != util.ApprovalStatusPending {
+			return ErrRecordNotPending
+		}
+
+		updateArg := sqlc.UpdateRecordApprovalStatusParams{
+			ID:             id,
+			ApprovalStatus: status,
+		}
+		res, err = q.UpdateRecordApprovalStatus(ctx, updateArg)
+		if err != nil {
+			return err
+		}
+
+		createArg := sqlc.CreateRecordApprovalParams{
+			RecordID:  id,
+			AccountID: record.AccountID,
+			Status:    status,
+			UserID:    userId,
+			Reason:    reason,
+		}
+		_, err = q.CreateRecordApproval(ctx, createArg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetRecordApprovalsByRecordId(ctx context.Context, recordId int64) ([]RecordApproval, error) {
+	var res []RecordApproval
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetRecordApprovalsByRecordId(ctx, recordId)
+		return err
+	})
+
+	return res, err
+}
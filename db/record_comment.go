@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type RecordComment = sqlc.RecordComment
+
+type UnreadCommentsCount = sqlc.GetUnreadCommentsCountsByUserIdRow
+
+var (
+	ErrInvalidCommentParent = errors.New("parent comment not found in the record")
+	ErrInvalidMention       = errors.New("mentioned user is not a member of the account")
+)
+
+/**
+ * 被@的用户必须是账单的成员
+ */
+func checkMentions(ctx context.Context, q *sqlc.Queries, accountId int64, mentionUserIds []int64) error {
+	if len(mentionUserIds) == 0 {
+		return nil
+	}
+
+	rules, err := q.GetAccountAccessRulesByAccountId(ctx, accountId)
+	if err != nil {
+		return err
+	}
+
+	members := make(map[int64]bool, len(rules))
+	for _, rule := range rules {
+		members[rule.UserID] = true
+	}
+
+	for _, id := range mentionUserIds {
+		if !members[id] {
+			return ErrInvalidMention
+		}
+	}
+
+	return nil
+}
+
+/**
+ * 1. parentId不为0时, 回复的评论必须属于同一条记录且未被删除
+ * 2. 检查被@的用户
+ * 3. 写入评论
+ */
+func (db *DB) CreateRecordComment(
+	ctx context.Context,
+	record Record,
+	parentId int64,
+	userId int64,
+	content string,
+	mentionUserIds []int64,
+) (RecordComment, error) {
+	var res RecordComment
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		if parentId != 0 {
+			parent, err := q.GetRecordComment(ctx, parentId)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return ErrInvalidCommentParent
+				}
+				return err
+			}
+
+			if parent.RecordID != record.ID || parent.DeleteTime.Valid {
+				return ErrInvalidCommentParent
+			}
+		}
+
+		err := checkMentions(ctx, q, record.AccountID, mentionUserIds)
+		if err != nil {
+			return err
+		}
+
+		arg := sqlc.CreateRecordCommentParams{
+			RecordID:       record.ID,
+			AccountID:      record.AccountID,
+			ParentID:       nullInt64(parentId),
+			UserID:         userId,
+			Content:        content,
+			MentionUserIds: mentionUserIds,
+		}
+
+		res, err = q.CreateRecordComment(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetRecordComment(ctx context.Context, id int64) (RecordComment, error) {
+	var res RecordComment
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetRecordComment(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 包括已删除的评论, 以便客户端显示其回复
+ */
+func (db *DB) GetRecordCommentsByRecordId(ctx context.Context, recordId int64) ([]RecordComment, error) {
+	var res []RecordComment
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetRecordCommentsByRecordId(ctx, recordId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpdateRecordComment(
+	ctx context.Context,
+	comment RecordComment,
+	content string,
+	mentionUserIds []int64,
+) (RecordComment, error) {
+	var res RecordComment
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		err := checkMentions(ctx, q, comment.AccountID, mentionUserIds)
+		if err != nil {
+			return err
+		}
+
+		arg := sqlc.UpdateRecordCommentParams{
+			ID:             comment.ID,
+			Content:        content,
+			MentionUserIds: mentionUserIds,
+		}
+
+		res, err = q.UpdateRecordComment(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 清空评论内容并标记删除, 保留行以维持回复的结构
+ */
+func (db *DB) DeleteRecordComment(ctx context.Context, id int64) (RecordComment, error) {
+	var res RecordComment
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.DeleteRecordComment(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 用户所在的每个账单中其他成员发表的未读评论数和其中@该用户的评论数
+ */
+func (db *DB) GetUnreadCommentsCounts(ctx context.Context, userId int64) ([]UnreadCommentsCount, error) {
+	var res []UnreadCommentsCount
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetUnreadCommentsCountsByUserId(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 把账单中当前的所有评论标记为已读, 已读位置只会前进
+ */
+func (db *DB) MarkCommentsRead(ctx context.Context, userId int64, accountId int64) error {
+	return db.execTx(ctx, func(q *sqlc.Queries) error {
+		lastId, err := q.GetLastRecordCommentIdByAccountId(ctx, accountId)
+		if err != nil {
+			return err
+		}
+
+		arg := sqlc.UpsertCommentReadParams{
+			UserID:            userId,
+			AccountID:         accountId,
+			LastReadCommentID: lastId,
+		}
+
+		return q.UpsertCommentRead(ctx, arg)
+	})
+}
@@ -7,6 +7,8 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -55,8 +57,112 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 	return i, err
 }
 
+const getAccountSummariesByUserId = `-- name: GetAccountSummariesByUserId :many
+WITH user_accounts AS (
+    SELECT account_id, role FROM account_access_rules WHERE user_id=$1
+), members AS (
+    SELECT account_id, COUNT(*) AS member_count FROM account_access_rules
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+), stats AS (
+    SELECT
+        account_id,
+        COUNT(*) AS record_count,
+        SUM(amount) FILTER (WHERE date>=$2::date AND date<$3::date AND approval_status=2) AS this_month_amount,
+        SUM(amount) FILTER (WHERE date>=$4::date AND date<$2::date AND approval_status=2) AS last_month_amount,
+        MAX(create_time) AS latest_record_time
+    FROM records
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+), changes AS (
+    SELECT account_id, MAX(change_time) AS latest_change_time FROM sync_changes
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+), comments AS (
+    SELECT account_id, MAX(GREATEST(create_time, update_time, delete_time)) AS latest_comment_time FROM record_comments
+    WHERE account_id IN (SELECT account_id FROM user_accounts)
+    GROUP BY account_id
+)
+SELECT
+    accounts.id AS account_id,
+    accounts.name,
+    user_accounts.role,
+    COALESCE(members.member_count, 0)::bigint AS member_count,
+    COALESCE(stats.record_count, 0)::bigint AS record_count,
+    COALESCE(stats.this_month_amount, 0)::numeric AS this_month_amount,
+    COALESCE(stats.last_month_amount, 0)::numeric AS last_month_amount,
+    GREATEST(
+        stats.latest_record_time,
+        changes.latest_change_time,
+        comments.latest_comment_time,
+        accounts.create_time
+    )::timestamptz AS latest_activity_time
+FROM user_accounts
+JOIN accounts ON accounts.id=user_accounts.account_id
+LEFT JOIN members ON members.account_id=user_accounts.account_id
+LEFT JOIN stats ON stats.account_id=user_accounts.account_id
+LEFT JOIN changes ON changes.account_id=user_accounts.account_id
+LEFT JOIN comments ON comments.account_id=user_accounts.account_id
+ORDER BY latest_activity_time DESC, accounts.id DESC
+`
+
+type GetAccountSummariesByUserIdParams struct {
+	UserID         int64     `json:"user_id"`
+	ThisMonthStart time.Time `json:"this_month_start"`
+	NextMonthStart time.Time `json:"next_month_start"`
+	LastMonthStart time.Time `json:"last_month_start"`
+}
+
+type GetAccountSummariesByUserIdRow struct {
+	AccountID          int64     `json:"account_id"`
+	Name               string    `json:"name"`
+	Role               int32     `json:"role"`
+	MemberCount        int64     `json:"member_count"`
+	RecordCount        int64     `json:"record_count"`
+	ThisMonthAmount    string    `json:"this_month_amount"`
+	LastMonthAmount    string    `json:"last_month_amount"`
+	LatestActivityTime time.Time `json:"latest_activity_time"`
+}
+
+func (q *Queries) GetAccountSummariesByUserId(ctx context.Context, arg GetAccountSummariesByUserIdParams) ([]GetAccountSummariesByUserIdRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAccountSummariesByUserId,
+		arg.UserID,
+		arg.ThisMonthStart,
+		arg.NextMonthStart,
+		arg.LastMonthStart,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetAccountSummariesByUserIdRow{}
+	for rows.Next() {
+		var i GetAccountSummariesByUserIdRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.Name,
+			&i.Role,
+			&i.MemberCount,
+			&i.RecordCount,
+			&i.ThisMonthAmount,
+			&i.LastMonthAmount,
+			&i.LatestActivityTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAccountsByIds = `-- name: GetAccountsByIds :many
-SELECT id, name, create_time FROM accounts WHERE id=ANY($1::bigint[])
+SELECT id, name, create_time FROM accounts WHERE id=ANY($1::bigint[]) ORDER BY id DESC
 `
 
 func (q *Queries) GetAccountsByIds(ctx context.Context, ids []int64) ([]Account, error) {
@@ -82,6 +188,60 @@ func (q *Queries) GetAccountsByIds(ctx context.Context, ids []int64) ([]Account,
 	return items, nil
 }
 
+const getAccountsByUserIdAndRoleAfterCursor = `-- name: GetAccountsByUserIdAndRoleAfterCursor :many
+SELECT accounts.id, accounts.name, accounts.create_time FROM accounts
+JOIN account_access_rules ON account_access_rules.account_id=accounts.id
+WHERE account_access_rules.user_id=$1
+    AND account_access_rules.role=$2
+    AND ($3::bigint IS NULL OR accounts.id<$3)
+ORDER BY accounts.id DESC
+LIMIT $4
+`
+
+type GetAccountsByUserIdAndRoleAfterCursorParams struct {
+	UserID    int64         `json:"user_id"`
+	Role      int32         `json:"role"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	PageLimit int64         `json:"page_limit"`
+}
+
+func (q *Queries) GetAccountsByUserIdAndRoleAfterCursor(ctx context.Context, arg GetAccountsByUserIdAndRoleAfterCursorParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, getAccountsByUserIdAndRoleAfterCursor,
+		arg.UserID,
+		arg.Role,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(&i.ID, &i.Name, &i.CreateTime); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockAccount = `-- name: LockAccount :exec
+SELECT id FROM accounts WHERE id=$1 FOR NO KEY UPDATE
+`
+
+func (q *Queries) LockAccount(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, lockAccount, id)
+	return err
+}
+
 const updateAccountName = `-- name: UpdateAccountName :exec
 UPDATE accounts SET name=$2 WHERE id=$1
 `
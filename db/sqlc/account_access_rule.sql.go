@@ -110,6 +110,70 @@ func (q *Queries) GetAccountAccessRuleByUserIdAndAccountId(ctx context.Context,
 	return i, err
 }
 
+const getAccountAccessRulesByAccountId = `-- name: GetAccountAccessRulesByAccountId :many
+SELECT id, user_id, account_id, role FROM account_access_rules WHERE account_id=$1 ORDER BY id
+`
+
+func (q *Queries) GetAccountAccessRulesByAccountId(ctx context.Context, accountID int64) ([]AccountAccessRule, error) {
+	rows, err := q.db.QueryContext(ctx, getAccountAccessRulesByAccountId, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AccountAccessRule{}
+	for rows.Next() {
+		var i AccountAccessRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.AccountID,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAccountAccessRulesByUserId = `-- name: GetAccountAccessRulesByUserId :many
+SELECT id, user_id, account_id, role FROM account_access_rules WHERE user_id=$1 ORDER BY account_id
+`
+
+func (q *Queries) GetAccountAccessRulesByUserId(ctx context.Context, userID int64) ([]AccountAccessRule, error) {
+	rows, err := q.db.QueryContext(ctx, getAccountAccessRulesByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AccountAccessRule{}
+	for rows.Next() {
+		var i AccountAccessRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.AccountID,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAccountIdsByCreateUserIdForDelete = `-- name: GetAccountIdsByCreateUserIdForDelete :many
 SELECT account_id FROM account_access_rules 
 WHERE user_id=$1 AND role=2
@@ -142,6 +206,7 @@ func (q *Queries) GetAccountIdsByCreateUserIdForDelete(ctx context.Context, user
 const getAccountIdsByUserIdAndRole = `-- name: GetAccountIdsByUserIdAndRole :many
 SELECT account_id FROM account_access_rules
 WHERE user_id=$1 AND role=$2
+ORDER BY account_id DESC
 OFFSET $3
 LIMIT $4
 `
@@ -200,6 +265,7 @@ func (q *Queries) GetAccountsCountByUserIdAndRole(ctx context.Context, arg GetAc
 const getUserIdsByAccountIdAndRole = `-- name: GetUserIdsByAccountIdAndRole :many
 SELECT user_id FROM account_access_rules
 WHERE account_id=$1 AND role=$2
+ORDER BY user_id
 OFFSET $3
 LIMIT $4
 `
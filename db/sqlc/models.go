@@ -5,6 +5,8 @@
 package sqlc
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -21,16 +23,181 @@ type AccountAccessRule struct {
 	Role      int32 `json:"role"`
 }
 
+type ApprovalRule struct {
+	ID           int64     `json:"id"`
+	AccountID    int64     `json:"account_id"`
+	MinAmount    string    `json:"min_amount"`
+	Types        []int32   `json:"types"`
+	CreateUserID int64     `json:"create_user_id"`
+	CreateTime   time.Time `json:"create_time"`
+}
+
+type Attachment struct {
+	ID           int64     `json:"id"`
+	RecordID     int64     `json:"record_id"`
+	AccountID    int64     `json:"account_id"`
+	BlobKey      string    `json:"blob_key"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	CreateUserID int64     `json:"create_user_id"`
+	CreateTime   time.Time `json:"create_time"`
+}
+
+type BalanceCheckpoint struct {
+	ID            int64        `json:"id"`
+	AccountID     int64        `json:"account_id"`
+	Date          time.Time    `json:"date"`
+	Balance       string       `json:"balance"`
+	Note          string       `json:"note"`
+	CreateUserID  int64        `json:"create_user_id"`
+	CreateTime    time.Time    `json:"create_time"`
+	ReconcileTime sql.NullTime `json:"reconcile_time"`
+}
+
+type BlobDeletion struct {
+	BlobKey    string    `json:"blob_key"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+type Budget struct {
+	ID         int64         `json:"id"`
+	AccountID  int64         `json:"account_id"`
+	Type       sql.NullInt32 `json:"type"`
+	UserID     sql.NullInt64 `json:"user_id"`
+	Period     int32         `json:"period"`
+	Amount     string        `json:"amount"`
+	Rollover   bool          `json:"rollover"`
+	Thresholds []int32       `json:"thresholds"`
+	CreateTime time.Time     `json:"create_time"`
+}
+
+type BudgetAlert struct {
+	ID          int64     `json:"id"`
+	BudgetID    int64     `json:"budget_id"`
+	AccountID   int64     `json:"account_id"`
+	PeriodStart time.Time `json:"period_start"`
+	Threshold   int32     `json:"threshold"`
+	Spent       string    `json:"spent"`
+	LimitAmount string    `json:"limit_amount"`
+	CreateTime  time.Time `json:"create_time"`
+}
+
+type CommentRead struct {
+	UserID            int64 `json:"user_id"`
+	AccountID         int64 `json:"account_id"`
+	LastReadCommentID int64 `json:"last_read_comment_id"`
+}
+
+type DigestSubscription struct {
+	UserID       int64        `json:"user_id"`
+	Frequency    int32        `json:"frequency"`
+	TimeZone     string       `json:"time_zone"`
+	NextSendTime time.Time    `json:"next_send_time"`
+	LastSentTime sql.NullTime `json:"last_sent_time"`
+	CreateTime   time.Time    `json:"create_time"`
+}
+
+type IdempotencyKey struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	Key          string    `json:"key"`
+	RequestHash  string    `json:"request_hash"`
+	StatusCode   int32     `json:"status_code"`
+	ResponseBody []byte    `json:"response_body"`
+	CreateTime   time.Time `json:"create_time"`
+	ExpireTime   time.Time `json:"expire_time"`
+	ContentType  string    `json:"content_type"`
+}
+
+type Notification struct {
+	ID                   int64           `json:"id"`
+	UserID               int64           `json:"user_id"`
+	Type                 string          `json:"type"`
+	Data                 json.RawMessage `json:"data"`
+	InApp                bool            `json:"in_app"`
+	ReadTime             sql.NullTime    `json:"read_time"`
+	EmailStatus          int32           `json:"email_status"`
+	EmailAttempts        int32           `json:"email_attempts"`
+	EmailNextAttemptTime time.Time       `json:"email_next_attempt_time"`
+	EmailError           string          `json:"email_error"`
+	CreateTime           time.Time       `json:"create_time"`
+}
+
+type NotificationPreference struct {
+	UserID int64  `json:"user_id"`
+	Type   string `json:"type"`
+	InApp  bool   `json:"in_app"`
+	Email  bool   `json:"email"`
+}
+
+type OpeningBalance struct {
+	AccountID    int64     `json:"account_id"`
+	Amount       string    `json:"amount"`
+	Date         time.Time `json:"date"`
+	UpdateUserID int64     `json:"update_user_id"`
+	UpdateTime   time.Time `json:"update_time"`
+}
+
+type PeriodClosing struct {
+	ID                  int64        `json:"id"`
+	AccountID           int64        `json:"account_id"`
+	Action              int32        `json:"action"`
+	ClosedUntil         sql.NullTime `json:"closed_until"`
+	PreviousClosedUntil sql.NullTime `json:"previous_closed_until"`
+	UserID              int64        `json:"user_id"`
+	Reason              string       `json:"reason"`
+	CreateTime          time.Time    `json:"create_time"`
+}
+
 type Record struct {
-	ID                 int64     `json:"id"`
-	Name               string    `json:"name"`
-	Type               int32     `json:"type"`
-	Date               time.Time `json:"date"`
-	Amount             string    `json:"amount"`
-	AccountID          int64     `json:"account_id"`
-	CreateUserID       int64     `json:"create_user_id"`
-	LastModifiedUserID int64     `json:"last_modified_user_id"`
-	CreateTime         time.Time `json:"create_time"`
+	ID                 int64           `json:"id"`
+	Name               string          `json:"name"`
+	Type               int32           `json:"type"`
+	Date               time.Time       `json:"date"`
+	Amount             string          `json:"amount"`
+	AccountID          int64           `json:"account_id"`
+	CreateUserID       int64           `json:"create_user_id"`
+	LastModifiedUserID int64           `json:"last_modified_user_id"`
+	CreateTime         time.Time       `json:"create_time"`
+	ExternalID         string          `json:"external_id"`
+	ClientID           string          `json:"client_id"`
+	FieldTimes         json.RawMessage `json:"field_times"`
+	ApprovalStatus     int32           `json:"approval_status"`
+	ClearStatus        int32           `json:"clear_status"`
+}
+
+type RecordApproval struct {
+	ID         int64     `json:"id"`
+	RecordID   int64     `json:"record_id"`
+	AccountID  int64     `json:"account_id"`
+	Status     int32     `json:"status"`
+	UserID     int64     `json:"user_id"`
+	Reason     string    `json:"reason"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+type RecordComment struct {
+	ID             int64         `json:"id"`
+	RecordID       int64         `json:"record_id"`
+	AccountID      int64         `json:"account_id"`
+	ParentID       sql.NullInt64 `json:"parent_id"`
+	UserID         int64         `json:"user_id"`
+	Content        string        `json:"content"`
+	MentionUserIds []int64       `json:"mention_user_ids"`
+	CreateTime     time.Time     `json:"create_time"`
+	UpdateTime     sql.NullTime  `json:"update_time"`
+	DeleteTime     sql.NullTime  `json:"delete_time"`
+}
+
+type SyncChange struct {
+	AccountID  int64     `json:"account_id"`
+	Entity     string    `json:"entity"`
+	EntityID   int64     `json:"entity_id"`
+	Deleted    bool      `json:"deleted"`
+	ClientID   string    `json:"client_id"`
+	ChangeSeq  int64     `json:"change_seq"`
+	ChangeTime time.Time `json:"change_time"`
 }
 
 type User struct {
@@ -40,3 +207,37 @@ type User struct {
 	HashedPassword string    `json:"hashed_password"`
 	CreateTime     time.Time `json:"create_time"`
 }
+
+type UserErasure struct {
+	UserID     int64     `json:"user_id"`
+	EraseTime  time.Time `json:"erase_time"`
+	CreateTime time.Time `json:"create_time"`
+	Attempts   int32     `json:"attempts"`
+	Error      string    `json:"error"`
+}
+
+type Webhook struct {
+	ID           int64     `json:"id"`
+	AccountID    int64     `json:"account_id"`
+	Url          string    `json:"url"`
+	Secret       string    `json:"secret"`
+	Events       []string  `json:"events"`
+	Active       bool      `json:"active"`
+	CreateUserID int64     `json:"create_user_id"`
+	CreateTime   time.Time `json:"create_time"`
+}
+
+type WebhookDelivery struct {
+	ID              int64        `json:"id"`
+	WebhookID       int64        `json:"webhook_id"`
+	AccountID       int64        `json:"account_id"`
+	Event           string       `json:"event"`
+	Payload         string       `json:"payload"`
+	Status          int32        `json:"status"`
+	Attempts        int32        `json:"attempts"`
+	NextAttemptTime time.Time    `json:"next_attempt_time"`
+	ResponseStatus  int32        `json:"response_status"`
+	Error           string       `json:"error"`
+	DeliveredTime   sql.NullTime `json:"delivered_time"`
+	CreateTime      time.Time    `json:"create_time"`
+}
@@ -7,6 +7,8 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
@@ -20,10 +22,11 @@ INSERT INTO records (
     amount,
     account_id,
     create_user_id,
-    last_modified_user_id
+    last_modified_user_id,
+    external_id
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $6
-) RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time
+    $1, $2, $3, $4, $5, $6, $6, $7
+) RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status
 `
 
 type CreateRecordParams struct {
@@ -33,6 +36,7 @@ type CreateRecordParams struct {
 	Amount       string    `json:"amount"`
 	AccountID    int64     `json:"account_id"`
 	CreateUserID int64     `json:"create_user_id"`
+	ExternalID   string    `json:"external_id"`
 }
 
 func (q *Queries) CreateRecord(ctx context.Context, arg CreateRecordParams) (Record, error) {
@@ -43,6 +47,7 @@ func (q *Queries) CreateRecord(ctx context.Context, arg CreateRecordParams) (Rec
 		arg.Amount,
 		arg.AccountID,
 		arg.CreateUserID,
+		arg.ExternalID,
 	)
 	var i Record
 	err := row.Scan(
@@ -55,6 +60,160 @@ func (q *Queries) CreateRecord(ctx context.Context, arg CreateRecordParams) (Rec
 		&i.CreateUserID,
 		&i.LastModifiedUserID,
 		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
+	)
+	return i, err
+}
+
+const createRecords = `-- name: CreateRecords :many
+WITH input AS (
+    SELECT nextval(pg_get_serial_sequence('records', 'id')) AS id, name, type, date, amount, account_id, ord
+    FROM unnest(
+        $1::varchar[],
+        $2::integer[],
+        $3::date[],
+        $4::numeric[],
+        $5::bigint[]
+    ) WITH ORDINALITY AS u(name, type, date, amount, account_id, ord)
+), inserted AS (
+    INSERT INTO records (
+        id,
+        name,
+        type,
+        date,
+        amount,
+        account_id,
+        create_user_id,
+        last_modified_user_id
+    ) SELECT
+        id,
+        name,
+        type,
+        date,
+        amount,
+        account_id,
+        $6::bigint,
+        $6::bigint
+    FROM input
+    RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status
+)
+SELECT inserted.id, inserted.name, inserted.type, inserted.date, inserted.amount, inserted.account_id, inserted.create_user_id, inserted.last_modified_user_id, inserted.create_time, inserted.external_id, inserted.client_id, inserted.field_times, inserted.approval_status, inserted.clear_status FROM inserted
+JOIN input ON input.id=inserted.id
+ORDER BY input.ord
+`
+
+type CreateRecordsParams struct {
+	Names        []string    `json:"names"`
+	Types        []int32     `json:"types"`
+	Dates        []time.Time `json:"dates"`
+	Amounts      []string    `json:"amounts"`
+	AccountIds   []int64     `json:"account_ids"`
+	CreateUserID int64       `json:"create_user_id"`
+}
+
+func (q *Queries) CreateRecords(ctx context.Context, arg CreateRecordsParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, createRecords,
+		pq.Array(arg.Names),
+		pq.Array(arg.Types),
+		pq.Array(arg.Dates),
+		pq.Array(arg.Amounts),
+		pq.Array(arg.AccountIds),
+		arg.CreateUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createSyncRecord = `-- name: CreateSyncRecord :one
+INSERT INTO records (
+    name,
+    type,
+    date,
+    amount,
+    account_id,
+    create_user_id,
+    last_modified_user_id,
+    client_id,
+    field_times
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $6, $7, $8
+) RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status
+`
+
+type CreateSyncRecordParams struct {
+	Name         string          `json:"name"`
+	Type         int32           `json:"type"`
+	Date         time.Time       `json:"date"`
+	Amount       string          `json:"amount"`
+	AccountID    int64           `json:"account_id"`
+	CreateUserID int64           `json:"create_user_id"`
+	ClientID     string          `json:"client_id"`
+	FieldTimes   json.RawMessage `json:"field_times"`
+}
+
+func (q *Queries) CreateSyncRecord(ctx context.Context, arg CreateSyncRecordParams) (Record, error) {
+	row := q.db.QueryRowContext(ctx, createSyncRecord,
+		arg.Name,
+		arg.Type,
+		arg.Date,
+		arg.Amount,
+		arg.AccountID,
+		arg.CreateUserID,
+		arg.ClientID,
+		arg.FieldTimes,
+	)
+	var i Record
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Date,
+		&i.Amount,
+		&i.AccountID,
+		&i.CreateUserID,
+		&i.LastModifiedUserID,
+		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
 	)
 	return i, err
 }
@@ -86,8 +245,89 @@ func (q *Queries) DeleteRecordsByAccountIds(ctx context.Context, ids []int64) er
 	return err
 }
 
+const deleteRecordsByIds = `-- name: DeleteRecordsByIds :exec
+DELETE FROM records WHERE id=ANY($1::bigint[])
+`
+
+func (q *Queries) DeleteRecordsByIds(ctx context.Context, ids []int64) error {
+	_, err := q.db.ExecContext(ctx, deleteRecordsByIds, pq.Array(ids))
+	return err
+}
+
+const getPendingRecordsByAccountIdAfterCursor = `-- name: GetPendingRecordsByAccountIdAfterCursor :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
+WHERE account_id=$1
+    AND approval_status=1
+    AND ($2::bigint IS NULL OR id>$2)
+ORDER BY id
+LIMIT $3
+`
+
+type GetPendingRecordsByAccountIdAfterCursorParams struct {
+	AccountID int64         `json:"account_id"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	PageLimit int64         `json:"page_limit"`
+}
+
+func (q *Queries) GetPendingRecordsByAccountIdAfterCursor(ctx context.Context, arg GetPendingRecordsByAccountIdAfterCursorParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getPendingRecordsByAccountIdAfterCursor, arg.AccountID, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPendingRecordsCountByAccountIdUntil = `-- name: GetPendingRecordsCountByAccountIdUntil :one
+SELECT COUNT(*) FROM records
+WHERE account_id=$1
+    AND approval_status=1
+    AND date<=$2
+`
+
+type GetPendingRecordsCountByAccountIdUntilParams struct {
+	AccountID int64     `json:"account_id"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+func (q *Queries) GetPendingRecordsCountByAccountIdUntil(ctx context.Context, arg GetPendingRecordsCountByAccountIdUntilParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getPendingRecordsCountByAccountIdUntil, arg.AccountID, arg.EndDate)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const getRecord = `-- name: GetRecord :one
-SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time FROM records WHERE id=$1
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records WHERE id=$1
 `
 
 func (q *Queries) GetRecord(ctx context.Context, id int64) (Record, error) {
@@ -103,12 +343,101 @@ func (q *Queries) GetRecord(ctx context.Context, id int64) (Record, error) {
 		&i.CreateUserID,
 		&i.LastModifiedUserID,
 		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
+	)
+	return i, err
+}
+
+const getRecordByClientIdForUpdate = `-- name: GetRecordByClientIdForUpdate :one
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records WHERE account_id=$1 AND client_id=$2 FOR UPDATE
+`
+
+type GetRecordByClientIdForUpdateParams struct {
+	AccountID int64  `json:"account_id"`
+	ClientID  string `json:"client_id"`
+}
+
+func (q *Queries) GetRecordByClientIdForUpdate(ctx context.Context, arg GetRecordByClientIdForUpdateParams) (Record, error) {
+	row := q.db.QueryRowContext(ctx, getRecordByClientIdForUpdate, arg.AccountID, arg.ClientID)
+	var i Record
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Date,
+		&i.Amount,
+		&i.AccountID,
+		&i.CreateUserID,
+		&i.LastModifiedUserID,
+		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
+	)
+	return i, err
+}
+
+const getRecordForUpdate = `-- name: GetRecordForUpdate :one
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records WHERE id=$1 FOR UPDATE
+`
+
+func (q *Queries) GetRecordForUpdate(ctx context.Context, id int64) (Record, error) {
+	row := q.db.QueryRowContext(ctx, getRecordForUpdate, id)
+	var i Record
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Date,
+		&i.Amount,
+		&i.AccountID,
+		&i.CreateUserID,
+		&i.LastModifiedUserID,
+		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
 	)
 	return i, err
 }
 
+const getRecordsAccountIds = `-- name: GetRecordsAccountIds :many
+SELECT DISTINCT account_id FROM records WHERE id=ANY($1::bigint[])
+`
+
+func (q *Queries) GetRecordsAccountIds(ctx context.Context, ids []int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsAccountIds, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var account_id int64
+		if err := rows.Scan(&account_id); err != nil {
+			return nil, err
+		}
+		items = append(items, account_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRecordsAmountSumByAccountId = `-- name: GetRecordsAmountSumByAccountId :one
-SELECT SUM(amount) FROM records WHERE account_id=$1
+SELECT SUM(amount) FROM records WHERE account_id=$1 AND approval_status=2
 `
 
 func (q *Queries) GetRecordsAmountSumByAccountId(ctx context.Context, accountID int64) (string, error) {
@@ -119,8 +448,9 @@ func (q *Queries) GetRecordsAmountSumByAccountId(ctx context.Context, accountID
 }
 
 const getRecordsByAccountId = `-- name: GetRecordsByAccountId :many
-SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time FROM records
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
 WHERE account_id=$1
+ORDER BY date DESC, id DESC
 OFFSET $2
 LIMIT $3
 `
@@ -150,6 +480,69 @@ func (q *Queries) GetRecordsByAccountId(ctx context.Context, arg GetRecordsByAcc
 			&i.CreateUserID,
 			&i.LastModifiedUserID,
 			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecordsByAccountIdAfterCursor = `-- name: GetRecordsByAccountIdAfterCursor :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
+WHERE account_id=$1
+    AND ($2::date IS NULL OR (date, id)<($2::date, $3::bigint))
+ORDER BY date DESC, id DESC
+LIMIT $4
+`
+
+type GetRecordsByAccountIdAfterCursorParams struct {
+	AccountID  int64        `json:"account_id"`
+	CursorDate sql.NullTime `json:"cursor_date"`
+	CursorID   int64        `json:"cursor_id"`
+	PageLimit  int64        `json:"page_limit"`
+}
+
+func (q *Queries) GetRecordsByAccountIdAfterCursor(ctx context.Context, arg GetRecordsByAccountIdAfterCursorParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsByAccountIdAfterCursor,
+		arg.AccountID,
+		arg.CursorDate,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -165,7 +558,7 @@ func (q *Queries) GetRecordsByAccountId(ctx context.Context, arg GetRecordsByAcc
 }
 
 const getRecordsByAccountIdAndCreateUserId = `-- name: GetRecordsByAccountIdAndCreateUserId :many
-SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time FROM records
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
 WHERE account_id=$1 AND create_user_id=$2
 OFFSET $3
 LIMIT $4
@@ -202,6 +595,11 @@ func (q *Queries) GetRecordsByAccountIdAndCreateUserId(ctx context.Context, arg
 			&i.CreateUserID,
 			&i.LastModifiedUserID,
 			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -217,7 +615,7 @@ func (q *Queries) GetRecordsByAccountIdAndCreateUserId(ctx context.Context, arg
 }
 
 const getRecordsByAccountIdAndLastModifiedUserId = `-- name: GetRecordsByAccountIdAndLastModifiedUserId :many
-SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time FROM records
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
 WHERE account_id=$1 AND last_modified_user_id=$2
 OFFSET $3
 LIMIT $4
@@ -254,6 +652,11 @@ func (q *Queries) GetRecordsByAccountIdAndLastModifiedUserId(ctx context.Context
 			&i.CreateUserID,
 			&i.LastModifiedUserID,
 			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -268,21 +671,799 @@ func (q *Queries) GetRecordsByAccountIdAndLastModifiedUserId(ctx context.Context
 	return items, nil
 }
 
-const getRecordsCountByAccountId = `-- name: GetRecordsCountByAccountId :one
-SELECT COUNT(*) FROM records WHERE account_id=$1
+const getRecordsByCreateUserId = `-- name: GetRecordsByCreateUserId :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records WHERE create_user_id=$1 ORDER BY date, id
 `
 
-func (q *Queries) GetRecordsCountByAccountId(ctx context.Context, accountID int64) (int64, error) {
-	row := q.db.QueryRowContext(ctx, getRecordsCountByAccountId, accountID)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+func (q *Queries) GetRecordsByCreateUserId(ctx context.Context, createUserID int64) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsByCreateUserId, createUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const updateRecord = `-- name: UpdateRecord :exec
-UPDATE records
-SET name=$2, type=$3, date=$4, amount=$5, last_modified_user_id=$6
-WHERE id=$1
+const getRecordsByIds = `-- name: GetRecordsByIds :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records WHERE id=ANY($1::bigint[])
+`
+
+func (q *Queries) GetRecordsByIds(ctx context.Context, ids []int64) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsByIds, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecordsCountByAccountId = `-- name: GetRecordsCountByAccountId :one
+SELECT COUNT(*) FROM records WHERE account_id=$1
+`
+
+func (q *Queries) GetRecordsCountByAccountId(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getRecordsCountByAccountId, accountID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getRecordsExternalIds = `-- name: GetRecordsExternalIds :many
+SELECT external_id FROM records
+WHERE account_id=$1 AND external_id=ANY($2::varchar[])
+`
+
+type GetRecordsExternalIdsParams struct {
+	AccountID   int64    `json:"account_id"`
+	ExternalIds []string `json:"external_ids"`
+}
+
+func (q *Queries) GetRecordsExternalIds(ctx context.Context, arg GetRecordsExternalIdsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsExternalIds, arg.AccountID, pq.Array(arg.ExternalIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var external_id string
+		if err := rows.Scan(&external_id); err != nil {
+			return nil, err
+		}
+		items = append(items, external_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecordsForExport = `-- name: GetRecordsForExport :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
+WHERE account_id=$1
+    AND ($2::date IS NULL OR date>=$2)
+    AND ($3::date IS NULL OR date<=$3)
+    AND ($4::date IS NULL OR (date, id)>($4::date, $5::bigint))
+ORDER BY date, id
+LIMIT $6
+`
+
+type GetRecordsForExportParams struct {
+	AccountID  int64        `json:"account_id"`
+	StartDate  sql.NullTime `json:"start_date"`
+	EndDate    sql.NullTime `json:"end_date"`
+	CursorDate sql.NullTime `json:"cursor_date"`
+	CursorID   int64        `json:"cursor_id"`
+	PageLimit  int64        `json:"page_limit"`
+}
+
+func (q *Queries) GetRecordsForExport(ctx context.Context, arg GetRecordsForExportParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsForExport,
+		arg.AccountID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.CursorDate,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecordsStatistics = `-- name: GetRecordsStatistics :many
+SELECT
+    date_trunc($1::text, date::timestamp)::date AS bucket,
+    (CASE $2::text
+        WHEN 'category' THEN type::bigint
+        WHEN 'creator' THEN create_user_id
+        ELSE 0
+    END)::bigint AS group_key,
+    COUNT(*) AS count,
+    COALESCE(-SUM(amount) FILTER (WHERE amount<0), 0)::numeric AS income,
+    COALESCE(SUM(amount) FILTER (WHERE amount>0), 0)::numeric AS expense
+FROM records
+WHERE account_id=$3
+    AND date>=$4::date
+    AND date<=$5::date
+    AND approval_status=2
+GROUP BY bucket, group_key
+ORDER BY bucket, group_key
+`
+
+type GetRecordsStatisticsParams struct {
+	Interval  string    `json:"interval"`
+	GroupBy   string    `json:"group_by"`
+	AccountID int64     `json:"account_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+type GetRecordsStatisticsRow struct {
+	Bucket   time.Time `json:"bucket"`
+	GroupKey int64     `json:"group_key"`
+	Count    int64     `json:"count"`
+	Income   string    `json:"income"`
+	Expense  string    `json:"expense"`
+}
+
+func (q *Queries) GetRecordsStatistics(ctx context.Context, arg GetRecordsStatisticsParams) ([]GetRecordsStatisticsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecordsStatistics,
+		arg.Interval,
+		arg.GroupBy,
+		arg.AccountID,
+		arg.StartDate,
+		arg.EndDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetRecordsStatisticsRow{}
+	for rows.Next() {
+		var i GetRecordsStatisticsRow
+		if err := rows.Scan(
+			&i.Bucket,
+			&i.GroupKey,
+			&i.Count,
+			&i.Income,
+			&i.Expense,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRunningBalancesByAccountIdAfterCursor = `-- name: GetRunningBalancesByAccountIdAfterCursor :many
+SELECT
+    id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time,
+    external_id, client_id, field_times, approval_status, clear_status, balance, cleared_balance
+FROM (
+    SELECT
+        records.id, records.name, records.type, records.date, records.amount, records.account_id, records.create_user_id, records.last_modified_user_id, records.create_time, records.external_id, records.client_id, records.field_times, records.approval_status, records.clear_status,
+        ($1::numeric - SUM(amount) OVER (ORDER BY date, id))::numeric AS balance,
+        ($1::numeric - COALESCE(SUM(amount) FILTER (WHERE clear_status<>0) OVER (ORDER BY date, id), 0))::numeric AS cleared_balance
+    FROM records
+    WHERE account_id=$2
+        AND approval_status=2
+        AND ($3::date IS NULL OR date>=$3)
+) AS balances
+WHERE $4::date IS NULL OR (date, id)>($4::date, $5::bigint)
+ORDER BY date, id
+LIMIT $6
+`
+
+type GetRunningBalancesByAccountIdAfterCursorParams struct {
+	OpeningAmount string       `json:"opening_amount"`
+	AccountID     int64        `json:"account_id"`
+	StartDate     sql.NullTime `json:"start_date"`
+	CursorDate    sql.NullTime `json:"cursor_date"`
+	CursorID      int64        `json:"cursor_id"`
+	PageLimit     int64        `json:"page_limit"`
+}
+
+type GetRunningBalancesByAccountIdAfterCursorRow struct {
+	ID                 int64           `json:"id"`
+	Name               string          `json:"name"`
+	Type               int32           `json:"type"`
+	Date               time.Time       `json:"date"`
+	Amount             string          `json:"amount"`
+	AccountID          int64           `json:"account_id"`
+	CreateUserID       int64           `json:"create_user_id"`
+	LastModifiedUserID int64           `json:"last_modified_user_id"`
+	CreateTime         time.Time       `json:"create_time"`
+	ExternalID         string          `json:"external_id"`
+	ClientID           string          `json:"client_id"`
+	FieldTimes         json.RawMessage `json:"field_times"`
+	ApprovalStatus     int32           `json:"approval_status"`
+	ClearStatus        int32           `json:"clear_status"`
+	Balance            string          `json:"balance"`
+	ClearedBalance     string          `json:"cleared_balance"`
+}
+
+func (q *Queries) GetRunningBalancesByAccountIdAfterCursor(ctx context.Context, arg GetRunningBalancesByAccountIdAfterCursorParams) ([]GetRunningBalancesByAccountIdAfterCursorRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRunningBalancesByAccountIdAfterCursor,
+		arg.OpeningAmount,
+		arg.AccountID,
+		arg.StartDate,
+		arg.CursorDate,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetRunningBalancesByAccountIdAfterCursorRow{}
+	for rows.Next() {
+		var i GetRunningBalancesByAccountIdAfterCursorRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+			&i.Balance,
+			&i.ClearedBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSearchRecordsSummary = `-- name: GetSearchRecordsSummary :one
+SELECT COUNT(*) AS total, COALESCE(SUM(amount) FILTER (WHERE approval_status=2), 0)::numeric AS amount_sum FROM records
+WHERE account_id=$1
+    AND ($2::date IS NULL OR date>=$2)
+    AND ($3::date IS NULL OR date<=$3)
+    AND (COALESCE(cardinality($4::integer[]), 0)=0 OR type=ANY($4::integer[]))
+    AND ($5::numeric IS NULL OR amount>=$5)
+    AND ($6::numeric IS NULL OR amount<=$6)
+    AND ($7::bigint IS NULL OR create_user_id=$7)
+    AND ($8::bigint IS NULL OR last_modified_user_id=$8)
+    AND ($9::text IS NULL OR strpos(lower(name), lower($9))>0)
+    AND ($10::text IS NULL OR to_tsvector('star_account_search', name) @@ plainto_tsquery('star_account_search', $10))
+`
+
+type GetSearchRecordsSummaryParams struct {
+	AccountID          int64          `json:"account_id"`
+	StartDate          sql.NullTime   `json:"start_date"`
+	EndDate            sql.NullTime   `json:"end_date"`
+	Types              []int32        `json:"types"`
+	MinAmount          sql.NullString `json:"min_amount"`
+	MaxAmount          sql.NullString `json:"max_amount"`
+	CreateUserID       sql.NullInt64  `json:"create_user_id"`
+	LastModifiedUserID sql.NullInt64  `json:"last_modified_user_id"`
+	Name               sql.NullString `json:"name"`
+	Query              sql.NullString `json:"query"`
+}
+
+type GetSearchRecordsSummaryRow struct {
+	Total     int64  `json:"total"`
+	AmountSum string `json:"amount_sum"`
+}
+
+func (q *Queries) GetSearchRecordsSummary(ctx context.Context, arg GetSearchRecordsSummaryParams) (GetSearchRecordsSummaryRow, error) {
+	row := q.db.QueryRowContext(ctx, getSearchRecordsSummary,
+		arg.AccountID,
+		arg.StartDate,
+		arg.EndDate,
+		pq.Array(arg.Types),
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CreateUserID,
+		arg.LastModifiedUserID,
+		arg.Name,
+		arg.Query,
+	)
+	var i GetSearchRecordsSummaryRow
+	err := row.Scan(&i.Total, &i.AmountSum)
+	return i, err
+}
+
+const getTopExpensesByAccountId = `-- name: GetTopExpensesByAccountId :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
+WHERE account_id=$1
+    AND date>=$2::date
+    AND date<=$3::date
+    AND amount>0
+    AND approval_status=2
+ORDER BY amount DESC, id
+LIMIT $4
+`
+
+type GetTopExpensesByAccountIdParams struct {
+	AccountID int64     `json:"account_id"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	PageLimit int64     `json:"page_limit"`
+}
+
+func (q *Queries) GetTopExpensesByAccountId(ctx context.Context, arg GetTopExpensesByAccountIdParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, getTopExpensesByAccountId,
+		arg.AccountID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reconcileRecords = `-- name: ReconcileRecords :execrows
+UPDATE records SET clear_status=2
+WHERE account_id=$1
+    AND clear_status=1
+    AND approval_status=2
+    AND date<=$2::date
+    AND ($3::date IS NULL OR date>=$3)
+`
+
+type ReconcileRecordsParams struct {
+	AccountID int64        `json:"account_id"`
+	EndDate   time.Time    `json:"end_date"`
+	StartDate sql.NullTime `json:"start_date"`
+}
+
+func (q *Queries) ReconcileRecords(ctx context.Context, arg ReconcileRecordsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reconcileRecords, arg.AccountID, arg.EndDate, arg.StartDate)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreRecords = `-- name: RestoreRecords :many
+WITH input AS (
+    SELECT nextval(pg_get_serial_sequence('records', 'id')) AS id,
+        name, type, date, amount, create_user_id, last_modified_user_id, create_time, external_id, ord
+    FROM unnest(
+        $1::varchar[],
+        $2::integer[],
+        $3::date[],
+        $4::numeric[],
+        $5::bigint[],
+        $6::bigint[],
+        $7::timestamptz[],
+        $8::varchar[]
+    ) WITH ORDINALITY AS u(name, type, date, amount, create_user_id, last_modified_user_id, create_time, external_id, ord)
+), inserted AS (
+    INSERT INTO records (
+        id,
+        name,
+        type,
+        date,
+        amount,
+        account_id,
+        create_user_id,
+        last_modified_user_id,
+        create_time,
+        external_id
+    ) SELECT
+        id,
+        name,
+        type,
+        date,
+        amount,
+        $9::bigint,
+        create_user_id,
+        last_modified_user_id,
+        create_time,
+        external_id
+    FROM input
+    RETURNING id
+)
+SELECT input.id FROM inserted
+JOIN input ON input.id=inserted.id
+ORDER BY input.ord
+`
+
+type RestoreRecordsParams struct {
+	Names               []string    `json:"names"`
+	Types               []int32     `json:"types"`
+	Dates               []time.Time `json:"dates"`
+	Amounts             []string    `json:"amounts"`
+	CreateUserIds       []int64     `json:"create_user_ids"`
+	LastModifiedUserIds []int64     `json:"last_modified_user_ids"`
+	CreateTimes         []time.Time `json:"create_times"`
+	ExternalIds         []string    `json:"external_ids"`
+	AccountID           int64       `json:"account_id"`
+}
+
+func (q *Queries) RestoreRecords(ctx context.Context, arg RestoreRecordsParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, restoreRecords,
+		pq.Array(arg.Names),
+		pq.Array(arg.Types),
+		pq.Array(arg.Dates),
+		pq.Array(arg.Amounts),
+		pq.Array(arg.CreateUserIds),
+		pq.Array(arg.LastModifiedUserIds),
+		pq.Array(arg.CreateTimes),
+		pq.Array(arg.ExternalIds),
+		arg.AccountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchRecords = `-- name: SearchRecords :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
+WHERE account_id=$1
+    AND ($2::date IS NULL OR date>=$2)
+    AND ($3::date IS NULL OR date<=$3)
+    AND (COALESCE(cardinality($4::integer[]), 0)=0 OR type=ANY($4::integer[]))
+    AND ($5::numeric IS NULL OR amount>=$5)
+    AND ($6::numeric IS NULL OR amount<=$6)
+    AND ($7::bigint IS NULL OR create_user_id=$7)
+    AND ($8::bigint IS NULL OR last_modified_user_id=$8)
+    AND ($9::text IS NULL OR strpos(lower(name), lower($9))>0)
+    AND ($10::text IS NULL OR to_tsvector('star_account_search', name) @@ plainto_tsquery('star_account_search', $10))
+ORDER BY
+    CASE WHEN $11::text='amount' AND NOT $12::boolean THEN amount END ASC,
+    CASE WHEN $11::text='amount' AND $12::boolean THEN amount END DESC,
+    CASE WHEN $11::text='create_time' AND NOT $12::boolean THEN create_time END ASC,
+    CASE WHEN $11::text='create_time' AND $12::boolean THEN create_time END DESC,
+    CASE WHEN NOT $12::boolean THEN date END ASC,
+    CASE WHEN $12::boolean THEN date END DESC,
+    CASE WHEN NOT $12::boolean THEN id END ASC,
+    id DESC
+OFFSET $13
+LIMIT $14
+`
+
+type SearchRecordsParams struct {
+	AccountID          int64          `json:"account_id"`
+	StartDate          sql.NullTime   `json:"start_date"`
+	EndDate            sql.NullTime   `json:"end_date"`
+	Types              []int32        `json:"types"`
+	MinAmount          sql.NullString `json:"min_amount"`
+	MaxAmount          sql.NullString `json:"max_amount"`
+	CreateUserID       sql.NullInt64  `json:"create_user_id"`
+	LastModifiedUserID sql.NullInt64  `json:"last_modified_user_id"`
+	Name               sql.NullString `json:"name"`
+	Query              sql.NullString `json:"query"`
+	SortBy             string         `json:"sort_by"`
+	SortDesc           bool           `json:"sort_desc"`
+	PageOffset         int64          `json:"page_offset"`
+	PageLimit          int64          `json:"page_limit"`
+}
+
+func (q *Queries) SearchRecords(ctx context.Context, arg SearchRecordsParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, searchRecords,
+		arg.AccountID,
+		arg.StartDate,
+		arg.EndDate,
+		pq.Array(arg.Types),
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CreateUserID,
+		arg.LastModifiedUserID,
+		arg.Name,
+		arg.Query,
+		arg.SortBy,
+		arg.SortDesc,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchRecordsAfterCursor = `-- name: SearchRecordsAfterCursor :many
+SELECT id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status FROM records
+WHERE account_id=$1
+    AND ($2::date IS NULL OR date>=$2)
+    AND ($3::date IS NULL OR date<=$3)
+    AND (COALESCE(cardinality($4::integer[]), 0)=0 OR type=ANY($4::integer[]))
+    AND ($5::numeric IS NULL OR amount>=$5)
+    AND ($6::numeric IS NULL OR amount<=$6)
+    AND ($7::bigint IS NULL OR create_user_id=$7)
+    AND ($8::bigint IS NULL OR last_modified_user_id=$8)
+    AND ($9::text IS NULL OR strpos(lower(name), lower($9))>0)
+    AND ($10::text IS NULL OR to_tsvector('star_account_search', name) @@ plainto_tsquery('star_account_search', $10))
+    AND ($11::date IS NULL OR CASE
+        WHEN $12::text='amount' AND $13::boolean
+            THEN (amount, date, id)<($14::numeric, $11::date, $15::bigint)
+        WHEN $12::text='amount'
+            THEN (amount, date, id)>($14::numeric, $11::date, $15::bigint)
+        WHEN $12::text='create_time' AND $13::boolean
+            THEN (create_time, date, id)<($16::timestamptz, $11::date, $15::bigint)
+        WHEN $12::text='create_time'
+            THEN (create_time, date, id)>($16::timestamptz, $11::date, $15::bigint)
+        WHEN $13::boolean
+            THEN (date, id)<($11::date, $15::bigint)
+        ELSE (date, id)>($11::date, $15::bigint)
+    END)
+ORDER BY
+    CASE WHEN $12::text='amount' AND NOT $13::boolean THEN amount END ASC,
+    CASE WHEN $12::text='amount' AND $13::boolean THEN amount END DESC,
+    CASE WHEN $12::text='create_time' AND NOT $13::boolean THEN create_time END ASC,
+    CASE WHEN $12::text='create_time' AND $13::boolean THEN create_time END DESC,
+    CASE WHEN NOT $13::boolean THEN date END ASC,
+    CASE WHEN $13::boolean THEN date END DESC,
+    CASE WHEN NOT $13::boolean THEN id END ASC,
+    id DESC
+LIMIT $17
+`
+
+type SearchRecordsAfterCursorParams struct {
+	AccountID          int64          `json:"account_id"`
+	StartDate          sql.NullTime   `json:"start_date"`
+	EndDate            sql.NullTime   `json:"end_date"`
+	Types              []int32        `json:"types"`
+	MinAmount          sql.NullString `json:"min_amount"`
+	MaxAmount          sql.NullString `json:"max_amount"`
+	CreateUserID       sql.NullInt64  `json:"create_user_id"`
+	LastModifiedUserID sql.NullInt64  `json:"last_modified_user_id"`
+	Name               sql.NullString `json:"name"`
+	Query              sql.NullString `json:"query"`
+	CursorDate         sql.NullTime   `json:"cursor_date"`
+	SortBy             string         `json:"sort_by"`
+	SortDesc           bool           `json:"sort_desc"`
+	CursorAmount       sql.NullString `json:"cursor_amount"`
+	CursorID           int64          `json:"cursor_id"`
+	CursorTime         sql.NullTime   `json:"cursor_time"`
+	PageLimit          int64          `json:"page_limit"`
+}
+
+func (q *Queries) SearchRecordsAfterCursor(ctx context.Context, arg SearchRecordsAfterCursorParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, searchRecordsAfterCursor,
+		arg.AccountID,
+		arg.StartDate,
+		arg.EndDate,
+		pq.Array(arg.Types),
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CreateUserID,
+		arg.LastModifiedUserID,
+		arg.Name,
+		arg.Query,
+		arg.CursorDate,
+		arg.SortBy,
+		arg.SortDesc,
+		arg.CursorAmount,
+		arg.CursorID,
+		arg.CursorTime,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateRecord = `-- name: UpdateRecord :one
+UPDATE records
+SET name=$2, type=$3, date=$4, amount=$5, last_modified_user_id=$6
+WHERE id=$1 RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status
 `
 
 type UpdateRecordParams struct {
@@ -294,8 +1475,8 @@ type UpdateRecordParams struct {
 	LastModifiedUserID int64     `json:"last_modified_user_id"`
 }
 
-func (q *Queries) UpdateRecord(ctx context.Context, arg UpdateRecordParams) error {
-	_, err := q.db.ExecContext(ctx, updateRecord,
+func (q *Queries) UpdateRecord(ctx context.Context, arg UpdateRecordParams) (Record, error) {
+	row := q.db.QueryRowContext(ctx, updateRecord,
 		arg.ID,
 		arg.Name,
 		arg.Type,
@@ -303,5 +1484,248 @@ func (q *Queries) UpdateRecord(ctx context.Context, arg UpdateRecordParams) erro
 		arg.Amount,
 		arg.LastModifiedUserID,
 	)
+	var i Record
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Date,
+		&i.Amount,
+		&i.AccountID,
+		&i.CreateUserID,
+		&i.LastModifiedUserID,
+		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
+	)
+	return i, err
+}
+
+const updateRecordApprovalStatus = `-- name: UpdateRecordApprovalStatus :one
+UPDATE records SET approval_status=$2
+WHERE id=$1 RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status
+`
+
+type UpdateRecordApprovalStatusParams struct {
+	ID             int64 `json:"id"`
+	ApprovalStatus int32 `json:"approval_status"`
+}
+
+func (q *Queries) UpdateRecordApprovalStatus(ctx context.Context, arg UpdateRecordApprovalStatusParams) (Record, error) {
+	row := q.db.QueryRowContext(ctx, updateRecordApprovalStatus, arg.ID, arg.ApprovalStatus)
+	var i Record
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Date,
+		&i.Amount,
+		&i.AccountID,
+		&i.CreateUserID,
+		&i.LastModifiedUserID,
+		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
+	)
+	return i, err
+}
+
+const updateRecords = `-- name: UpdateRecords :many
+UPDATE records
+SET name=u.name, type=u.type, date=u.date, amount=u.amount, last_modified_user_id=$1
+FROM (
+    SELECT
+        unnest($2::bigint[]) AS id,
+        unnest($3::varchar[]) AS name,
+        unnest($4::integer[]) AS type,
+        unnest($5::date[]) AS date,
+        unnest($6::numeric[]) AS amount
+) AS u
+WHERE records.id=u.id
+RETURNING records.id, records.name, records.type, records.date, records.amount, records.account_id, records.create_user_id, records.last_modified_user_id, records.create_time, records.external_id, records.client_id, records.field_times, records.approval_status, records.clear_status
+`
+
+type UpdateRecordsParams struct {
+	LastModifiedUserID int64       `json:"last_modified_user_id"`
+	Ids                []int64     `json:"ids"`
+	Names              []string    `json:"names"`
+	Types              []int32     `json:"types"`
+	Dates              []time.Time `json:"dates"`
+	Amounts            []string    `json:"amounts"`
+}
+
+func (q *Queries) UpdateRecords(ctx context.Context, arg UpdateRecordsParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, updateRecords,
+		arg.LastModifiedUserID,
+		pq.Array(arg.Ids),
+		pq.Array(arg.Names),
+		pq.Array(arg.Types),
+		pq.Array(arg.Dates),
+		pq.Array(arg.Amounts),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateRecordsApprovalStatus = `-- name: UpdateRecordsApprovalStatus :exec
+UPDATE records SET approval_status=u.approval_status
+FROM (
+    SELECT
+        unnest($1::bigint[]) AS id,
+        unnest($2::integer[]) AS approval_status
+) AS u
+WHERE records.id=u.id
+`
+
+type UpdateRecordsApprovalStatusParams struct {
+	Ids              []int64 `json:"ids"`
+	ApprovalStatuses []int32 `json:"approval_statuses"`
+}
+
+func (q *Queries) UpdateRecordsApprovalStatus(ctx context.Context, arg UpdateRecordsApprovalStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateRecordsApprovalStatus, pq.Array(arg.Ids), pq.Array(arg.ApprovalStatuses))
 	return err
 }
+
+const updateRecordsClearStatus = `-- name: UpdateRecordsClearStatus :many
+UPDATE records SET clear_status=$1
+WHERE account_id=$2
+    AND id=ANY($3::bigint[])
+    AND clear_status<>2
+    AND clear_status<>$1
+RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status
+`
+
+type UpdateRecordsClearStatusParams struct {
+	ClearStatus int32   `json:"clear_status"`
+	AccountID   int64   `json:"account_id"`
+	Ids         []int64 `json:"ids"`
+}
+
+func (q *Queries) UpdateRecordsClearStatus(ctx context.Context, arg UpdateRecordsClearStatusParams) ([]Record, error) {
+	rows, err := q.db.QueryContext(ctx, updateRecordsClearStatus, arg.ClearStatus, arg.AccountID, pq.Array(arg.Ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Record{}
+	for rows.Next() {
+		var i Record
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Date,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreateUserID,
+			&i.LastModifiedUserID,
+			&i.CreateTime,
+			&i.ExternalID,
+			&i.ClientID,
+			&i.FieldTimes,
+			&i.ApprovalStatus,
+			&i.ClearStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateSyncRecord = `-- name: UpdateSyncRecord :one
+UPDATE records SET
+    name=$2,
+    type=$3,
+    date=$4,
+    amount=$5,
+    last_modified_user_id=$6,
+    field_times=$7
+WHERE id=$1
+RETURNING id, name, type, date, amount, account_id, create_user_id, last_modified_user_id, create_time, external_id, client_id, field_times, approval_status, clear_status
+`
+
+type UpdateSyncRecordParams struct {
+	ID                 int64           `json:"id"`
+	Name               string          `json:"name"`
+	Type               int32           `json:"type"`
+	Date               time.Time       `json:"date"`
+	Amount             string          `json:"amount"`
+	LastModifiedUserID int64           `json:"last_modified_user_id"`
+	FieldTimes         json.RawMessage `json:"field_times"`
+}
+
+func (q *Queries) UpdateSyncRecord(ctx context.Context, arg UpdateSyncRecordParams) (Record, error) {
+	row := q.db.QueryRowContext(ctx, updateSyncRecord,
+		arg.ID,
+		arg.Name,
+		arg.Type,
+		arg.Date,
+		arg.Amount,
+		arg.LastModifiedUserID,
+		arg.FieldTimes,
+	)
+	var i Record
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Date,
+		&i.Amount,
+		&i.AccountID,
+		&i.CreateUserID,
+		&i.LastModifiedUserID,
+		&i.CreateTime,
+		&i.ExternalID,
+		&i.ClientID,
+		&i.FieldTimes,
+		&i.ApprovalStatus,
+		&i.ClearStatus,
+	)
+	return i, err
+}
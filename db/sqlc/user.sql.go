@@ -7,10 +7,26 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/lib/pq"
 )
 
+const anonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users SET name=$2, email=$3, hashed_password='' WHERE id=$1
+`
+
+type AnonymizeUserParams struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (q *Queries) AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUser, arg.ID, arg.Name, arg.Email)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
     name,
@@ -40,15 +56,6 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 	return i, err
 }
 
-const deleteUser = `-- name: DeleteUser :exec
-DELETE FROM users WHERE id=$1
-`
-
-func (q *Queries) DeleteUser(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, id)
-	return err
-}
-
 const getUser = `-- name: GetUser :one
 SELECT id, name, email, hashed_password, create_time FROM users WHERE id=$1 LIMIT 1
 `
@@ -83,8 +90,59 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 	return i, err
 }
 
+const getUsersByAccountIdAndRoleAfterCursor = `-- name: GetUsersByAccountIdAndRoleAfterCursor :many
+SELECT users.id, users.name, users.email, users.hashed_password, users.create_time FROM users
+JOIN account_access_rules ON account_access_rules.user_id=users.id
+WHERE account_access_rules.account_id=$1
+    AND account_access_rules.role=$2
+    AND ($3::bigint IS NULL OR users.id>$3)
+ORDER BY users.id
+LIMIT $4
+`
+
+type GetUsersByAccountIdAndRoleAfterCursorParams struct {
+	AccountID int64         `json:"account_id"`
+	Role      int32         `json:"role"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	PageLimit int64         `json:"page_limit"`
+}
+
+func (q *Queries) GetUsersByAccountIdAndRoleAfterCursor(ctx context.Context, arg GetUsersByAccountIdAndRoleAfterCursorParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByAccountIdAndRoleAfterCursor,
+		arg.AccountID,
+		arg.Role,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.HashedPassword,
+			&i.CreateTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUsersByIds = `-- name: GetUsersByIds :many
-SELECT id, name, email, hashed_password, create_time FROM users WHERE id=ANY($1::bigint[])
+SELECT id, name, email, hashed_password, create_time FROM users WHERE id=ANY($1::bigint[]) ORDER BY id
 `
 
 func (q *Queries) GetUsersByIds(ctx context.Context, ids []int64) ([]User, error) {
@@ -119,6 +177,7 @@ func (q *Queries) GetUsersByIds(ctx context.Context, ids []int64) ([]User, error
 const getUsersByName = `-- name: GetUsersByName :many
 SELECT id, name, email, hashed_password, create_time FROM users
 WHERE name=$1
+ORDER BY id
 OFFSET $2
 LIMIT $3
 `
@@ -158,6 +217,49 @@ func (q *Queries) GetUsersByName(ctx context.Context, arg GetUsersByNameParams)
 	return items, nil
 }
 
+const getUsersByNameAfterCursor = `-- name: GetUsersByNameAfterCursor :many
+SELECT id, name, email, hashed_password, create_time FROM users
+WHERE name=$1
+    AND ($2::bigint IS NULL OR id>$2)
+ORDER BY id
+LIMIT $3
+`
+
+type GetUsersByNameAfterCursorParams struct {
+	Name      string        `json:"name"`
+	CursorID  sql.NullInt64 `json:"cursor_id"`
+	PageLimit int64         `json:"page_limit"`
+}
+
+func (q *Queries) GetUsersByNameAfterCursor(ctx context.Context, arg GetUsersByNameAfterCursorParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByNameAfterCursor, arg.Name, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.HashedPassword,
+			&i.CreateTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUserEmail = `-- name: UpdateUserEmail :exec
 UPDATE users SET email=$2 WHERE id=$1
 `
@@ -0,0 +1,556 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type SyncChange = sqlc.SyncChange
+
+/**
+ * sync_changes中的实体类型
+ */
+const (
+	SyncEntityRecord  = "record"
+	SyncEntityMember  = "member"
+	SyncEntityAccount = "account"
+)
+
+/**
+ * 同步推送的操作
+ */
+const (
+	SyncOpCreate = "create"
+	SyncOpUpdate = "update"
+	SyncOpDelete = "delete"
+)
+
+/**
+ * 同步推送的结果
+ * applied: 所有字段都已写入
+ * conflict: 部分或全部字段被服务端更新的修改覆盖, Conflicts为未写入的字段
+ */
+const (
+	SyncStatusApplied  = "applied"
+	SyncStatusConflict = "conflict"
+)
+
+/**
+ * 可以按字段合并的记录字段名, 与field_times中的键一致
+ */
+const (
+	syncFieldName   = "name"
+	syncFieldType   = "type"
+	syncFieldDate   = "date"
+	syncFieldAmount = "amount"
+)
+
+var (
+	ErrSyncRecordNotFound = errors.New("record not found")
+)
+
+/**
+ * 账单中change_seq大于since的变更, 以及变更涉及的记录, 成员和账单的当前状态
+ * 变更之后又被修改的实体会在下一次同步中再次出现, 因此当前状态可能比变更更新
+ */
+type AccountChanges struct {
+	Changes []SyncChange
+	Records []Record
+	Members []AccountAccessRule
+	Account *Account
+}
+
+/**
+ * since为0时表示首次同步, 不返回墓碑
+ */
+func (db *DB) GetAccountChangesSince(
+	ctx context.Context,
+	accountId int64,
+	since int64,
+	limit int64,
+) (AccountChanges, error) {
+	var res AccountChanges
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetSyncChangesSinceParams{
+			AccountID: accountId,
+			Since:     since,
+			PageLimit: limit,
+		}
+
+		res.Changes, err = q.GetSyncChangesSince(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		recordIds := []int64{}
+		memberIds := map[int64]bool{}
+		accountChanged := false
+		for _, change := range res.Changes {
+			if change.Deleted {
+				continue
+			}
+
+			switch change.Entity {
+			case SyncEntityRecord:
+				recordIds = append(recordIds, change.EntityID)
+			case SyncEntityMember:
+				memberIds[change.EntityID] = true
+			case SyncEntityAccount:
+				accountChanged = true
+			}
+		}
+
+		if len(recordIds) > 0 {
+			res.Records, err = q.GetRecordsByIds(ctx, recordIds)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(memberIds) > 0 {
+			var rules []AccountAccessRule
+			rules, err = q.GetAccountAccessRulesByAccountId(ctx, accountId)
+			if err != nil {
+				return err
+			}
+
+			for _, rule := range rules {
+				if memberIds[rule.UserID] {
+					res.Members = append(res.Members, rule)
+				}
+			}
+		}
+
+		if accountChanged {
+			var account Account
+			account, err = q.GetAccount(ctx, accountId)
+			if err != nil {
+				return err
+			}
+			res.Account = &account
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+/**
+ * 客户端离线时对记录的修改, 为nil的字段表示未修改
+ * 记录由Id或AccountId+ClientId确定, ClientTime是客户端修改的时间
+ */
+type SyncMutation struct {
+	Op         string
+	AccountId  int64
+	Id         int64
+	ClientId   string
+	ClientTime time.Time
+	Name       *string
+	RecordType *int32
+	Date       *time.Time
+	Amount     *string
+}
+
+type SyncResult struct {
+	Status    string
+	Record    *Record
+	Conflicts []string
+	// 记录已被删除, 修改无法写入
+	Deleted bool
+	// 本次推送是否写入了数据库, 重试和完全冲突时为false
+	Changed bool
+	// 记录不存在, 日期已关账等单条推送的错误, 不影响其他推送
+	Err error
+}
+
+/**
+ * 在一个事务中按顺序写入客户端的修改
+ * 每个字段保留修改时间较晚的值, 删除只有晚于所有字段的修改时才生效
+ */
+func (db *DB) ApplySyncMutations(
+	ctx context.Context,
+	mutations []SyncMutation,
+	userId int64,
+) ([]SyncResult, error) {
+	res := make([]SyncResult, len(mutations))
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		accountIds := make([]int64, len(mutations))
+		for i, mutation := range mutations {
+			accountIds[i] = mutation.AccountId
+		}
+
+		err := lockAccountsSyncChanges(ctx, q, accountIds)
+		if err != nil {
+			return err
+		}
+
+		for i, mutation := range mutations {
+			switch mutation.Op {
+			case SyncOpCreate:
+				res[i], err = applySyncCreate(ctx, q, mutation, userId)
+			case SyncOpUpdate:
+				res[i], err = applySyncUpdate(ctx, q, mutation, userId)
+			case SyncOpDelete:
+				res[i], err = applySyncDelete(ctx, q, mutation)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+/**
+ * 删除change_time早于before的墓碑, 返回删除的数量
+ */
+func (db *DB) DeleteSyncTombstonesBefore(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	var res int64
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.DeleteSyncTombstonesBeforeParams{
+			Before:    before,
+			PageLimit: limit,
+		}
+
+		res, err = q.DeleteSyncTombstonesBefore(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 记录变更的触发器会获取账单的咨询锁, 一个事务修改多个账单的记录时按账单id升序预先加锁
+ * 否则两个事务以相反的顺序修改两个账单时会互相等待
+ */
+func lockAccountsSyncChanges(ctx context.Context, q *sqlc.Queries, accountIds []int64) error {
+	ids := make([]int64, len(accountIds))
+	copy(ids, accountIds)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+
+		err := q.LockAccountSyncChanges(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+ * 同一ClientId的记录已存在时视为重试, 返回已有的记录
+ */
+func applySyncCreate(ctx context.Context, q *sqlc.Queries, mutation SyncMutation, userId int64) (SyncResult, error) {
+	getArg := sqlc.GetRecordByClientIdForUpdateParams{
+		AccountID: mutation.AccountId,
+		ClientID:  mutation.ClientId,
+	}
+
+	record, err := q.GetRecordByClientIdForUpdate(ctx, getArg)
+	if err == nil {
+		return SyncResult{Status: SyncStatusApplied, Record: &record}, nil
+	}
+	if err != sql.ErrNoRows {
+		return SyncResult{}, err
+	}
+
+	var deleted bool
+	deleted, err = isRecordDeleted(ctx, q, mutation)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if deleted {
+		return SyncResult{Status: SyncStatusConflict, Deleted: true}, nil
+	}
+
+	clientMs := mutation.ClientTime.UnixMilli()
+	var fieldTimes json.RawMessage
+	fieldTimes, err = json.Marshal(map[string]int64{
+		syncFieldName:   clientMs,
+		syncFieldType:   clientMs,
+		syncFieldDate:   clientMs,
+		syncFieldAmount: clientMs,
+	})
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	err = checkPeriodOpen(ctx, q, mutation.AccountId, *mutation.Date)
+	if err == ErrPeriodClosed {
+		return SyncResult{Err: err}, nil
+	}
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	arg := sqlc.CreateSyncRecordParams{
+		Name:         *mutation.Name,
+		Type:         *mutation.RecordType,
+		Date:         *mutation.Date,
+		Amount:       *mutation.Amount,
+		AccountID:    mutation.AccountId,
+		CreateUserID: userId,
+		ClientID:     mutation.ClientId,
+		FieldTimes:   fieldTimes,
+	}
+
+	record, err = q.CreateSyncRecord(ctx, arg)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	return SyncResult{Status: SyncStatusApplied, Record: &record, Changed: true}, nil
+}
+
+func applySyncUpdate(ctx context.Context, q *sqlc.Queries, mutation SyncMutation, userId int64) (SyncResult, error) {
+	record, found, err := getRecordForSync(ctx, q, mutation)
+	if err != nil || !found {
+		return syncMissingResult(ctx, q, mutation, err)
+	}
+
+	var fieldTimes map[string]int64
+	fieldTimes, err = recordFieldTimes(record)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	clientMs := mutation.ClientTime.UnixMilli()
+	conflicts := []string{}
+	applied := false
+	apply := func(field string, set func()) {
+		if clientMs >= fieldTimes[field] {
+			set()
+			fieldTimes[field] = clientMs
+			applied = true
+		} else {
+			conflicts = append(conflicts, field)
+		}
+	}
+
+	arg := sqlc.UpdateSyncRecordParams{
+		ID:                 record.ID,
+		Name:               record.Name,
+		Type:               record.Type,
+		Date:               record.Date,
+		Amount:             record.Amount,
+		LastModifiedUserID: userId,
+	}
+	if mutation.Name != nil {
+		apply(syncFieldName, func() { arg.Name = *mutation.Name })
+	}
+	if mutation.RecordType != nil {
+		apply(syncFieldType, func() { arg.Type = *mutation.RecordType })
+	}
+	if mutation.Date != nil {
+		apply(syncFieldDate, func() { arg.Date = *mutation.Date })
+	}
+	if mutation.Amount != nil {
+		apply(syncFieldAmount, func() { arg.Amount = *mutation.Amount })
+	}
+
+	if applied {
+		if IsReconciledRecordChanged(record, arg.Date, arg.Amount) {
+			return SyncResult{Record: &record, Err: ErrRecordReconciled}, nil
+		}
+
+		err = checkPeriodOpen(ctx, q, record.AccountID, record.Date, arg.Date)
+		if err == ErrPeriodClosed {
+			return SyncResult{Record: &record, Err: err}, nil
+		}
+		if err != nil {
+			return SyncResult{}, err
+		}
+
+		arg.FieldTimes, err = json.Marshal(fieldTimes)
+		if err != nil {
+			return SyncResult{}, err
+		}
+
+		record, err = q.UpdateSyncRecord(ctx, arg)
+		if err != nil {
+			return SyncResult{}, err
+		}
+	}
+
+	status := SyncStatusApplied
+	if len(conflicts) > 0 {
+		status = SyncStatusConflict
+	}
+
+	return SyncResult{Status: status, Record: &record, Conflicts: conflicts, Changed: applied}, nil
+}
+
+/**
+ * 已经删除的记录视为删除成功, 以便客户端重试
+ */
+func applySyncDelete(ctx context.Context, q *sqlc.Queries, mutation SyncMutation) (SyncResult, error) {
+	record, found, err := getRecordForSync(ctx, q, mutation)
+	if err != nil || !found {
+		res, err := syncMissingResult(ctx, q, mutation, err)
+		if res.Deleted {
+			res = SyncResult{Status: SyncStatusApplied, Deleted: true}
+		}
+		return res, err
+	}
+
+	var fieldTimes map[string]int64
+	fieldTimes, err = recordFieldTimes(record)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	clientMs := mutation.ClientTime.UnixMilli()
+	conflicts := []string{}
+	for _, field := range []string{syncFieldName, syncFieldType, syncFieldDate, syncFieldAmount} {
+		if fieldTimes[field] > clientMs {
+			conflicts = append(conflicts, field)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return SyncResult{Status: SyncStatusConflict, Record: &record, Conflicts: conflicts}, nil
+	}
+
+	err = checkPeriodOpen(ctx, q, record.AccountID, record.Date)
+	if err == ErrPeriodClosed {
+		return SyncResult{Record: &record, Err: err}, nil
+	}
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	err = q.DeleteAttachmentsByRecordId(ctx, record.ID)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	err = q.DeleteRecordCommentsByRecordId(ctx, record.ID)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	err = q.DeleteRecordApprovalsByRecordId(ctx, record.ID)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	err = q.DeleteRecord(ctx, record.ID)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	return SyncResult{Status: SyncStatusApplied, Record: &record, Deleted: true, Changed: true}, nil
+}
+
+/**
+ * 按Id或AccountId+ClientId查询并锁定记录, 记录不属于AccountId时视为不存在
+ */
+func getRecordForSync(ctx context.Context, q *sqlc.Queries, mutation SyncMutation) (Record, bool, error) {
+	var record Record
+	var err error
+	if mutation.Id != 0 {
+		record, err = q.GetRecordForUpdate(ctx, mutation.Id)
+		if err == nil && record.AccountID != mutation.AccountId {
+			err = sql.ErrNoRows
+		}
+	} else {
+		arg := sqlc.GetRecordByClientIdForUpdateParams{
+			AccountID: mutation.AccountId,
+			ClientID:  mutation.ClientId,
+		}
+		record, err = q.GetRecordByClientIdForUpdate(ctx, arg)
+	}
+
+	if err == sql.ErrNoRows {
+		return record, false, nil
+	}
+
+	return record, err == nil, err
+}
+
+/**
+ * 记录不存在时, 区分已被删除和从未存在
+ */
+func syncMissingResult(ctx context.Context, q *sqlc.Queries, mutation SyncMutation, err error) (SyncResult, error) {
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	var deleted bool
+	deleted, err = isRecordDeleted(ctx, q, mutation)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if deleted {
+		return SyncResult{Status: SyncStatusConflict, Deleted: true}, nil
+	}
+
+	return SyncResult{Err: ErrSyncRecordNotFound}, nil
+}
+
+func isRecordDeleted(ctx context.Context, q *sqlc.Queries, mutation SyncMutation) (bool, error) {
+	var change SyncChange
+	var err error
+	if mutation.Id != 0 {
+		arg := sqlc.GetSyncChangeParams{
+			AccountID: mutation.AccountId,
+			Entity:    SyncEntityRecord,
+			EntityID:  mutation.Id,
+		}
+		change, err = q.GetSyncChange(ctx, arg)
+	} else {
+		arg := sqlc.GetRecordSyncChangeByClientIdParams{
+			AccountID: mutation.AccountId,
+			ClientID:  mutation.ClientId,
+		}
+		change, err = q.GetRecordSyncChangeByClientId(ctx, arg)
+	}
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return change.Deleted, err
+}
+
+/**
+ * 各字段的修改时间, 没有记录的字段视为在记录创建时修改
+ */
+func recordFieldTimes(record Record) (map[string]int64, error) {
+	times := map[string]int64{}
+	if len(record.FieldTimes) > 0 {
+		err := json.Unmarshal(record.FieldTimes, &times)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createMs := record.CreateTime.UnixMilli()
+	for _, field := range []string{syncFieldName, syncFieldType, syncFieldDate, syncFieldAmount} {
+		if _, ok := times[field]; !ok {
+			times[field] = createMs
+		}
+	}
+
+	return times, nil
+}
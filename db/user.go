@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/timelyrain/star-account/db/sqlc"
 )
@@ -35,14 +36,25 @@ func (db *DB) CreateUser(
 	return res, err
 }
 
+/**
+ * 注销后用户名和邮箱的替换值, 邮箱带上用户id以满足唯一约束
+ */
+const erasedUserName = "已注销用户"
+
+func erasedUserEmail(id int64) string {
+	return fmt.Sprintf("erased-%d@erased.invalid", id)
+}
+
 /**
  * 1. 找到用户拥有的所有账单的id
- * 2. 按照账单id删除所有的账单记录和账单权限信息
+ * 2. 按照账单id删除所有的附件, 评论, 审批规则, 审批历史, 关账记录, 期初余额, 余额检查点, 账单记录, 预算, webhook和账单权限信息
  * 3. 删除用户id关联的所有账单权限信息(该用户是管理者,但不是拥有者)
- * 4. 删除用户拥有的所有账单
- * 5. 删除该用户的信息
+ * 4. 删除用户拥有的所有账单及其同步变更记录
+ * 5. 删除该用户在其他账单中的成员预算和预算提醒
+ * 6. 删除该用户的幂等键记录, 评论已读位置, 通知, 通知偏好, 摘要订阅和注销申请, 清空其在其他账单中的评论内容
+ * 7. 匿名化该用户的信息, 保留用户行, 使其他账单中的记录仍有合法的创建者
  */
-func (db *DB) DeleteUser(ctx context.Context, id int64) error {
+func (db *DB) EraseUser(ctx context.Context, id int64) error {
 	return db.execTx(ctx, func(q *sqlc.Queries) error {
 		var accountIds []int64
 		var err error
@@ -52,11 +64,71 @@ func (db *DB) DeleteUser(ctx context.Context, id int64) error {
 			return err
 		}
 
+		err = q.DeleteAttachmentsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordCommentsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteCommentReadsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecordApprovalsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteApprovalRulesByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeletePeriodClosingsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteOpeningBalancesByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteBalanceCheckpointsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
 		err = q.DeleteRecordsByAccountIds(ctx, accountIds)
 		if err != nil {
 			return err
 		}
 
+		err = q.DeleteBudgetAlertsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteBudgetsByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteWebhookDeliveriesByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteWebhooksByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
 		err = q.DeleteAccountAccessRulesByAccountIds(ctx, accountIds)
 		if err != nil {
 			return err
@@ -67,12 +139,68 @@ func (db *DB) DeleteUser(ctx context.Context, id int64) error {
 			return err
 		}
 
+		err = q.DeleteSyncChangesByAccountIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
 		err = q.DeleteAccountsByIds(ctx, accountIds)
 		if err != nil {
 			return err
 		}
 
-		return q.DeleteUser(ctx, id)
+		err = q.DeleteBudgetAlertsByBudgetUserId(ctx, nullInt64(id))
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteBudgetsByUserId(ctx, nullInt64(id))
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteIdempotencyKeysByUserId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteCommentReadsByUserId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.EraseRecordCommentsByUserId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteNotificationsByUserId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteNotificationPreferencesByUserId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteDigestSubscription(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserErasure(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		arg := sqlc.AnonymizeUserParams{
+			ID:    id,
+			Name:  erasedUserName,
+			Email: erasedUserEmail(id),
+		}
+
+		return q.AnonymizeUser(ctx, arg)
 	})
 }
 
@@ -131,6 +259,18 @@ func (db *DB) GetUsersByAccountIdAndRole(
 	return res, err
 }
 
+func (db *DB) GetUsersByIds(ctx context.Context, ids []int64) ([]User, error) {
+	var res []User
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetUsersByIds(ctx, ids)
+		return err
+	})
+
+	return res, err
+}
+
 func (db *DB) GetUsersByName(
 	ctx context.Context,
 	name string,
@@ -154,6 +294,57 @@ func (db *DB) GetUsersByName(
 	return res, err
 }
 
+/**
+ * 按id的顺序查询游标之后的用户, cursorId为0时从头开始
+ */
+func (db *DB) GetUsersByAccountIdAndRoleAfterCursor(
+	ctx context.Context,
+	accountId int64,
+	role int32,
+	cursorId int64,
+	limit int64,
+) ([]User, error) {
+	var res []User
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetUsersByAccountIdAndRoleAfterCursorParams{
+			AccountID: accountId,
+			Role:      role,
+			CursorID:  nullInt64(cursorId),
+			PageLimit: limit,
+		}
+
+		res, err = q.GetUsersByAccountIdAndRoleAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetUsersByNameAfterCursor(
+	ctx context.Context,
+	name string,
+	cursorId int64,
+	limit int64,
+) ([]User, error) {
+	var res []User
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetUsersByNameAfterCursorParams{
+			Name:      name,
+			CursorID:  nullInt64(cursorId),
+			PageLimit: limit,
+		}
+
+		res, err = q.GetUsersByNameAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
 func (db *DB) UpdateUserEmail(ctx context.Context, id int64, email string) error {
 	return db.exec(ctx, func(q *sqlc.Queries) error {
 		arg := sqlc.UpdateUserEmailParams{
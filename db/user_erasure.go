@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type UserErasure = sqlc.UserErasure
+
+/**
+ * 申请注销用户, 已有申请时返回原来的申请, 不会推迟注销时间
+ */
+func (db *DB) CreateUserErasure(ctx context.Context, userId int64, eraseTime time.Time) (UserErasure, error) {
+	var res UserErasure
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.CreateUserErasureParams{
+			UserID:    userId,
+			EraseTime: eraseTime,
+		}
+
+		res, err = q.CreateUserErasure(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetUserErasure(ctx context.Context, userId int64) (UserErasure, error) {
+	var res UserErasure
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetUserErasure(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) DeleteUserErasure(ctx context.Context, userId int64) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		return q.DeleteUserErasure(ctx, userId)
+	})
+}
+
+/**
+ * 宽限期已结束且失败次数少于maxAttempts的注销申请, 按注销时间和用户id排序, 返回游标之后的一页
+ */
+func (db *DB) GetDueUserErasuresAfterCursor(
+	ctx context.Context,
+	now time.Time,
+	maxAttempts int32,
+	cursorTime time.Time,
+	cursorId int64,
+	limit int64,
+) ([]UserErasure, error) {
+	var res []UserErasure
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetDueUserErasuresAfterCursorParams{
+			Now:         now,
+			MaxAttempts: maxAttempts,
+			CursorTime:  nullTime(cursorTime),
+			CursorID:    cursorId,
+			PageLimit:   limit,
+		}
+
+		res, err = q.GetDueUserErasuresAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 记录一次失败的注销
+ */
+func (db *DB) UpdateUserErasureAttempt(ctx context.Context, userId int64, errMsg string) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.UpdateUserErasureAttemptParams{
+			UserID: userId,
+			Error:  errMsg,
+		}
+
+		return q.UpdateUserErasureAttempt(ctx, arg)
+	})
+}
+
+/**
+ * 用户的个人数据: 用户信息, 所属账单及权限, 用户创建的所有账单记录
+ */
+type UserData struct {
+	User               User
+	AccountAccessRules []AccountAccessRule
+	Accounts           []Account
+	Records            []Record
+	Comments           []RecordComment
+}
+
+func (db *DB) GetUserData(ctx context.Context, userId int64) (UserData, error) {
+	var res UserData
+
+	err := db.execTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res.User, err = q.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		res.AccountAccessRules, err = q.GetAccountAccessRulesByUserId(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		accountIds := make([]int64, len(res.AccountAccessRules))
+		for i, rule := range res.AccountAccessRules {
+			accountIds[i] = rule.AccountID
+		}
+
+		res.Accounts, err = q.GetAccountsByIds(ctx, accountIds)
+		if err != nil {
+			return err
+		}
+
+		res.Records, err = q.GetRecordsByCreateUserId(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		res.Comments, err = q.GetRecordCommentsByUserId(ctx, userId)
+		return err
+	})
+
+	return res, err
+}
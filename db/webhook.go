@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/timelyrain/star-account/db/sqlc"
+)
+
+type Webhook = sqlc.Webhook
+
+type WebhookDelivery = sqlc.WebhookDelivery
+
+func (db *DB) CreateWebhook(
+	ctx context.Context,
+	accountId int64,
+	url string,
+	secret string,
+	events []string,
+	createUserId int64,
+) (Webhook, error) {
+	var res Webhook
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.CreateWebhookParams{
+			AccountID:    accountId,
+			Url:          url,
+			Secret:       secret,
+			Events:       events,
+			CreateUserID: createUserId,
+		}
+
+		res, err = q.CreateWebhook(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	var res Webhook
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetWebhook(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetWebhooksByIds(ctx context.Context, ids []int64) ([]Webhook, error) {
+	var res []Webhook
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetWebhooksByIds(ctx, ids)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) GetWebhooksByAccountId(ctx context.Context, accountId int64) ([]Webhook, error) {
+	var res []Webhook
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetWebhooksByAccountId(ctx, accountId)
+		return err
+	})
+
+	return res, err
+}
+
+func (db *DB) UpdateWebhook(
+	ctx context.Context,
+	id int64,
+	url string,
+	events []string,
+	active bool,
+) (Webhook, error) {
+	var res Webhook
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.UpdateWebhookParams{
+			ID:     id,
+			Url:    url,
+			Events: events,
+			Active: active,
+		}
+
+		res, err = q.UpdateWebhook(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 1. 删除webhook的所有投递记录
+ * 2. 删除webhook
+ */
+func (db *DB) DeleteWebhook(ctx context.Context, id int64) error {
+	return db.execTx(ctx, func(q *sqlc.Queries) error {
+		err := q.DeleteWebhookDeliveriesByWebhookId(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteWebhook(ctx, id)
+	})
+}
+
+func (db *DB) GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	var res WebhookDelivery
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.GetWebhookDelivery(ctx, id)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 按id DESC的顺序查询游标之后的投递记录, cursorId为0时从头开始
+ */
+func (db *DB) GetWebhookDeliveriesByWebhookIdAfterCursor(
+	ctx context.Context,
+	webhookId int64,
+	cursorId int64,
+	limit int64,
+) ([]WebhookDelivery, error) {
+	var res []WebhookDelivery
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.GetWebhookDeliveriesByWebhookIdAfterCursorParams{
+			WebhookID: webhookId,
+			CursorID:  nullInt64(cursorId),
+			PageLimit: limit,
+		}
+
+		res, err = q.GetWebhookDeliveriesByWebhookIdAfterCursor(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 领取到期的投递记录, 并把下次投递时间推迟到leaseUntil
+ * 领取者崩溃时, 记录在leaseUntil之后可以被重新领取
+ */
+func (db *DB) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	leaseUntil time.Time,
+	limit int64,
+) ([]WebhookDelivery, error) {
+	var res []WebhookDelivery
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		arg := sqlc.ClaimWebhookDeliveriesParams{
+			LeaseUntil: leaseUntil,
+			Now:        now,
+			PageLimit:  limit,
+		}
+
+		res, err = q.ClaimWebhookDeliveries(ctx, arg)
+		return err
+	})
+
+	return res, err
+}
+
+/**
+ * 记录一次投递的结果, deliveredTime为零值表示未投递成功
+ */
+func (db *DB) UpdateWebhookDeliveryAttempt(
+	ctx context.Context,
+	id int64,
+	status int32,
+	nextAttemptTime time.Time,
+	responseStatus int32,
+	errMsg string,
+	deliveredTime time.Time,
+) error {
+	return db.exec(ctx, func(q *sqlc.Queries) error {
+		arg := sqlc.UpdateWebhookDeliveryAttemptParams{
+			ID:              id,
+			Status:          status,
+			NextAttemptTime: nextAttemptTime,
+			ResponseStatus:  responseStatus,
+			Error:           errMsg,
+			DeliveredTime:   nullTime(deliveredTime),
+		}
+
+		return q.UpdateWebhookDeliveryAttempt(ctx, arg)
+	})
+}
+
+/**
+ * 重新投递, 重置重试次数并立即进入待投递状态
+ */
+func (db *DB) RedeliverWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	var res WebhookDelivery
+
+	err := db.exec(ctx, func(q *sqlc.Queries) error {
+		var err error
+		res, err = q.RedeliverWebhookDelivery(ctx, id)
+		return err
+	})
+
+	return res, err
+}
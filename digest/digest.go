@@ -0,0 +1,121 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/digest.txt.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/digest.html.tmpl"))
+)
+
+/**
+ * 一封摘要邮件的内容, 金额都是格式化后的字符串
+ */
+type Digest struct {
+	UserName    string
+	Frequency   util.DigestFrequency
+	PeriodStart string
+	// 周期的最后一天
+	PeriodEnd string
+	Accounts  []Account
+}
+
+/**
+ * 一个账单在周期内的摘要
+ * ExpenseChange是支出相对上一周期的变化, 如"+12.5%", 上一周期没有支出时为空
+ */
+type Account struct {
+	Name            string
+	Income          string
+	Expense         string
+	PreviousIncome  string
+	PreviousExpense string
+	ExpenseChange   string
+	Categories      []Category
+	TopExpenses     []Expense
+	Budgets         []Budget
+}
+
+type Category struct {
+	Name    string
+	Income  string
+	Expense string
+}
+
+type Expense struct {
+	Date     string
+	Name     string
+	Category string
+	Amount   string
+}
+
+type Budget struct {
+	Name    string
+	Period  string
+	Spent   string
+	Limit   string
+	Percent int32
+}
+
+/**
+ * 邮件中显示的分类名称
+ */
+var categoryNames = map[util.RecordType]string{
+	util.RecordTypeFood:      "餐饮",
+	util.RecordTypeShopping:  "购物",
+	util.RecordTypeCommuting: "交通",
+	util.RecordTypeAmuse:     "娱乐",
+	util.RecordTypeStudying:  "学习",
+	util.RecordTypeOffice:    "办公",
+	util.RecordTypeGift:      "礼物",
+}
+
+func CategoryName(recordType util.RecordType) string {
+	if name, ok := categoryNames[recordType]; ok {
+		return name
+	}
+	return "其他"
+}
+
+func (digest Digest) FrequencyName() string {
+	if digest.Frequency == util.DigestFrequencyMonthly {
+		return "月度"
+	}
+	return "每周"
+}
+
+func (budget Budget) Over() bool {
+	return budget.Percent >= 100
+}
+
+func (digest Digest) Subject() string {
+	return "STAR-ACCOUNT " + digest.FrequencyName() + "摘要 " + digest.PeriodStart + " ~ " + digest.PeriodEnd
+}
+
+/**
+ * 渲染纯文本和HTML两种格式的正文
+ */
+func Render(digest Digest) (string, string, error) {
+	var text bytes.Buffer
+	err := textTemplate.Execute(&text, digest)
+	if err != nil {
+		return "", "", err
+	}
+
+	var html bytes.Buffer
+	err = htmlTemplate.Execute(&html, digest)
+	if err != nil {
+		return "", "", err
+	}
+
+	return text.String(), html.String(), nil
+}
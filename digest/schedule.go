@@ -0,0 +1,66 @@
+package digest
+
+import (
+	"time"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 摘要在用户所在时区的发送时刻, 每周摘要在周一发送, 每月摘要在1日发送
+ */
+const sendHour = 8
+
+/**
+ * after之后(不含)的下一次发送时间
+ */
+func NextSendTime(frequency util.DigestFrequency, after time.Time, loc *time.Location) time.Time {
+	local := after.In(loc)
+	year, month, day := local.Date()
+
+	var next time.Time
+	if frequency == util.DigestFrequencyMonthly {
+		next = time.Date(year, month, 1, sendHour, 0, 0, 0, loc)
+		for !next.After(after) {
+			month++
+			next = time.Date(year, month, 1, sendHour, 0, 0, 0, loc)
+		}
+		return next
+	}
+
+	day -= (int(local.Weekday()) + 6) % 7
+	next = time.Date(year, month, day, sendHour, 0, 0, 0, loc)
+	for !next.After(after) {
+		day += 7
+		next = time.Date(year, month, day, sendHour, 0, 0, 0, loc)
+	}
+	return next
+}
+
+/**
+ * 发送时间对应的周期, 即发送日之前完整的一周或一个月
+ * 日期以UTC零点表示, 与数据库中date类型的取值一致, end不包含在周期内
+ */
+func Period(frequency util.DigestFrequency, sendTime time.Time, loc *time.Location) (time.Time, time.Time) {
+	year, month, day := sendTime.In(loc).Date()
+
+	if frequency == util.DigestFrequencyMonthly {
+		end := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		return end.AddDate(0, -1, 0), end
+	}
+
+	end := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	end = end.AddDate(0, 0, -((int(end.Weekday()) + 6) % 7))
+	return end.AddDate(0, 0, -7), end
+}
+
+/**
+ * 用于比较的上一个周期
+ */
+func PreviousPeriod(frequency util.DigestFrequency, start time.Time) (time.Time, time.Time) {
+	if frequency == util.DigestFrequencyMonthly {
+		return start.AddDate(0, -1, 0), start
+	}
+
+	return start.AddDate(0, 0, -7), start
+}
@@ -0,0 +1,496 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"unicode/utf16"
+)
+
+var (
+	errUnsupportedFont = errors.New("only truetype fonts with glyf outlines are supported")
+	errInvalidFont     = errors.New("invalid font file")
+)
+
+/**
+ * 嵌入PDF的TrueType字体, 只支持glyf轮廓的单个字体文件, 不支持CFF轮廓的OpenType和TTC字体集合
+ * 1. 宽度统一换算为1/1000字号
+ * 2. 嵌入时只保留用到的字形, 字形编号保持不变, 因此PDF中可以直接用字形编号作为CID
+ */
+type Font struct {
+	name        string
+	unitsPerEm  int
+	bbox        [4]int
+	ascent      int
+	descent     int
+	capHeight   int
+	italicAngle int
+	numGlyphs   int
+	advances    []int
+	glyphs      map[rune]uint16
+	tables      map[string][]byte
+	glyphData   [][]byte
+}
+
+/**
+ * 从文件加载字体
+ */
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseFont(data)
+}
+
+func ParseFont(data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, errInvalidFont
+	}
+	switch binary.BigEndian.Uint32(data) {
+	case 0x00010000, 0x74727565: // 'true'
+	default:
+		return nil, errUnsupportedFont
+	}
+
+	tables := map[string][]byte{}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil, errInvalidFont
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, errInvalidFont
+		}
+		tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("font table %s is missing: %w", tag, errUnsupportedFont)
+		}
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errInvalidFont
+	}
+
+	font := &Font{
+		name:       "Embedded",
+		unitsPerEm: int(binary.BigEndian.Uint16(head[18:])),
+		numGlyphs:  int(binary.BigEndian.Uint16(maxp[4:])),
+		tables:     tables,
+	}
+	if font.unitsPerEm == 0 {
+		return nil, errInvalidFont
+	}
+	for i := range font.bbox {
+		font.bbox[i] = font.scale(int(int16(binary.BigEndian.Uint16(head[36+i*2:]))))
+	}
+	font.ascent = font.scale(int(int16(binary.BigEndian.Uint16(hhea[4:]))))
+	font.descent = font.scale(int(int16(binary.BigEndian.Uint16(hhea[6:]))))
+	font.capHeight = font.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		font.capHeight = font.scale(int(int16(binary.BigEndian.Uint16(os2[88:]))))
+	}
+	if post := tables["post"]; len(post) >= 8 {
+		font.italicAngle = int(int16(binary.BigEndian.Uint16(post[4:])))
+	}
+	if name := postScriptName(tables["name"]); name != "" {
+		font.name = name
+	}
+
+	err := font.parseAdvances(tables["hmtx"], int(binary.BigEndian.Uint16(hhea[34:])))
+	if err != nil {
+		return nil, err
+	}
+	err = font.parseGlyphData(tables["loca"], tables["glyf"], binary.BigEndian.Uint16(head[50:]) == 1)
+	if err != nil {
+		return nil, err
+	}
+	font.glyphs, err = parseCmap(tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+
+	return font, nil
+}
+
+func (font *Font) scale(v int) int {
+	return v * 1000 / font.unitsPerEm
+}
+
+/**
+ * 字符对应的字形编号, 字体中没有的字符返回0, 即.notdef
+ */
+func (font *Font) GlyphIndex(r rune) uint16 {
+	return font.glyphs[r]
+}
+
+/**
+ * 字形的宽度, 单位为1/1000字号
+ */
+func (font *Font) GlyphWidth(gid uint16) int {
+	if int(gid) >= len(font.advances) {
+		return 0
+	}
+	return font.advances[gid]
+}
+
+func (font *Font) parseAdvances(hmtx []byte, numberOfHMetrics int) error {
+	if numberOfHMetrics == 0 || numberOfHMetrics > font.numGlyphs || len(hmtx) < numberOfHMetrics*4 {
+		return errInvalidFont
+	}
+
+	// numberOfHMetrics之后的字形沿用最后一个宽度
+	font.advances = make([]int, font.numGlyphs)
+	for i := range font.advances {
+		metric := i
+		if metric >= numberOfHMetrics {
+			metric = numberOfHMetrics - 1
+		}
+		font.advances[i] = font.scale(int(binary.BigEndian.Uint16(hmtx[metric*4:])))
+	}
+	return nil
+}
+
+func (font *Font) parseGlyphData(loca []byte, glyf []byte, longOffsets bool) error {
+	offset := func(i int) int {
+		if longOffsets {
+			return int(binary.BigEndian.Uint32(loca[i*4:]))
+		}
+		return int(binary.BigEndian.Uint16(loca[i*2:])) * 2
+	}
+	size := 2
+	if longOffsets {
+		size = 4
+	}
+	if len(loca) < (font.numGlyphs+1)*size {
+		return errInvalidFont
+	}
+
+	font.glyphData = make([][]byte, font.numGlyphs)
+	for i := range font.glyphData {
+		start, end := offset(i), offset(i+1)
+		if start > end || end > len(glyf) {
+			return errInvalidFont
+		}
+		font.glyphData[i] = glyf[start:end]
+	}
+	return nil
+}
+
+/**
+ * 解析Unicode的cmap子表, 优先使用支持全部平面的format 12
+ */
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errInvalidFont
+	}
+
+	var format4, format12 []byte
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numTables; i++ {
+		record := 4 + i*8
+		if record+8 > len(cmap) {
+			return nil, errInvalidFont
+		}
+		platformId := binary.BigEndian.Uint16(cmap[record:])
+		encodingId := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+4 > len(cmap) {
+			return nil, errInvalidFont
+		}
+		if platformId != 0 && !(platformId == 3 && (encodingId == 1 || encodingId == 10)) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		return parseCmapFormat12(format12)
+	case format4 != nil:
+		return parseCmapFormat4(format4)
+	default:
+		return nil, fmt.Errorf("unicode cmap is missing: %w", errUnsupportedFont)
+	}
+}
+
+func parseCmapFormat4(table []byte) (map[rune]uint16, error) {
+	if len(table) < 14 {
+		return nil, errInvalidFont
+	}
+	segCount := int(binary.BigEndian.Uint16(table[6:])) / 2
+	endCodes := 14
+	startCodes := endCodes + segCount*2 + 2
+	idDeltas := startCodes + segCount*2
+	idRangeOffsets := idDeltas + segCount*2
+	if len(table) < idRangeOffsets+segCount*2 {
+		return nil, errInvalidFont
+	}
+
+	glyphs := map[rune]uint16{}
+	for i := 0; i < segCount; i++ {
+		end := int(binary.BigEndian.Uint16(table[endCodes+i*2:]))
+		start := int(binary.BigEndian.Uint16(table[startCodes+i*2:]))
+		delta := binary.BigEndian.Uint16(table[idDeltas+i*2:])
+		rangeOffset := int(binary.BigEndian.Uint16(table[idRangeOffsets+i*2:]))
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			var gid uint16
+			if rangeOffset == 0 {
+				gid = uint16(c) + delta
+			} else {
+				// idRangeOffset是相对于自身位置的偏移
+				index := idRangeOffsets + i*2 + rangeOffset + (c-start)*2
+				if index+2 > len(table) {
+					return nil, errInvalidFont
+				}
+				gid = binary.BigEndian.Uint16(table[index:])
+				if gid != 0 {
+					gid += delta
+				}
+			}
+			if gid != 0 {
+				glyphs[rune(c)] = gid
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+func parseCmapFormat12(table []byte) (map[rune]uint16, error) {
+	if len(table) < 16 {
+		return nil, errInvalidFont
+	}
+	numGroups := int(binary.BigEndian.Uint32(table[12:]))
+	if numGroups < 0 || len(table) < 16+numGroups*12 {
+		return nil, errInvalidFont
+	}
+
+	glyphs := map[rune]uint16{}
+	for i := 0; i < numGroups; i++ {
+		group := table[16+i*12:]
+		start := rune(binary.BigEndian.Uint32(group))
+		end := rune(binary.BigEndian.Uint32(group[4:]))
+		startGlyph := binary.BigEndian.Uint32(group[8:])
+		if end > 0x10FFFF || start > end {
+			return nil, errInvalidFont
+		}
+		for c := start; c <= end; c++ {
+			glyphs[c] = uint16(startGlyph + uint32(c-start))
+		}
+	}
+	return glyphs, nil
+}
+
+/**
+ * name表中的PostScript名称(nameID 6), 只保留PDF名称中允许的字符
+ */
+func postScriptName(name []byte) string {
+	if len(name) < 6 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint16(name[2:]))
+	storage := int(binary.BigEndian.Uint16(name[4:]))
+	for i := 0; i < count; i++ {
+		record := 6 + i*12
+		if record+12 > len(name) {
+			return ""
+		}
+		platformId := binary.BigEndian.Uint16(name[record:])
+		nameId := binary.BigEndian.Uint16(name[record+6:])
+		length := int(binary.BigEndian.Uint16(name[record+8:]))
+		offset := storage + int(binary.BigEndian.Uint16(name[record+10:]))
+		if nameId != 6 || offset+length > len(name) {
+			continue
+		}
+
+		raw := name[offset : offset+length]
+		var s []rune
+		switch platformId {
+		case 1:
+			s = []rune(string(raw))
+		case 0, 3:
+			units := make([]uint16, len(raw)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(raw[j*2:])
+			}
+			s = utf16.Decode(units)
+		default:
+			continue
+		}
+
+		result := []rune{}
+		for _, r := range s {
+			if r > 32 && r < 127 && r != '/' && r != '(' && r != ')' && r != '<' && r != '>' &&
+				r != '[' && r != ']' && r != '{' && r != '}' && r != '%' && r != '#' {
+				result = append(result, r)
+			}
+		}
+		if len(result) > 0 {
+			return string(result)
+		}
+	}
+	return ""
+}
+
+/**
+ * 生成只包含指定字形的字体文件, 组合字形引用的字形也会保留
+ * 未用到的字形数据置空, 字形编号保持不变
+ */
+func (font *Font) Subset(gids []uint16) []byte {
+	keep := map[uint16]bool{}
+	queue := append([]uint16{0}, gids...)
+	for len(queue) > 0 {
+		gid := queue[0]
+		queue = queue[1:]
+		if int(gid) >= font.numGlyphs || keep[gid] {
+			continue
+		}
+		keep[gid] = true
+		queue = append(queue, compositeComponents(font.glyphData[gid])...)
+	}
+
+	var glyf []byte
+	loca := make([]byte, (font.numGlyphs+1)*4)
+	for i, data := range font.glyphData {
+		binary.BigEndian.PutUint32(loca[i*4:], uint32(len(glyf)))
+		if keep[uint16(i)] {
+			glyf = append(glyf, data...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[font.numGlyphs*4:], uint32(len(glyf)))
+
+	// loca统一使用长偏移, checkSumAdjustment在最后重新计算
+	head := append([]byte{}, font.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{
+		"glyf": glyf,
+		"head": head,
+		"hhea": font.tables["hhea"],
+		"hmtx": font.tables["hmtx"],
+		"loca": loca,
+		"maxp": font.tables["maxp"],
+	}
+	// 保留hinting相关的表
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if table, ok := font.tables[tag]; ok {
+			tables[tag] = table
+		}
+	}
+
+	data := writeFont(tables)
+	headOffset := 0
+	for i := 0; i < len(tables); i++ {
+		if string(data[12+i*16:16+i*16]) == "head" {
+			headOffset = int(binary.BigEndian.Uint32(data[12+i*16+8:]))
+		}
+	}
+	binary.BigEndian.PutUint32(data[headOffset+8:], 0xB1B0AFBA-fontChecksum(data))
+	return data
+}
+
+/**
+ * 组合字形(numberOfContours < 0)引用的字形编号
+ */
+func compositeComponents(glyph []byte) []uint16 {
+	const (
+		argsAreWords    = 0x0001
+		haveScale       = 0x0008
+		moreComponents  = 0x0020
+		haveXYScale     = 0x0040
+		haveTwoByTwo    = 0x0080
+		componentHeader = 4
+	)
+
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+
+	components := []uint16{}
+	offset := 10
+	for offset+componentHeader <= len(glyph) {
+		flags := binary.BigEndian.Uint16(glyph[offset:])
+		components = append(components, binary.BigEndian.Uint16(glyph[offset+2:]))
+		offset += componentHeader
+		if flags&argsAreWords != 0 {
+			offset += 4
+		} else {
+			offset += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			offset += 2
+		case flags&haveXYScale != 0:
+			offset += 4
+		case flags&haveTwoByTwo != 0:
+			offset += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+func writeFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= len(tags) {
+		searchRange *= 2
+		entrySelector++
+	}
+
+	data := make([]byte, 12+len(tags)*16)
+	binary.BigEndian.PutUint32(data, 0x00010000)
+	binary.BigEndian.PutUint16(data[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(data[6:], uint16(searchRange*16))
+	binary.BigEndian.PutUint16(data[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(data[10:], uint16((len(tags)-searchRange)*16))
+
+	for i, tag := range tags {
+		table := tables[tag]
+		record := data[12+i*16 : 28+i*16]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], fontChecksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(len(data)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		data = append(data, table...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	return data
+}
+
+func fontChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,340 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	testGlyphNotdef    = 0
+	testGlyphZhong     = 1 // 中, 简单字形
+	testGlyphWen       = 2 // 文, 简单字形, cmap中通过idRangeOffset映射
+	testGlyphGuo       = 3 // 国, 引用1和4的组合字形
+	testGlyphComponent = 4 // 字, 简单字形
+	testNumGlyphs      = 5
+)
+
+func putUint16s(values ...int) []byte {
+	data := make([]byte, len(values)*2)
+	for i, v := range values {
+		binary.BigEndian.PutUint16(data[i*2:], uint16(v))
+	}
+	return data
+}
+
+/**
+ * 只有一个三角形轮廓的简单字形, 长度补齐为偶数以便使用短偏移的loca
+ */
+func testSimpleGlyph(size int) []byte {
+	glyph := putUint16s(1, 0, 0, size, size, 2, 0)
+	glyph = append(glyph, 0x01, 0x01, 0x01)
+	glyph = append(glyph, putUint16s(0, size, -size)...)
+	glyph = append(glyph, putUint16s(0, size, 0)...)
+	return append(glyph, 0)
+}
+
+/**
+ * 第一个组件使用字长的参数, 第二个组件使用字节参数和缩放
+ */
+func testCompositeGlyph() []byte {
+	glyph := putUint16s(-1, 0, 0, 2048, 2048)
+	glyph = append(glyph, putUint16s(0x0001|0x0020, testGlyphZhong, 100, -100)...)
+	glyph = append(glyph, putUint16s(0x0008, testGlyphComponent)...)
+	glyph = append(glyph, 10, 20)
+	return append(glyph, putUint16s(0x2000)...)
+}
+
+func testCmap() []byte {
+	// 中 U+4E2D, 国 U+56FD, 字 U+5B57, 文 U+6587, 最后一段是必需的0xFFFF
+	segments := []struct{ start, end, delta, rangeOffset int }{
+		{0x4E2D, 0x4E2D, testGlyphZhong - 0x4E2D, 0},
+		{0x56FD, 0x56FD, testGlyphGuo - 0x56FD, 0},
+		{0x5B57, 0x5B57, testGlyphComponent - 0x5B57, 0},
+		{0x6587, 0x6587, 0, 4},
+		{0xFFFF, 0xFFFF, 1, 0},
+	}
+	segCount := len(segments)
+
+	table := putUint16s(4, 0, 0, segCount*2, 8, 2, segCount*2-8)
+	for _, s := range segments {
+		table = append(table, putUint16s(s.end)...)
+	}
+	table = append(table, 0, 0)
+	for _, s := range segments {
+		table = append(table, putUint16s(s.start)...)
+	}
+	for _, s := range segments {
+		table = append(table, putUint16s(s.delta)...)
+	}
+	for _, s := range segments {
+		table = append(table, putUint16s(s.rangeOffset)...)
+	}
+	table = append(table, putUint16s(testGlyphWen)...)
+	binary.BigEndian.PutUint16(table[2:], uint16(len(table)))
+
+	cmap := putUint16s(0, 1, 3, 1, 0, 12)
+	return append(cmap, table...)
+}
+
+func testName() []byte {
+	value := putUint16s()
+	for _, unit := range utf16.Encode([]rune("Test Sans(1)")) {
+		value = append(value, putUint16s(int(unit))...)
+	}
+	name := putUint16s(0, 1, 18, 3, 1, 0x409, 6, len(value), 0)
+	return append(name, value...)
+}
+
+/**
+ * 用代码构造的最小TrueType字体, unitsPerEm为2048, loca使用短偏移
+ */
+func testFontData() []byte {
+	glyphs := [][]byte{
+		testSimpleGlyph(1024),
+		testSimpleGlyph(2048),
+		testSimpleGlyph(1536),
+		testCompositeGlyph(),
+		testSimpleGlyph(512),
+	}
+	var glyf []byte
+	loca := []byte{}
+	for _, glyph := range glyphs {
+		loca = append(loca, putUint16s(len(glyf)/2)...)
+		glyf = append(glyf, glyph...)
+	}
+	loca = append(loca, putUint16s(len(glyf)/2)...)
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)
+	binary.BigEndian.PutUint32(head[12:], 0x5F0F3CF5)
+	copy(head[18:], putUint16s(2048))
+	copy(head[36:], putUint16s(-100, -400, 2000, 1800))
+
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint32(hhea, 0x00010000)
+	copy(hhea[4:], putUint16s(1800, -400))
+	copy(hhea[34:], putUint16s(3))
+
+	maxp := append(putUint16s(0, 0x5000), putUint16s(testNumGlyphs)...)
+
+	// 后两个字形沿用最后一个宽度, 只有左侧空白
+	hmtx := putUint16s(1024, 0, 2048, 0, 1536, 0, 10, 20)
+
+	os2 := make([]byte, 96)
+	copy(os2, putUint16s(2))
+	copy(os2[88:], putUint16s(1434))
+
+	post := make([]byte, 32)
+	binary.BigEndian.PutUint32(post, 0x00030000)
+	copy(post[4:], putUint16s(-12))
+
+	return writeFont(map[string][]byte{
+		"cmap": testCmap(),
+		"glyf": glyf,
+		"head": head,
+		"hhea": hhea,
+		"hmtx": hmtx,
+		"loca": loca,
+		"maxp": maxp,
+		"name": testName(),
+		"OS/2": os2,
+		"post": post,
+		"prep": {0xB0, 0x01},
+	})
+}
+
+func testFont(t *testing.T) *Font {
+	t.Helper()
+
+	font, err := ParseFont(testFontData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+/**
+ * 字体文件的表目录, 同时检查每个表的校验和
+ */
+func fontTables(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	tables := map[string][]byte{}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := data[12+i*16:]
+		tag := string(record[:4])
+		offset := binary.BigEndian.Uint32(record[8:])
+		length := binary.BigEndian.Uint32(record[12:])
+		if offset%4 != 0 {
+			t.Errorf("table %s offset %d is not aligned", tag, offset)
+		}
+		table := data[offset : offset+length]
+		if tag != "head" && fontChecksum(table) != binary.BigEndian.Uint32(record[4:]) {
+			t.Errorf("table %s checksum mismatch", tag)
+		}
+		tables[tag] = table
+	}
+	return tables
+}
+
+func TestParseFont(t *testing.T) {
+	font := testFont(t)
+
+	if font.name != "TestSans1" {
+		t.Errorf("name = %q, want TestSans1", font.name)
+	}
+	if font.bbox != [4]int{-48, -195, 976, 878} {
+		t.Errorf("bbox = %v", font.bbox)
+	}
+	if font.ascent != 878 || font.descent != -195 || font.capHeight != 700 || font.italicAngle != -12 {
+		t.Errorf("ascent, descent, capHeight, italicAngle = %d, %d, %d, %d",
+			font.ascent, font.descent, font.capHeight, font.italicAngle)
+	}
+
+	glyphs := map[rune]uint16{
+		'中': testGlyphZhong,
+		'文': testGlyphWen,
+		'国': testGlyphGuo,
+		'字': testGlyphComponent,
+		'A': testGlyphNotdef,
+		'😀': testGlyphNotdef,
+	}
+	for r, want := range glyphs {
+		if got := font.GlyphIndex(r); got != want {
+			t.Errorf("GlyphIndex(%q) = %d, want %d", r, got, want)
+		}
+	}
+
+	widths := map[uint16]int{0: 500, 1: 1000, 2: 750, 3: 750, 4: 750, 5: 0}
+	for gid, want := range widths {
+		if got := font.GlyphWidth(gid); got != want {
+			t.Errorf("GlyphWidth(%d) = %d, want %d", gid, got, want)
+		}
+	}
+}
+
+func TestParseFontInvalid(t *testing.T) {
+	data := testFontData()
+
+	otto := append([]byte("OTTO"), data[4:]...)
+	truncated := data[:len(data)-64]
+	noCmap := fontTables(t, data)
+	delete(noCmap, "cmap")
+
+	cases := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"empty":     {nil, errInvalidFont},
+		"cff":       {otto, errUnsupportedFont},
+		"truncated": {truncated, errInvalidFont},
+		"no cmap":   {writeFont(noCmap), errUnsupportedFont},
+	}
+
+	for name, c := range cases {
+		_, err := ParseFont(c.data)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: err = %v, want %v", name, err, c.err)
+		}
+	}
+}
+
+func TestParseCmapFormat12(t *testing.T) {
+	format12 := make([]byte, 16)
+	binary.BigEndian.PutUint16(format12, 12)
+	binary.BigEndian.PutUint32(format12[12:], 2)
+	for _, group := range [][3]uint32{{0x4E2D, 0x4E2D, 1}, {0x1F600, 0x1F602, 2}} {
+		format12 = binary.BigEndian.AppendUint32(format12, group[0])
+		format12 = binary.BigEndian.AppendUint32(format12, group[1])
+		format12 = binary.BigEndian.AppendUint32(format12, group[2])
+	}
+
+	// format 4的子表在前, 仍然优先使用format 12
+	format4 := testCmap()[12:]
+	cmap := putUint16s(0, 2, 3, 1, 0, 20, 3, 10, 0, 20+len(format4))
+	cmap = append(cmap, format4...)
+	cmap = append(cmap, format12...)
+
+	glyphs, err := parseCmap(cmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[rune]uint16{'中': 1, '😀': 2, '😁': 3, '😂': 4}
+	if len(glyphs) != len(want) {
+		t.Errorf("glyphs = %v, want %v", glyphs, want)
+	}
+	for r, gid := range want {
+		if glyphs[r] != gid {
+			t.Errorf("glyphs[%q] = %d, want %d", r, glyphs[r], gid)
+		}
+	}
+}
+
+func TestCompositeComponents(t *testing.T) {
+	got := compositeComponents(testCompositeGlyph())
+	if len(got) != 2 || got[0] != testGlyphZhong || got[1] != testGlyphComponent {
+		t.Errorf("compositeComponents = %v, want [%d %d]", got, testGlyphZhong, testGlyphComponent)
+	}
+
+	if got := compositeComponents(testSimpleGlyph(1024)); got != nil {
+		t.Errorf("simple glyph components = %v, want nil", got)
+	}
+}
+
+/**
+ * 子集字体不包含cmap, 补回原字体的cmap后重新解析, 比较保留和置空的字形
+ */
+func TestFontSubset(t *testing.T) {
+	cases := []struct {
+		gids []uint16
+		keep []uint16
+	}{
+		{[]uint16{testGlyphGuo}, []uint16{testGlyphNotdef, testGlyphZhong, testGlyphGuo, testGlyphComponent}},
+		{[]uint16{testGlyphWen, testGlyphWen, 100}, []uint16{testGlyphNotdef, testGlyphWen}},
+		{nil, []uint16{testGlyphNotdef}},
+	}
+
+	font := testFont(t)
+	for _, c := range cases {
+		data := font.Subset(c.gids)
+
+		if sum := fontChecksum(data); sum != 0xB1B0AFBA {
+			t.Errorf("Subset(%v) checksum = %#x, want 0xb1b0afba", c.gids, sum)
+		}
+		tables := fontTables(t, data)
+		if _, ok := tables["cmap"]; ok {
+			t.Errorf("Subset(%v) contains cmap", c.gids)
+		}
+		if _, ok := tables["prep"]; !ok {
+			t.Errorf("Subset(%v) dropped prep", c.gids)
+		}
+
+		tables["cmap"] = font.tables["cmap"]
+		subset, err := ParseFont(writeFont(tables))
+		if err != nil {
+			t.Fatalf("Subset(%v) cannot be parsed: %v", c.gids, err)
+		}
+		if subset.numGlyphs != testNumGlyphs || subset.GlyphIndex('文') != testGlyphWen || subset.GlyphWidth(testGlyphZhong) != 1000 {
+			t.Errorf("Subset(%v) changed glyph numbering or widths", c.gids)
+		}
+
+		keep := map[uint16]bool{}
+		for _, gid := range c.keep {
+			keep[gid] = true
+		}
+		for gid := range subset.glyphData {
+			want := font.glyphData[gid]
+			if !keep[uint16(gid)] {
+				want = nil
+			}
+			got := bytes.TrimRight(subset.glyphData[gid], "\x00")
+			if !bytes.Equal(got, bytes.TrimRight(want, "\x00")) {
+				t.Errorf("Subset(%v) glyph %d = %x, want %x", c.gids, gid, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package exporter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+const (
+	JournalBeancount = "beancount"
+	JournalLedger    = "ledger"
+
+	defaultAssetAccount = "Assets:Cash"
+)
+
+var (
+	errInvalidAccountPath = errors.New("invalid account path")
+
+	// Beancount的账户名必须以五种根账户之一开头, 每一级以大写字母或数字开头
+	beancountAccountPattern = regexp.MustCompile(`^(Assets|Liabilities|Equity|Income|Expenses)(:[A-Z0-9][A-Za-z0-9-]*)+$`)
+	// Ledger的摘要中连续的空格
+	ledgerSpacesPattern = regexp.MustCompile(` {2,}`)
+
+	beancountEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+/**
+ * 导出为复式记账文本时的一条记录
+ */
+type JournalEntry struct {
+	ID         int64
+	Date       time.Time
+	Name       string
+	RecordType util.RecordType
+	Amount     string
+	Creator    string
+}
+
+/**
+ * 支出和收入按记录类型映射到不同的账户, 未设置的类型使用Expenses:Food, Income:Food这样的默认路径
+ * 所有记录的另一方都是AssetAccount
+ */
+type JournalOptions struct {
+	Format          string
+	Currency        string
+	AssetAccount    string
+	ExpenseAccounts map[util.RecordType]string
+	IncomeAccounts  map[util.RecordType]string
+}
+
+func (opts JournalOptions) assetAccount() string {
+	if opts.AssetAccount == "" {
+		return defaultAssetAccount
+	}
+	return opts.AssetAccount
+}
+
+/**
+ * 金额为负的记录是收入, 其他记录是支出
+ */
+func (opts JournalOptions) categoryAccount(recordType util.RecordType, expense bool) string {
+	accounts, root := opts.IncomeAccounts, "Income"
+	if expense {
+		accounts, root = opts.ExpenseAccounts, "Expenses"
+	}
+
+	if account := accounts[recordType]; account != "" {
+		return account
+	}
+
+	name := util.RecordTypeName(recordType)
+	if name == "" {
+		name = "Other"
+	}
+	return root + ":" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+/**
+ * Beancount的账户名有严格的格式
+ * Ledger的账户名中不能出现连续空格或制表符, 首尾不能有空格, 以括号开头的账户会被当作虚拟账户
+ */
+func (opts JournalOptions) Validate() error {
+	accounts := []string{opts.AssetAccount}
+	for _, account := range opts.ExpenseAccounts {
+		accounts = append(accounts, account)
+	}
+	for _, account := range opts.IncomeAccounts {
+		accounts = append(accounts, account)
+	}
+
+	for _, account := range accounts {
+		if account == "" {
+			continue
+		}
+		if opts.Format == JournalBeancount && !beancountAccountPattern.MatchString(account) ||
+			strings.Contains(account, "  ") || strings.ContainsAny(account, "\t\n;") ||
+			strings.TrimSpace(account) != account || strings.HasPrefix(account, "(") || strings.HasPrefix(account, "[") {
+			return fmt.Errorf("%w: %s", errInvalidAccountPath, account)
+		}
+	}
+
+	return nil
+}
+
+/**
+ * 输出Beancount或Ledger格式的日记账
+ * 1. 记录按日期和id排序, 同样的数据总是产生同样的输出, 便于比较两次导出的差异
+ * 2. Beancount在最早的记录日期为用到的账户生成open指令
+ * 3. 记录的创建者和id写为元数据
+ * 4. 名称中的换行等控制字符替换为空格, 其余字符按各自格式的规则转义
+ */
+func WriteJournal(w io.Writer, entries []JournalEntry, opts JournalOptions) error {
+	entries = append([]JournalEntry{}, entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	// 先解析全部金额, open指令和分录按同样的方式区分收支, 例如-0也是支出
+	amounts := make([]*big.Rat, len(entries))
+	for i, entry := range entries {
+		amount, ok := new(big.Rat).SetString(entry.Amount)
+		if !ok {
+			return fmt.Errorf("invalid amount %q in record %d", entry.Amount, entry.ID)
+		}
+		amounts[i] = amount
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if opts.Format == JournalBeancount {
+		fmt.Fprintf(bw, "option \"operating_currency\" \"%s\"\n\n", opts.Currency)
+
+		if len(entries) > 0 {
+			accounts := map[string]bool{opts.assetAccount(): true}
+			for i, entry := range entries {
+				accounts[opts.categoryAccount(entry.RecordType, amounts[i].Sign() >= 0)] = true
+			}
+
+			sorted := []string{}
+			for account := range accounts {
+				sorted = append(sorted, account)
+			}
+			sort.Strings(sorted)
+
+			for _, account := range sorted {
+				fmt.Fprintf(bw, "%s open %s %s\n", entries[0].Date.Format(util.DateFormat), account, opts.Currency)
+			}
+			bw.WriteString("\n")
+		}
+	}
+
+	for i, entry := range entries {
+		amount := amounts[i]
+		category := opts.categoryAccount(entry.RecordType, amount.Sign() >= 0)
+		// 类别账户的金额与记录相同, 支出记为正数, 收入记为负数, 资产账户的金额相反
+		postings := [][2]string{
+			{category, amount.FloatString(2)},
+			{opts.assetAccount(), new(big.Rat).Neg(amount).FloatString(2)},
+		}
+
+		if opts.Format == JournalBeancount {
+			fmt.Fprintf(bw, "%s * %s\n", entry.Date.Format(util.DateFormat), beancountString(entry.Name))
+			fmt.Fprintf(bw, "  creator: %s\n", beancountString(entry.Creator))
+			fmt.Fprintf(bw, "  record-id: \"%d\"\n", entry.ID)
+			for _, posting := range postings {
+				fmt.Fprintf(bw, "  %-40s %12s %s\n", posting[0], posting[1], opts.Currency)
+			}
+		} else {
+			fmt.Fprintf(bw, "%s %s\n", entry.Date.Format("2006/01/02"), ledgerPayee(entry.Name))
+			fmt.Fprintf(bw, "    ; creator: %s\n", journalText(entry.Creator))
+			fmt.Fprintf(bw, "    ; record-id: %d\n", entry.ID)
+			for _, posting := range postings {
+				fmt.Fprintf(bw, "    %-40s  %12s %s\n", posting[0], posting[1], opts.Currency)
+			}
+		}
+		bw.WriteString("\n")
+	}
+
+	return bw.Flush()
+}
+
+/**
+ * 换行和制表符等控制字符在日记账中没有意义, 替换为空格
+ */
+func journalText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+/**
+ * Beancount的字符串中只有双引号和反斜杠需要转义
+ */
+func beancountString(s string) string {
+	return `"` + beancountEscaper.Replace(journalText(s)) + `"`
+}
+
+/**
+ * Ledger的摘要没有转义语法
+ * 1. 两个以上的空格后的分号会开始注释, 因此连续空格合并为一个
+ * 2. 开头的*和!会被解析为交易状态, (会被解析为交易代码, 这时先写一个空的交易代码
+ */
+func ledgerPayee(s string) string {
+	payee := strings.Trim(ledgerSpacesPattern.ReplaceAllString(journalText(s), " "), " ")
+	if strings.HasPrefix(payee, "*") || strings.HasPrefix(payee, "!") || strings.HasPrefix(payee, "(") {
+		return "() " + payee
+	}
+	return payee
+}
@@ -0,0 +1,192 @@
+package exporter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * 名称中包含引号, 分号, 反斜杠, 换行, 以及会被Ledger当作交易状态或代码的开头
+ * 输入的顺序是乱的, 金额-0按支出处理
+ */
+var testJournalEntries = []JournalEntry{
+	{ID: 3, Date: date("2024-03-06"), Name: `咖啡 "拿铁"; 加糖`, RecordType: util.RecordTypeFood, Amount: "25.5", Creator: "alice"},
+	{ID: 1, Date: date("2024-03-05"), Name: "C:\\票据\r\n第二行\t(备注)", RecordType: util.RecordTypeShopping, Amount: "-100", Creator: "bob\nli"},
+	{ID: 2, Date: date("2024-03-05"), Name: "(AA)  ;  注释", RecordType: util.RecordTypeGift, Amount: "-0", Creator: "carol"},
+	{ID: 4, Date: date("2024-03-06"), Name: " *重要 ", RecordType: util.RecordTypeAmuse, Amount: "0.125", Creator: "dave"},
+}
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+const testBeancountJournal = `option "operating_currency" "CNY"
+
+2024-03-05 open Assets:Cash CNY
+2024-03-05 open Expenses:Amuse CNY
+2024-03-05 open Expenses:Food:Dining CNY
+2024-03-05 open Expenses:Gift CNY
+2024-03-05 open Income:Shopping CNY
+
+2024-03-05 * "C:\\票据  第二行 (备注)"
+  creator: "bob li"
+  record-id: "1"
+  Income:Shopping                               -100.00 CNY
+  Assets:Cash                                    100.00 CNY
+
+2024-03-05 * "(AA)  ;  注释"
+  creator: "carol"
+  record-id: "2"
+  Expenses:Gift                                    0.00 CNY
+  Assets:Cash                                      0.00 CNY
+
+2024-03-06 * "咖啡 \"拿铁\"; 加糖"
+  creator: "alice"
+  record-id: "3"
+  Expenses:Food:Dining                            25.50 CNY
+  Assets:Cash                                    -25.50 CNY
+
+2024-03-06 * " *重要 "
+  creator: "dave"
+  record-id: "4"
+  Expenses:Amuse                                   0.13 CNY
+  Assets:Cash                                     -0.13 CNY
+
+`
+
+const testLedgerJournal = `2024/03/05 C:\票据 第二行 (备注)
+    ; creator: bob li
+    ; record-id: 1
+    Income:Shopping                                -100.00 CNY
+    Assets:Cash                                     100.00 CNY
+
+2024/03/05 () (AA) ; 注释
+    ; creator: carol
+    ; record-id: 2
+    Expenses:Gift                                     0.00 CNY
+    Assets:Cash                                       0.00 CNY
+
+2024/03/06 咖啡 "拿铁"; 加糖
+    ; creator: alice
+    ; record-id: 3
+    Expenses:Food:Dining                             25.50 CNY
+    Assets:Cash                                     -25.50 CNY
+
+2024/03/06 () *重要
+    ; creator: dave
+    ; record-id: 4
+    Expenses:Amuse                                    0.13 CNY
+    Assets:Cash                                      -0.13 CNY
+
+`
+
+func TestWriteJournal(t *testing.T) {
+	cases := map[string]string{
+		JournalBeancount: testBeancountJournal,
+		JournalLedger:    testLedgerJournal,
+	}
+
+	for format, want := range cases {
+		opts := JournalOptions{
+			Format:          format,
+			Currency:        "CNY",
+			ExpenseAccounts: map[util.RecordType]string{util.RecordTypeFood: "Expenses:Food:Dining"},
+		}
+
+		var buf bytes.Buffer
+		err := WriteJournal(&buf, testJournalEntries, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("%s journal = \n%s\nwant\n%s", format, got, want)
+		}
+
+		// 输入顺序不影响输出
+		reversed := make([]JournalEntry, len(testJournalEntries))
+		for i, entry := range testJournalEntries {
+			reversed[len(reversed)-1-i] = entry
+		}
+		var again bytes.Buffer
+		err = WriteJournal(&again, reversed, opts)
+		if err != nil || again.String() != buf.String() {
+			t.Errorf("%s journal depends on the input order", format)
+		}
+	}
+}
+
+func TestWriteJournalEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteJournal(&buf, nil, JournalOptions{Format: JournalBeancount, Currency: "USD"})
+	if err != nil || buf.String() != "option \"operating_currency\" \"USD\"\n\n" {
+		t.Errorf("empty journal = %q, %v", buf.String(), err)
+	}
+}
+
+func TestWriteJournalInvalidAmount(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []JournalEntry{{ID: 7, Date: time.Now(), Name: "午餐", Amount: "abc"}}
+	err := WriteJournal(&buf, entries, JournalOptions{Format: JournalLedger, Currency: "CNY"})
+	if err == nil || buf.Len() != 0 {
+		t.Errorf("journal = %q, err = %v, want an error and no output", buf.String(), err)
+	}
+}
+
+func TestJournalOptionsValidate(t *testing.T) {
+	cases := []struct {
+		format  string
+		account string
+		valid   bool
+	}{
+		{JournalBeancount, "Assets:Bank:CMB", true},
+		{JournalBeancount, "Expenses:Food-2024", true},
+		{JournalBeancount, "Assets", false},
+		{JournalBeancount, "Cash:Wallet", false},
+		{JournalBeancount, "Expenses:food", false},
+		{JournalBeancount, "Expenses:餐饮", false},
+		{JournalLedger, "Expenses:餐饮:午餐 外卖", true},
+		{JournalLedger, "Assets:Cash  Box", false},
+		{JournalLedger, "Assets:Cash\tBox", false},
+		{JournalLedger, "Assets:Cash;Box", false},
+		{JournalLedger, " Assets:Cash", false},
+		{JournalLedger, "Assets:Cash ", false},
+		{JournalLedger, "(Assets:Cash)", false},
+		{JournalLedger, "[Assets:Cash]", false},
+	}
+
+	for _, c := range cases {
+		opts := JournalOptions{
+			Format:         c.format,
+			IncomeAccounts: map[util.RecordType]string{util.RecordTypeGift: c.account},
+		}
+		err := opts.Validate()
+		if (err == nil) != c.valid || err != nil && !errors.Is(err, errInvalidAccountPath) {
+			t.Errorf("Validate(%s, %q) = %v, want valid %v", c.format, c.account, err, c.valid)
+		}
+	}
+}
+
+func TestLedgerPayee(t *testing.T) {
+	cases := map[string]string{
+		"午餐":        "午餐",
+		"午餐  ; 备注":  "午餐 ; 备注",
+		"午餐\t;备注":   "午餐 ;备注",
+		"  午餐  ":    "午餐",
+		"*午餐":       "() *午餐",
+		"!午餐":       "() !午餐",
+		"(外卖) 午餐":   "() (外卖) 午餐",
+		"午餐 (外卖)":   "午餐 (外卖)",
+		"午餐\n\n第二行": "午餐 第二行",
+	}
+
+	for in, want := range cases {
+		if got := ledgerPayee(in); got != want {
+			t.Errorf("ledgerPayee(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
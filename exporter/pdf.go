@@ -0,0 +1,328 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+/**
+ * A4纸张的尺寸, 单位为point
+ */
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+/**
+ * Helvetica中ASCII可见字符(32~126)的宽度, 单位为1/1000字号, 取自Adobe的AFM文件
+ */
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+/**
+ * 生成PDF文档, 只支持文本, 直线和矩形
+ * 1. 坐标以页面左上角为原点, y轴向下, 单位为point
+ * 2. ASCII字符使用Helvetica, 其他字符使用font并嵌入用到的字形子集
+ *    font为nil时使用Adobe标准的中文字体STSong-Light, 不嵌入文件, 由阅读器提供字形
+ */
+type PDF struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+	font    *Font
+	// 用到的字形编号和对应的字符, 用于生成字体子集和ToUnicode
+	glyphs map[uint16]rune
+}
+
+func NewPDF(font *Font) *PDF {
+	return &PDF{
+		font:   font,
+		glyphs: map[uint16]rune{},
+	}
+}
+
+func (pdf *PDF) AddPage() {
+	pdf.current = &bytes.Buffer{}
+	pdf.pages = append(pdf.pages, pdf.current)
+}
+
+func (pdf *PDF) PageCount() int {
+	return len(pdf.pages)
+}
+
+/**
+ * 切换到已有的页面, 用于在排版完成后添加页码等内容
+ */
+func (pdf *PDF) SetPage(i int) {
+	pdf.current = pdf.pages[i]
+}
+
+/**
+ * 文本的宽度, 非ASCII字符使用嵌入字体的宽度, 未嵌入字体时按全角计算
+ */
+func (pdf *PDF) TextWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		switch {
+		case r >= 32 && r <= 126:
+			width += helveticaWidths[r-32]
+		case pdf.font != nil:
+			width += pdf.font.GlyphWidth(pdf.font.GlyphIndex(r))
+		default:
+			width += 1000
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+/**
+ * 截断文本使其宽度不超过maxWidth
+ */
+func (pdf *PDF) TruncateText(s string, size float64, maxWidth float64) string {
+	if pdf.TextWidth(s, size) <= maxWidth {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.TextWidth(string(runes)+"...", size) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+/**
+ * 在(x, y)处绘制文本, y是文本基线的位置
+ */
+func (pdf *PDF) Text(x float64, y float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+
+	fmt.Fprintf(pdf.current, "BT %.2f %.2f Td ", x, PageHeight-y)
+	for _, run := range splitRuns(s) {
+		if run.ascii {
+			fmt.Fprintf(pdf.current, "/%s %.1f Tf (%s) Tj ", font, size, escapePDFString(run.text))
+		} else if pdf.font != nil {
+			fmt.Fprintf(pdf.current, "/F3 %.1f Tf <%s> Tj ", size, pdf.glyphHex(run.text))
+		} else {
+			fmt.Fprintf(pdf.current, "/F3 %.1f Tf <%s> Tj ", size, ucs2Hex(run.text))
+		}
+	}
+	pdf.current.WriteString("ET\n")
+}
+
+/**
+ * 右对齐绘制文本, x是文本右侧的位置
+ */
+func (pdf *PDF) TextRight(x float64, y float64, size float64, bold bool, s string) {
+	pdf.Text(x-pdf.TextWidth(s, size), y, size, bold, s)
+}
+
+func (pdf *PDF) Line(x1 float64, y1 float64, x2 float64, y2 float64, width float64) {
+	fmt.Fprintf(pdf.current, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+/**
+ * 填充矩形, (x, y)是左上角, gray为0~1的灰度, 0为黑色
+ */
+func (pdf *PDF) FillRect(x float64, y float64, w float64, h float64, gray float64) {
+	fmt.Fprintf(pdf.current, "q %.2f g %.2f %.2f %.2f %.2f re f Q\n", gray, x, PageHeight-y-h, w, h)
+}
+
+type textRun struct {
+	text  string
+	ascii bool
+}
+
+func splitRuns(s string) []textRun {
+	runs := []textRun{}
+	for _, r := range s {
+		ascii := r >= 32 && r <= 126
+		if len(runs) == 0 || runs[len(runs)-1].ascii != ascii {
+			runs = append(runs, textRun{ascii: ascii})
+		}
+		runs[len(runs)-1].text += string(r)
+	}
+	return runs
+}
+
+func escapePDFString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s)
+}
+
+/**
+ * UniGB-UCS2-H编码只支持基本多文种平面, 其他字符替换为问号
+ */
+func ucs2Hex(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF || utf16.IsSurrogate(r) || r < 32 {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+/**
+ * Identity-H编码下CID即字形编号, 同时记录用到的字形
+ */
+func (pdf *PDF) glyphHex(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		gid := pdf.font.GlyphIndex(r)
+		if gid != 0 {
+			pdf.glyphs[gid] = r
+		}
+		fmt.Fprintf(&b, "%04X", gid)
+	}
+	return b.String()
+}
+
+/**
+ * 嵌入字体子集的对象, 依次为Type0字体, CIDFontType2字体, FontDescriptor, 字体文件和ToUnicode
+ * first是第一个对象的编号
+ */
+func (pdf *PDF) embeddedFontObjects(first int) []string {
+	gids := make([]uint16, 0, len(pdf.glyphs))
+	for gid := range pdf.glyphs {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	// 子集字体的名称需要加上6个大写字母的前缀, 由用到的字形决定
+	hash := fnv.New32a()
+	widths := []string{}
+	toUnicode := []string{}
+	for _, gid := range gids {
+		fmt.Fprintf(hash, "%d,", gid)
+		widths = append(widths, fmt.Sprintf("%d [%d]", gid, pdf.font.GlyphWidth(gid)))
+		toUnicode = append(toUnicode, fmt.Sprintf("<%04X> <%s>", gid, utf16Hex(pdf.glyphs[gid])))
+	}
+	tag := make([]byte, 6)
+	sum := hash.Sum32()
+	for i := range tag {
+		tag[i] = byte('A' + sum%26)
+		sum /= 26
+	}
+	name := string(tag) + "+" + pdf.font.name
+
+	fontFile := pdf.font.Subset(gids)
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write(fontFile)
+	_ = zw.Close()
+
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// 每个bfchar块最多100项
+	for i := 0; i < len(toUnicode); i += 100 {
+		block := toUnicode[i:min(i+100, len(toUnicode))]
+		fmt.Fprintf(&cmap, "%d beginbfchar\n%s\nendbfchar\n", len(block), strings.Join(block, "\n"))
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+
+	font := pdf.font
+	return []string{
+		fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+			name, first+1, first+4),
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R "+
+			"/DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+			name, first+2, strings.Join(widths, " ")),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] "+
+			"/ItalicAngle %d /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			name, font.bbox[0], font.bbox[1], font.bbox[2], font.bbox[3],
+			font.italicAngle, font.ascent, font.descent, font.capHeight, first+3),
+		fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+			compressed.Len(), len(fontFile), compressed.String()),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", cmap.Len(), cmap.String()),
+	}
+}
+
+func utf16Hex(r rune) string {
+	var b strings.Builder
+	for _, unit := range utf16.Encode([]rune{r}) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	return b.String()
+}
+
+func (pdf *PDF) Write(w io.Writer) error {
+	var buf bytes.Buffer
+	offsets := []int{}
+
+	// 对象编号从1开始, 1为Catalog, 2为Pages, 之后是字体, 最后每页占用两个对象
+	object := func(content string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	fonts := []string{
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	if pdf.font != nil {
+		fonts = append(fonts, pdf.embeddedFontObjects(5)...)
+	} else {
+		fonts = append(fonts,
+			"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [6 0 R] >>",
+			"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light "+
+				"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 7 0 R /DW 1000 >>",
+			"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] "+
+				"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+		)
+	}
+	firstPage := 3 + len(fonts)
+
+	kids := []string{}
+	for i := range pdf.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+i*2))
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pdf.pages)))
+	for _, font := range fonts {
+		object(font)
+	}
+
+	for i, page := range pdf.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, firstPage+1+i*2,
+		))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		_, _ = zw.Write(page.Bytes())
+		_ = zw.Close()
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,233 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	pdfTrailerPattern = regexp.MustCompile(`trailer\n<< /Size (\d+) /Root 1 0 R >>\nstartxref\n(\d+)\n%%EOF\n$`)
+	pdfLengthPattern  = regexp.MustCompile(`/Length (\d+)`)
+)
+
+/**
+ * 按交叉引用表读取全部对象, 同时检查每个偏移都指向对应编号的对象
+ */
+func pdfObjects(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("header = %q", data[:min(len(data), 16)])
+	}
+	trailer := pdfTrailerPattern.FindSubmatch(data)
+	if trailer == nil {
+		t.Fatalf("trailer is missing: %q", data[max(0, len(data)-100):])
+	}
+	size, _ := strconv.Atoi(string(trailer[1]))
+	xref, _ := strconv.Atoi(string(trailer[2]))
+
+	table := string(data[xref:])
+	prefix := fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", size)
+	if !strings.HasPrefix(table, prefix) {
+		t.Fatalf("xref = %q, want prefix %q", table[:min(len(table), 60)], prefix)
+	}
+	table = table[len(prefix):]
+
+	objects := []string{}
+	for i := 1; i < size; i++ {
+		entry := table[(i-1)*20 : i*20]
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil || entry[10:] != " 00000 n \n" {
+			t.Fatalf("xref entry %d = %q", i, entry)
+		}
+		header := fmt.Sprintf("%d 0 obj\n", i)
+		if !bytes.HasPrefix(data[offset:], []byte(header)) {
+			t.Fatalf("object %d at offset %d = %q", i, offset, data[offset:min(len(data), offset+20)])
+		}
+		end := bytes.Index(data[offset:], []byte("\nendobj\n"))
+		objects = append(objects, string(data[offset+len(header):offset+end]))
+	}
+	return objects
+}
+
+/**
+ * 解压流对象的内容, 同时检查/Length和实际长度一致
+ */
+func pdfStream(t *testing.T, object string) []byte {
+	t.Helper()
+
+	start := strings.Index(object, "\nstream\n")
+	end := strings.LastIndex(object, "\nendstream")
+	length := pdfLengthPattern.FindStringSubmatch(object)
+	if start < 0 || end < 0 || length == nil {
+		t.Fatalf("not a stream object: %q", object[:min(len(object), 60)])
+	}
+	content := object[start+len("\nstream\n") : end]
+	if strconv.Itoa(len(content)) != length[1] {
+		t.Errorf("stream length = %d, /Length %s", len(content), length[1])
+	}
+	if !strings.Contains(object, "/FlateDecode") {
+		return []byte(content)
+	}
+
+	zr, err := zlib.NewReader(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestTextWidth(t *testing.T) {
+	font := testFont(t)
+	cases := []struct {
+		font *Font
+		text string
+		want float64
+	}{
+		{nil, "", 0},
+		{nil, "Hello", 22.78},
+		{nil, "A中", 16.67},
+		{font, "A中", 16.67},
+		{font, "文字", 15},
+		{font, "😀", 5},
+	}
+
+	for _, c := range cases {
+		got := NewPDF(c.font).TextWidth(c.text, 10)
+		if fmt.Sprintf("%.2f", got) != fmt.Sprintf("%.2f", c.want) {
+			t.Errorf("TextWidth(%q) = %v, want %v", c.text, got, c.want)
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	pdf := NewPDF(nil)
+	cases := []struct {
+		text     string
+		maxWidth float64
+		want     string
+	}{
+		{"Hello", 30, "Hello"},
+		{"Hello", 22.78, "Hello"},
+		{"Hello world", 30, "Hell..."},
+		{"中文名称", 30, "中文..."},
+		{"中文名称", 28, "中..."},
+		{"中文", 5, "..."},
+	}
+
+	for _, c := range cases {
+		if got := pdf.TruncateText(c.text, 10, c.maxWidth); got != c.want {
+			t.Errorf("TruncateText(%q, %v) = %q, want %q", c.text, c.maxWidth, got, c.want)
+		}
+	}
+}
+
+func TestPDFWriteStandardFont(t *testing.T) {
+	pdf := NewPDF(nil)
+	pdf.AddPage()
+	pdf.Text(50, 100, 12, true, `a(b)\c 中文😀`)
+	pdf.FillRect(10, 20, 30, 40, 0.9)
+	pdf.AddPage()
+	pdf.Line(0, 0, PageWidth, PageHeight, 0.5)
+	pdf.SetPage(0)
+	pdf.TextRight(545, 800, 9, false, "1/2")
+
+	var buf bytes.Buffer
+	err := pdf.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := pdfObjects(t, buf.Bytes())
+	if len(objects) != 11 {
+		t.Fatalf("objects = %d, want 11", len(objects))
+	}
+	if objects[1] != "<< /Type /Pages /Kids [8 0 R 10 0 R] /Count 2 >>" {
+		t.Errorf("pages = %q", objects[1])
+	}
+	if !strings.Contains(objects[4], "/STSong-Light /Encoding /UniGB-UCS2-H") {
+		t.Errorf("cjk font = %q", objects[4])
+	}
+
+	want := "BT 50.00 742.00 Td /F2 12.0 Tf (a\\(b\\)\\\\c ) Tj /F3 12.0 Tf <4E2D6587003F> Tj ET\n" +
+		"q 0.90 g 10.00 782.00 30.00 40.00 re f Q\n" +
+		"BT 532.49 42.00 Td /F1 9.0 Tf (1/2) Tj ET\n"
+	if got := string(pdfStream(t, objects[8])); got != want {
+		t.Errorf("page 1 = %q, want %q", got, want)
+	}
+	if got := string(pdfStream(t, objects[10])); got != "0.50 w 0.00 842.00 m 595.00 0.00 l S\n" {
+		t.Errorf("page 2 = %q", got)
+	}
+}
+
+func TestPDFWriteEmbeddedFont(t *testing.T) {
+	font := testFont(t)
+	pdf := NewPDF(font)
+	pdf.AddPage()
+	pdf.Text(0, 0, 10, false, "国 A 中中 é")
+
+	var buf bytes.Buffer
+	err := pdf.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := pdfObjects(t, buf.Bytes())
+	if len(objects) != 11 {
+		t.Fatalf("objects = %d, want 11", len(objects))
+	}
+
+	want := "BT 0.00 842.00 Td /F3 10.0 Tf <0003> Tj /F1 10.0 Tf ( A ) Tj /F3 10.0 Tf <00010001> Tj /F1 10.0 Tf ( ) Tj /F3 10.0 Tf <0000> Tj ET\n"
+	if got := string(pdfStream(t, objects[10])); got != want {
+		t.Errorf("page = %q, want %q", got, want)
+	}
+
+	name := regexp.MustCompile(`/BaseFont /([A-Z]{6}\+TestSans1) `).FindStringSubmatch(objects[4])
+	if name == nil {
+		t.Fatalf("type0 font = %q", objects[4])
+	}
+	for i, want := range []string{
+		"/DescendantFonts [6 0 R] /ToUnicode 9 0 R",
+		"/BaseFont /" + name[1] + " ",
+		"/FontName /" + name[1] + " /Flags 4 /FontBBox [-48 -195 976 878] /ItalicAngle -12 /Ascent 878 /Descent -195 /CapHeight 700",
+	} {
+		if !strings.Contains(objects[4+i], want) {
+			t.Errorf("object %d = %q, want %q", 5+i, objects[4+i], want)
+		}
+	}
+	if !strings.Contains(objects[5], "/W [1 [1000] 3 [750]]") {
+		t.Errorf("widths = %q", objects[5])
+	}
+
+	toUnicode := string(pdfStream(t, objects[8]))
+	if !strings.Contains(toUnicode, "2 beginbfchar\n<0001> <4E2D>\n<0003> <56FD>\nendbfchar") {
+		t.Errorf("to unicode = %q", toUnicode)
+	}
+
+	// 嵌入的子集只保留用到的字形和组合字形引用的字形
+	fontFile := pdfStream(t, objects[7])
+	if !strings.Contains(objects[7], fmt.Sprintf("/Length1 %d ", len(fontFile))) {
+		t.Errorf("font file = %q, want /Length1 %d", objects[7][:60], len(fontFile))
+	}
+	tables := fontTables(t, fontFile)
+	tables["cmap"] = font.tables["cmap"]
+	subset, err := ParseFont(writeFont(tables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for gid, data := range subset.glyphData {
+		if kept := len(data) > 0; kept != (gid != testGlyphWen) {
+			t.Errorf("glyph %d kept = %v", gid, kept)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/**
+ * 单元格的类型, 数字和日期以Excel的原生类型写入, 公式在打开文件时计算
+ */
+const (
+	cellText = iota
+	cellNumber
+	cellDate
+	cellFormula
+)
+
+/**
+ * 单元格样式在styles.xml中的序号
+ */
+const (
+	styleDefault = iota
+	styleDate
+	styleNumber
+	styleHeader
+)
+
+type Cell struct {
+	kind  int
+	value string
+	style int
+}
+
+func Text(s string) Cell {
+	return Cell{kind: cellText, value: s}
+}
+
+/**
+ * 以十进制字符串表示的数字, 如数据库numeric类型的值, 原样写入避免精度损失
+ */
+func Number(s string) Cell {
+	return Cell{kind: cellNumber, value: s, style: styleNumber}
+}
+
+func Integer(i int64) Cell {
+	return Cell{kind: cellNumber, value: fmt.Sprint(i)}
+}
+
+/**
+ * 日期写为Excel的序列号, 即距1899-12-30的天数
+ */
+func Date(t time.Time) Cell {
+	days := t.Sub(time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	return Cell{kind: cellDate, value: fmt.Sprint(int64(days)), style: styleDate}
+}
+
+/**
+ * 公式不包含开头的等号, 如SUM(B2:B10)
+ */
+func Formula(f string) Cell {
+	return Cell{kind: cellFormula, value: f, style: styleNumber}
+}
+
+func Header(s string) Cell {
+	return Cell{kind: cellText, value: s, style: styleHeader}
+}
+
+type Sheet struct {
+	name string
+	rows [][]Cell
+}
+
+func (sheet *Sheet) AddRow(cells ...Cell) {
+	sheet.rows = append(sheet.rows, cells)
+}
+
+/**
+ * 下一行的行号, 从1开始, 用于在公式中引用
+ */
+func (sheet *Sheet) NextRow() int {
+	return len(sheet.rows) + 1
+}
+
+type Workbook struct {
+	sheets []*Sheet
+}
+
+func NewWorkbook() *Workbook {
+	return &Workbook{}
+}
+
+/**
+ * 工作表名称最长31个字符, 不能包含[]:*?/\
+ */
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	sheet := &Sheet{name: name}
+	wb.sheets = append(wb.sheets, sheet)
+	return sheet
+}
+
+/**
+ * 把从0开始的列号转换为列名, 如0为A, 27为AB
+ */
+func ColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func CellRef(column int, row int) string {
+	return fmt.Sprintf("%s%d", ColumnName(column), row)
+}
+
+func (wb *Workbook) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	sheets := []string{}
+	rels := []string{}
+	overrides := []string{}
+	for i, sheet := range wb.sheets {
+		sheets = append(sheets, fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeXML(sheet.name), i+1, i+1))
+		rels = append(rels, fmt.Sprintf(
+			`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`,
+			i+1, i+1,
+		))
+		overrides = append(overrides, fmt.Sprintf(
+			`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`,
+			i+1,
+		))
+	}
+	rels = append(rels, fmt.Sprintf(
+		`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`,
+		len(wb.sheets)+1,
+	))
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			strings.Join(overrides, "") + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + strings.Join(sheets, "") + `</sheets><calcPr fullCalcOnLoad="1"/></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			strings.Join(rels, "") + `</Relationships>`},
+		{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/></numFmts>` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="4">` +
+			`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+			`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+			`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+			`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+			`</cellXfs></styleSheet>`},
+	}
+
+	for _, file := range files {
+		err := writeZipFile(zw, file.name, file.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range wb.sheets {
+		fw, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+
+		err = sheet.writeTo(fw)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (sheet *Sheet) writeTo(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	for i, row := range sheet.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			ref := CellRef(j, i+1)
+			switch cell.kind {
+			case cellText:
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, cell.style, escapeXML(cell.value))
+			case cellFormula:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><f>%s</f></c>`, ref, cell.style, escapeXML(cell.value))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, cell.value)
+			}
+		}
+		b.WriteString(`</row>`)
+
+		// 按行分批写入, 避免大的工作表占用过多内存
+		if b.Len() > 64<<10 {
+			_, err := io.WriteString(w, b.String())
+			if err != nil {
+				return err
+			}
+			b.Reset()
+		}
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZipFile(zw *zip.Writer, name string, content string) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(fw, content)
+	return err
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
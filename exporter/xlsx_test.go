@@ -0,0 +1,224 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+type testWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type testWorksheet struct {
+	Rows []struct {
+		Ref   string `xml:"r,attr"`
+		Cells []struct {
+			Ref     string `xml:"r,attr"`
+			Style   string `xml:"s,attr"`
+			Type    string `xml:"t,attr"`
+			Value   string `xml:"v"`
+			Formula string `xml:"f"`
+			Text    string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+/**
+ * 写出工作簿并检查每个部件都是合法的XML, 返回部件名到内容的映射
+ */
+func writeTestWorkbook(t *testing.T, wb *Workbook) map[string][]byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := wb.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string][]byte{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			_, err = decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s is not valid xml: %v", file.Name, err)
+			}
+		}
+		parts[file.Name] = data
+	}
+
+	return parts
+}
+
+func TestColumnName(t *testing.T) {
+	cases := map[int]string{
+		0:     "A",
+		1:     "B",
+		25:    "Z",
+		26:    "AA",
+		27:    "AB",
+		51:    "AZ",
+		52:    "BA",
+		701:   "ZZ",
+		702:   "AAA",
+		16383: "XFD",
+	}
+
+	for i, want := range cases {
+		if got := ColumnName(i); got != want {
+			t.Errorf("ColumnName(%d) = %q, want %q", i, got, want)
+		}
+	}
+
+	if got := CellRef(27, 10); got != "AB10" {
+		t.Errorf("CellRef(27, 10) = %q, want AB10", got)
+	}
+}
+
+func TestDateCell(t *testing.T) {
+	cases := map[string]string{
+		"1899-12-31": "1",
+		"1900-03-01": "61",
+		"2024-03-05": "45356",
+		"2024-12-31": "45657",
+	}
+
+	for in, want := range cases {
+		d, _ := time.Parse(time.DateOnly, in)
+		if got := Date(d); got.value != want || got.kind != cellDate || got.style != styleDate {
+			t.Errorf("Date(%s) = %+v, want serial %s", in, got, want)
+		}
+	}
+}
+
+func TestWorkbookWrite(t *testing.T) {
+	wb := NewWorkbook()
+	records := wb.AddSheet("记录 & 汇总")
+	records.AddRow(Header("名称"), Header("金额"), Header("日期"), Header("数量"))
+	records.AddRow(Text("<咖啡> & \"蛋糕\"\n下午茶"), Number("-12.50"), Date(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)), Integer(3))
+	records.AddRow(Text("  "), Number("0.12345678"), Date(time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)), Integer(-1))
+	records.AddRow(Text("合计"), Formula(fmt.Sprintf("SUM(%s:%s)", CellRef(1, 2), CellRef(1, records.NextRow()-1))))
+	wb.AddSheet("Empty")
+
+	parts := writeTestWorkbook(t, wb)
+	for _, name := range []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"xl/workbook.xml",
+		"xl/_rels/workbook.xml.rels",
+		"xl/styles.xml",
+		"xl/worksheets/sheet1.xml",
+		"xl/worksheets/sheet2.xml",
+	} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	var workbook testWorkbook
+	err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workbook.Sheets) != 2 || workbook.Sheets[0].Name != "记录 & 汇总" || workbook.Sheets[1].ID != "rId2" {
+		t.Errorf("sheets = %+v", workbook.Sheets)
+	}
+	for _, want := range []string{`Target="worksheets/sheet2.xml"`, `Id="rId3"`, `Target="styles.xml"`} {
+		if !bytes.Contains(parts["xl/_rels/workbook.xml.rels"], []byte(want)) {
+			t.Errorf("workbook rels do not contain %s", want)
+		}
+	}
+
+	var sheet testWorksheet
+	err = xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != 4 {
+		t.Fatalf("rows = %d, want 4", len(sheet.Rows))
+	}
+
+	row := sheet.Rows[1]
+	if row.Ref != "2" || len(row.Cells) != 4 {
+		t.Fatalf("row 2 = %+v", row)
+	}
+	if c := row.Cells[0]; c.Ref != "A2" || c.Type != "inlineStr" || c.Text != "<咖啡> & \"蛋糕\"\n下午茶" {
+		t.Errorf("text cell = %+v", c)
+	}
+	if c := row.Cells[1]; c.Ref != "B2" || c.Type != "" || c.Value != "-12.50" || c.Style != fmt.Sprint(styleNumber) {
+		t.Errorf("number cell = %+v", c)
+	}
+	if c := row.Cells[2]; c.Ref != "C2" || c.Value != "45356" || c.Style != fmt.Sprint(styleDate) {
+		t.Errorf("date cell = %+v", c)
+	}
+	if c := row.Cells[3]; c.Ref != "D2" || c.Value != "3" || c.Style != fmt.Sprint(styleDefault) {
+		t.Errorf("integer cell = %+v", c)
+	}
+	if c := sheet.Rows[0].Cells[0]; c.Style != fmt.Sprint(styleHeader) || c.Text != "名称" {
+		t.Errorf("header cell = %+v", c)
+	}
+	if c := sheet.Rows[2].Cells[0]; c.Text != "  " {
+		t.Errorf("whitespace cell = %+v", c)
+	}
+	if c := sheet.Rows[3].Cells[1]; c.Ref != "B4" || c.Formula != "SUM(B2:B3)" || c.Value != "" {
+		t.Errorf("formula cell = %+v", c)
+	}
+
+	var empty testWorksheet
+	err = xml.Unmarshal(parts["xl/worksheets/sheet2.xml"], &empty)
+	if err != nil || len(empty.Rows) != 0 {
+		t.Errorf("empty sheet = %+v, %v", empty, err)
+	}
+}
+
+/**
+ * 大量行的工作表写出后仍是合法的XML, 行号连续
+ */
+func TestWorkbookWriteLargeSheet(t *testing.T) {
+	wb := NewWorkbook()
+	sheet := wb.AddSheet("Records")
+	for i := 0; i < 5000; i++ {
+		sheet.AddRow(Text(fmt.Sprintf("记录%d", i)), Number(fmt.Sprintf("%d.01", i)))
+	}
+
+	parts := writeTestWorkbook(t, wb)
+
+	var parsed testWorksheet
+	err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Rows) != 5000 {
+		t.Fatalf("rows = %d, want 5000", len(parsed.Rows))
+	}
+	last := parsed.Rows[4999]
+	if last.Ref != "5000" || last.Cells[0].Text != "记录4999" || last.Cells[1].Value != "4999.01" {
+		t.Errorf("last row = %+v", last)
+	}
+}
@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+const DefaultDateFormat = "YYYY-MM-DD"
+
+var (
+	errTooManyRows = errors.New("too many rows")
+)
+
+/**
+ * 记录字段对应的CSV列名, Category为空时所有记录使用默认类型
+ */
+type CSVMapping struct {
+	Name     string
+	Date     string
+	Amount   string
+	Category string
+}
+
+type CSVOptions struct {
+	Mapping CSVMapping
+	// 表头之前需要跳过的行数
+	SkipRows  int
+	Delimiter rune
+	// 为空时使用DefaultDateFormat
+	DateFormat   string
+	DecimalComma bool
+	// 文件中支出为负数时(如银行流水)把金额取反
+	NegateAmount bool
+	// 分类名称到记录类型的映射, 未映射的分类依次尝试类型名称和类型编号
+	Categories        map[string]util.RecordType
+	DefaultRecordType util.RecordType
+	MaxRows           int
+}
+
+/**
+ * 解析带表头的CSV文件
+ * 表头缺少映射的列时返回错误, 单行的错误记录在Row.Err中
+ */
+func ParseCSV(r io.Reader, opts CSVOptions) ([]Row, error) {
+	reader := csv.NewReader(skipBOM(r))
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	dateFormat := opts.DateFormat
+	if dateFormat == "" {
+		dateFormat = DefaultDateFormat
+	}
+
+	for i := 0; i < opts.SkipRows; i++ {
+		_, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+
+	columns := map[string]int{}
+	for field, name := range map[string]string{
+		"name":     opts.Mapping.Name,
+		"date":     opts.Mapping.Date,
+		"amount":   opts.Mapping.Amount,
+		"category": opts.Mapping.Category,
+	} {
+		if name == "" {
+			columns[field] = -1
+			continue
+		}
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found in header", name)
+		}
+		columns[field] = i
+	}
+
+	rows := []Row{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+		if opts.MaxRows > 0 && len(rows) == opts.MaxRows {
+			return nil, errTooManyRows
+		}
+
+		value := func(field string) string {
+			i := columns[field]
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := Row{Line: line, Name: TruncateName(value("name"))}
+		row.Date, row.Err = ParseDate(value("date"), dateFormat)
+		if row.Err == nil {
+			row.Amount, row.Err = NormalizeAmount(value("amount"), opts.DecimalComma)
+		}
+		if row.Err == nil && opts.NegateAmount {
+			row.Amount = negateAmount(row.Amount)
+		}
+		if row.Err == nil {
+			row.RecordType, row.Err = opts.recordType(value("category"))
+		}
+		if row.Err == nil && row.Name == "" {
+			row.Err = fmt.Errorf("%w: %s", errMissingValue, opts.Mapping.Name)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func (opts CSVOptions) recordType(category string) (util.RecordType, error) {
+	if category == "" {
+		if opts.DefaultRecordType == 0 {
+			return 0, errUnknownCategory
+		}
+		return opts.DefaultRecordType, nil
+	}
+
+	if recordType, ok := opts.Categories[category]; ok {
+		return recordType, nil
+	}
+	if recordType := util.ParseRecordType(category); recordType != 0 {
+		return recordType, nil
+	}
+	if n, err := strconv.Atoi(category); err == nil && util.RecordTypeName(util.RecordType(n)) != "" {
+		return util.RecordType(n), nil
+	}
+	if opts.DefaultRecordType != 0 {
+		return opts.DefaultRecordType, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s", errUnknownCategory, category)
+}
+
+func negateAmount(amount string) string {
+	if strings.HasPrefix(amount, "-") {
+		return amount[1:]
+	}
+	if amount == "0" {
+		return amount
+	}
+	return "-" + amount
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ * Excel保存的UTF-8文件带有BOM, 读取时去掉
+ */
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	bom, err := br.Peek(3)
+	if err == nil && string(bom) == "\xef\xbb\xbf" {
+		_, _ = br.Discard(3)
+	}
+	return br
+}
@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+var testCSVMapping = CSVMapping{Name: "名称", Date: "日期", Amount: "金额", Category: "分类"}
+
+/**
+ * 只比较解析结果中有意义的字段, Err只比较是否包含want中的错误
+ */
+func checkRows(t *testing.T, got []Row, want []Row) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("rows = %+v, want %d rows", got, len(want))
+	}
+	for i := range want {
+		if want[i].Err != nil {
+			if !errors.Is(got[i].Err, want[i].Err) || got[i].Line != want[i].Line {
+				t.Errorf("row %d = %+v, want line %d with error %v", i, got[i], want[i].Line, want[i].Err)
+			}
+			continue
+		}
+		if got[i].Err != nil ||
+			got[i].Line != want[i].Line ||
+			got[i].Name != want[i].Name ||
+			got[i].RecordType != want[i].RecordType ||
+			!got[i].Date.Equal(want[i].Date) ||
+			got[i].Amount != want[i].Amount ||
+			got[i].ExternalId != want[i].ExternalId {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func TestParseCSV(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		opts CSVOptions
+		want []Row
+	}{
+		{
+			name: "default options",
+			data: "名称,日期,金额,分类\n午餐,2024-03-05,25.50,food\n地铁,2024-03-06,4,3\n",
+			opts: CSVOptions{Mapping: testCSVMapping},
+			want: []Row{
+				{Line: 2, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "25.5"},
+				{Line: 3, Name: "地铁", RecordType: util.RecordTypeCommuting, Date: date("2024-03-06"), Amount: "4"},
+			},
+		},
+		{
+			name: "bom, skipped rows and blank lines",
+			data: "\xef\xbb\xbf导出时间 2024-03-31\n\n名称,日期,金额,分类\n\n , , , \n午餐,2024-03-05,25.50,food\n",
+			opts: CSVOptions{Mapping: testCSVMapping, SkipRows: 1},
+			want: []Row{
+				{Line: 6, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "25.5"},
+			},
+		},
+		{
+			name: "semicolon delimiter, decimal comma and slash dates",
+			data: "名称;日期;金额;分类\n午餐;2024/03/05;\"1.234,50\";food\n",
+			opts: CSVOptions{Mapping: testCSVMapping, Delimiter: ';', DateFormat: "YYYY/MM/DD", DecimalComma: true},
+			want: []Row{
+				{Line: 2, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "1234.5"},
+			},
+		},
+		{
+			name: "negate amount",
+			data: "名称,日期,金额,分类\n午餐,2024-03-05,-25.50,food\n工资,2024-03-06,+5,gift\n零,2024-03-07,0,gift\n",
+			opts: CSVOptions{Mapping: testCSVMapping, NegateAmount: true},
+			want: []Row{
+				{Line: 2, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "25.5"},
+				{Line: 3, Name: "工资", RecordType: util.RecordTypeGift, Date: date("2024-03-06"), Amount: "-5"},
+				{Line: 4, Name: "零", RecordType: util.RecordTypeGift, Date: date("2024-03-07"), Amount: "0"},
+			},
+		},
+		{
+			name: "category mapping and default type",
+			data: "名称,日期,金额,分类\n午餐,2024-03-05,1,餐饮\n书,2024-03-05,2,其他\n",
+			opts: CSVOptions{
+				Mapping:           testCSVMapping,
+				Categories:        map[string]util.RecordType{"餐饮": util.RecordTypeFood},
+				DefaultRecordType: util.RecordTypeStudying,
+			},
+			want: []Row{
+				{Line: 2, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "1"},
+				{Line: 3, Name: "书", RecordType: util.RecordTypeStudying, Date: date("2024-03-05"), Amount: "2"},
+			},
+		},
+		{
+			name: "no category column",
+			data: "名称,日期,金额\n午餐,2024-03-05,1\n",
+			opts: CSVOptions{Mapping: CSVMapping{Name: "名称", Date: "日期", Amount: "金额"}, DefaultRecordType: util.RecordTypeFood},
+			want: []Row{
+				{Line: 2, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "1"},
+			},
+		},
+		{
+			name: "per row errors",
+			data: "名称,日期,金额,分类\n" +
+				"坏日期,2024-13-01,1,food\n" +
+				"坏金额,2024-03-05,abc,food\n" +
+				"小数过长,2024-03-05,0.123456789,food\n" +
+				"坏分类,2024-03-05,1,其他\n" +
+				",2024-03-05,1,food\n" +
+				"缺列\n" +
+				"午餐,2024-03-05,1,food\n",
+			opts: CSVOptions{Mapping: testCSVMapping},
+			want: []Row{
+				{Line: 2, Err: errInvalidDate},
+				{Line: 3, Err: errInvalidAmount},
+				{Line: 4, Err: errInvalidAmount},
+				{Line: 5, Err: errUnknownCategory},
+				{Line: 6, Err: errMissingValue},
+				{Line: 7, Err: errInvalidDate},
+				{Line: 8, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "1"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rows, err := ParseCSV(strings.NewReader(c.data), c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRows(t, rows, c.want)
+		})
+	}
+}
+
+func TestParseCSVMissingColumn(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("名称,日期\n午餐,2024-03-05\n"), CSVOptions{Mapping: testCSVMapping})
+	if err == nil || !strings.Contains(err.Error(), "金额") {
+		t.Errorf("err = %v, want a missing column error", err)
+	}
+}
+
+func TestParseCSVTooManyRows(t *testing.T) {
+	data := "名称,日期,金额,分类\n午餐,2024-03-05,1,food\n晚餐,2024-03-05,2,food\n"
+
+	_, err := ParseCSV(strings.NewReader(data), CSVOptions{Mapping: testCSVMapping, MaxRows: 1})
+	if !errors.Is(err, errTooManyRows) {
+		t.Errorf("err = %v, want %v", err, errTooManyRows)
+	}
+
+	rows, err := ParseCSV(strings.NewReader(data), CSVOptions{Mapping: testCSVMapping, MaxRows: 2})
+	if err != nil || len(rows) != 2 {
+		t.Errorf("rows = %+v, err = %v, want 2 rows", rows, err)
+	}
+}
@@ -0,0 +1,186 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/timelyrain/star-account/util"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+/**
+ * 与createRecord的校验规则保持一致
+ */
+const maxNameLength = 15
+
+/**
+ * 金额最多的小数位数, 超过时视为无效的金额而不是舍入
+ */
+const maxAmountScale = 8
+
+var (
+	errInvalidAmount   = errors.New("invalid amount")
+	errInvalidDate     = errors.New("invalid date")
+	errUnknownCategory = errors.New("unknown category")
+	errMissingValue    = errors.New("missing value")
+)
+
+/**
+ * 从外部文件中解析出的一条记录
+ * Line是记录在文件中的行号, 从1开始; Err不为空表示该行解析失败
+ */
+type Row struct {
+	Line       int
+	Name       string
+	RecordType util.RecordType
+	Date       time.Time
+	Amount     string
+	// 来源平台的交易号, 用于重复导入时去重
+	ExternalId string
+	Err        error
+}
+
+/**
+ * 第三方平台导出的账单的导入选项
+ */
+type StatementOptions struct {
+	// 覆盖默认的分类映射
+	Categories map[string]util.RecordType
+	// 无法识别的分类使用的类型, 为0时使用购物
+	DefaultRecordType util.RecordType
+	MaxRows           int
+	// QIF文件的日期顺序和小数点, 其他格式的日期和金额格式是固定的
+	DateOrder    string
+	DecimalComma bool
+}
+
+func (opts StatementOptions) recordType(category string) util.RecordType {
+	if recordType, ok := opts.Categories[category]; ok {
+		return recordType
+	}
+	if recordType, ok := walletCategories[category]; ok {
+		return recordType
+	}
+	if recordType := util.ParseRecordType(strings.ToLower(category)); recordType != 0 {
+		return recordType
+	}
+	if opts.DefaultRecordType != 0 {
+		return opts.DefaultRecordType
+	}
+	return util.RecordTypeShopping
+}
+
+/**
+ * 国内平台导出的CSV文件常用GBK编码, 不是合法的UTF-8时按GBK解码
+ */
+func decodeText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+
+	decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+/**
+ * 把金额规范化为数据库numeric可以接受的格式
+ * 1. 去掉货币符号, 空白和千分位分隔符
+ * 2. decimalComma为true时以逗号作为小数点, 否则以点作为小数点
+ * 3. 括号包围的金额视为负数
+ * 4. 小数位数超过maxAmountScale时返回errInvalidAmount
+ */
+func NormalizeAmount(s string, decimalComma bool) (string, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	thousands, decimal := ",", "."
+	if decimalComma {
+		thousands, decimal = ".", ","
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '+', r == '-':
+			b.WriteRune(r)
+		case string(r) == decimal:
+			b.WriteByte('.')
+		case string(r) == thousands, r == '\'', r == ' ', r == '\u00a0':
+		case strings.ContainsRune("¥￥$€£", r):
+		default:
+			return "", errInvalidAmount
+		}
+	}
+
+	amount, ok := new(big.Rat).SetString(b.String())
+	if !ok || b.Len() == 0 {
+		return "", errInvalidAmount
+	}
+	if i := strings.IndexByte(b.String(), '.'); i >= 0 && b.Len()-i-1 > maxAmountScale {
+		return "", errInvalidAmount
+	}
+	if negative {
+		amount.Neg(amount)
+	}
+
+	return FormatAmount(amount), nil
+}
+
+/**
+ * 以最少的小数位数精确表示金额, 不做舍入
+ * 金额都由NormalizeAmount解析的金额加减得到, 小数位数不超过maxAmountScale
+ */
+func FormatAmount(amount *big.Rat) string {
+	for prec := 0; prec < maxAmountScale; prec++ {
+		s := amount.FloatString(prec)
+		if exact, _ := new(big.Rat).SetString(s); exact.Cmp(amount) == 0 {
+			return s
+		}
+	}
+	return amount.FloatString(maxAmountScale)
+}
+
+/**
+ * 记录名称超过长度限制时截断
+ */
+func TruncateName(name string) string {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) <= maxNameLength {
+		return name
+	}
+	return string([]rune(name)[:maxNameLength])
+}
+
+/**
+ * 日期格式使用YYYY, MM, DD, HH, mm, ss占位, 例如YYYY-MM-DD, DD/MM/YYYY
+ * 只保留日期部分
+ */
+func ParseDate(value string, format string) (time.Time, error) {
+	layout := strings.NewReplacer(
+		"YYYY", "2006",
+		"MM", "01",
+		"DD", "02",
+		"HH", "15",
+		"mm", "04",
+		"ss", "05",
+	).Replace(format)
+
+	t, err := time.Parse(layout, strings.TrimSpace(value))
+	if err != nil {
+		return t, errInvalidDate
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestNormalizeAmount(t *testing.T) {
+	cases := []struct {
+		in           string
+		decimalComma bool
+		want         string
+		err          error
+	}{
+		{"12.50", false, "12.5", nil},
+		{" 1,234.56 ", false, "1234.56", nil},
+		{"¥1,000", false, "1000", nil},
+		{"￥ 8.00", false, "8", nil},
+		{"$-3.10", false, "-3.1", nil},
+		{"+5", false, "5", nil},
+		{"-0", false, "0", nil},
+		{"0.00", false, "0", nil},
+		{"(12.50)", false, "-12.5", nil},
+		{"1'234.5", false, "1234.5", nil},
+		{"1 234", false, "1234", nil},
+		{"1.234,56", true, "1234.56", nil},
+		{"€ 12,5", true, "12.5", nil},
+		{"(1.000,00)", true, "-1000", nil},
+		{"0.12345678", false, "0.12345678", nil},
+		{"0.123456789", false, "", errInvalidAmount},
+		{"1,123456789", true, "", errInvalidAmount},
+		{"", false, "", errInvalidAmount},
+		{"¥", false, "", errInvalidAmount},
+		{"12abc", false, "", errInvalidAmount},
+		{"1.2.3", false, "", errInvalidAmount},
+		{"--5", false, "", errInvalidAmount},
+		{"5-", false, "", errInvalidAmount},
+	}
+
+	for _, c := range cases {
+		got, err := NormalizeAmount(c.in, c.decimalComma)
+		if !errors.Is(err, c.err) || got != c.want {
+			t.Errorf("NormalizeAmount(%q, %v) = %q, %v, want %q, %v", c.in, c.decimalComma, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	cases := map[string]string{
+		"0":          "0",
+		"-0":         "0",
+		"100":        "100",
+		"12.50":      "12.5",
+		"-0.01":      "-0.01",
+		"0.12345678": "0.12345678",
+		"1/3":        "0.33333333",
+	}
+
+	for in, want := range cases {
+		amount, _ := new(big.Rat).SetString(in)
+		if got := FormatAmount(amount); got != want {
+			t.Errorf("FormatAmount(%s) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTruncateName(t *testing.T) {
+	cases := map[string]string{
+		"  午餐  ":           "午餐",
+		"0123456789abcde":  "0123456789abcde",
+		"0123456789abcdef": "0123456789abcde",
+		"一二三四五六七八九十一二三四五六七": "一二三四五六七八九十一二三四五",
+	}
+
+	for in, want := range cases {
+		if got := TruncateName(in); got != want {
+			t.Errorf("TruncateName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	cases := []struct {
+		value  string
+		format string
+		want   string
+		err    error
+	}{
+		{"2024-03-05", "YYYY-MM-DD", "2024-03-05", nil},
+		{" 2024-03-05 ", "YYYY-MM-DD", "2024-03-05", nil},
+		{"2024/03/05", "YYYY/MM/DD", "2024-03-05", nil},
+		{"05/03/2024", "DD/MM/YYYY", "2024-03-05", nil},
+		{"03/05/2024", "MM/DD/YYYY", "2024-03-05", nil},
+		{"20240305", "YYYYMMDD", "2024-03-05", nil},
+		{"2024-03-05 23:59:59", "YYYY-MM-DD HH:mm:ss", "2024-03-05", nil},
+		{"2024-02-30", "YYYY-MM-DD", "", errInvalidDate},
+		{"2024/03/05", "YYYY-MM-DD", "", errInvalidDate},
+		{"2024-03-05", "YYYY-MM-DD HH:mm:ss", "", errInvalidDate},
+		{"", "YYYY-MM-DD", "", errInvalidDate},
+	}
+
+	for _, c := range cases {
+		got, err := ParseDate(c.value, c.format)
+		if !errors.Is(err, c.err) {
+			t.Errorf("ParseDate(%q, %q): err = %v, want %v", c.value, c.format, err, c.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got.Format(time.DateOnly) != c.want || got.Location() != time.UTC || !got.Equal(got.Truncate(24*time.Hour)) {
+			t.Errorf("ParseDate(%q, %q) = %v, want %s", c.value, c.format, got, c.want)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	cases := map[string]string{
+		"\xef\xbb\xbf日期,金额": "日期,金额",
+		"日期,金额":             "日期,金额",
+		// GBK编码的"日期,金额"
+		"\xc8\xd5\xc6\xda,\xbd\xf0\xb6\xee": "日期,金额",
+	}
+
+	for in, want := range cases {
+		got, err := decodeText([]byte(in))
+		if err != nil || got != want {
+			t.Errorf("decodeText(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var (
+	errNoOFXTransactions = errors.New("no transactions found in ofx file")
+
+	// OFX 1.x是SGML格式, 叶子元素没有结束标签, 只取开始标签之后到下一个标签之前的文本
+	ofxElementPattern = regexp.MustCompile(`<([A-Z0-9.]+)>([^<\r\n]*)`)
+
+	// 两种格式的文本中&, <和>都以实体表示
+	ofxEntityReplacer = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ")
+)
+
+/**
+ * OFX 1.x的文件头声明CHARSET:1252时不是UTF-8
+ */
+func decodeOFX(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+/**
+ * 日期格式为YYYYMMDD, 后面可能带有时间和时区, 只取日期部分
+ */
+func parseOFXDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, errInvalidDate
+	}
+
+	t, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return t, errInvalidDate
+	}
+	return t, nil
+}
+
+/**
+ * 解析OFX和QFX文件中的交易, 兼容SGML格式的OFX 1.x和XML格式的OFX 2.x
+ * 1. TRNAMT以收入为正, 支出为负, 导入时取反
+ * 2. 以收款方(NAME)作为记录名称, 没有收款方时使用备注
+ * 3. 以银行账号和FITID作为交易号, 同一账号内FITID唯一
+ * 4. OFX没有分类, 所有记录使用默认类型
+ */
+func ParseOFX(data []byte, opts StatementOptions) ([]Row, error) {
+	parts := strings.Split(decodeOFX(data), "<STMTTRN>")
+	line := strings.Count(parts[0], "\n") + 1
+	account := lastOFXElement(parts[0], "ACCTID")
+
+	res := []Row{}
+	for _, part := range parts[1:] {
+		if opts.MaxRows > 0 && len(res) == opts.MaxRows {
+			return nil, errTooManyRows
+		}
+
+		block, rest, _ := strings.Cut(part, "</STMTTRN>")
+		values := map[string]string{}
+		for _, match := range ofxElementPattern.FindAllStringSubmatch(block, -1) {
+			value := strings.TrimSpace(ofxEntityReplacer.Replace(match[2]))
+			if _, ok := values[match[1]]; !ok && value != "" {
+				values[match[1]] = value
+			}
+		}
+		res = append(res, ofxRow(values, account, line, opts))
+
+		line += strings.Count(part, "\n")
+		// 一个文件可能包含多个账户的对账单
+		if next := lastOFXElement(rest, "ACCTID"); next != "" {
+			account = next
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, errNoOFXTransactions
+	}
+
+	return res, nil
+}
+
+func lastOFXElement(text string, tag string) string {
+	value := ""
+	for _, match := range ofxElementPattern.FindAllStringSubmatch(text, -1) {
+		if match[1] == tag {
+			value = strings.TrimSpace(ofxEntityReplacer.Replace(match[2]))
+		}
+	}
+	return value
+}
+
+func ofxRow(values map[string]string, account string, line int, opts StatementOptions) Row {
+	row := Row{
+		Line:       line,
+		Name:       TruncateName(values["NAME"]),
+		RecordType: opts.recordType(""),
+	}
+	if row.Name == "" {
+		row.Name = TruncateName(values["MEMO"])
+	}
+
+	if fitId := values["FITID"]; fitId != "" {
+		row.ExternalId = "ofx:" + account + ":" + fitId
+	}
+
+	row.Date, row.Err = parseOFXDate(values["DTPOSTED"])
+	if row.Err == nil {
+		// 部分银行导出的金额以逗号作为小数点
+		amount := values["TRNAMT"]
+		row.Amount, row.Err = NormalizeAmount(amount, strings.Contains(amount, ",") && !strings.Contains(amount, "."))
+	}
+	if row.Err == nil {
+		row.Amount = negateAmount(row.Amount)
+	}
+
+	return row
+}
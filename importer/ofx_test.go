@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+/**
+ * SGML格式的OFX 1.x以Windows-1252编码, 叶子元素没有结束标签, 包含银行账户和信用卡两个对账单
+ * XML格式的OFX 2.x有结束标签, 其中一笔交易的所有元素写在同一行
+ */
+func TestParseOFX(t *testing.T) {
+	cases := []struct {
+		file string
+		want []Row
+	}{
+		{
+			file: "bank_sgml.ofx",
+			want: []Row{
+				{Line: 39, Name: "Café Central", RecordType: util.RecordTypeShopping, Date: date("2024-03-05"), Amount: "12.5", ExternalId: "ofx:111222333:T0001"},
+				{Line: 47, Name: "Salary March", RecordType: util.RecordTypeShopping, Date: date("2024-03-06"), Amount: "-1500", ExternalId: "ofx:111222333:T0002"},
+				{Line: 55, Err: errInvalidDate},
+				{Line: 85, Name: "AT&T Mobile", RecordType: util.RecordTypeShopping, Date: date("2024-03-10"), Amount: "40", ExternalId: "ofx:444555666:T0001"},
+			},
+		},
+		{
+			file: "bank_xml.ofx",
+			want: []Row{
+				{Line: 27, Name: "星巴克", RecordType: util.RecordTypeShopping, Date: date("2024-03-05"), Amount: "32", ExternalId: "ofx:6225880000001234:202403050001"},
+				{Line: 35, Name: "工资", RecordType: util.RecordTypeShopping, Date: date("2024-03-06"), Amount: "-8000", ExternalId: "ofx:6225880000001234:202403060001"},
+				{Line: 42, Name: "AT&T <Mobile>", RecordType: util.RecordTypeShopping, Date: date("2024-03-07"), Amount: "8.2", ExternalId: "ofx:6225880000001234:202403070001"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			rows, err := ParseOFX(readTestdata(t, c.file), StatementOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRows(t, rows, c.want)
+		})
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	_, err := ParseOFX([]byte("<OFX><BANKMSGSRSV1></BANKMSGSRSV1></OFX>"), StatementOptions{})
+	if !errors.Is(err, errNoOFXTransactions) {
+		t.Errorf("err = %v, want %v", err, errNoOFXTransactions)
+	}
+
+	_, err = ParseOFX(readTestdata(t, "bank_sgml.ofx"), StatementOptions{MaxRows: 3})
+	if !errors.Is(err, errTooManyRows) {
+		t.Errorf("err = %v, want %v", err, errTooManyRows)
+	}
+}
+
+func TestParseOFXDate(t *testing.T) {
+	cases := map[string]string{
+		"20240305":                   "2024-03-05",
+		"20240305120000":             "2024-03-05",
+		"20240305120000.000[-5:EST]": "2024-03-05",
+		"20240230":                   "",
+		"2024031":                    "",
+		"":                           "",
+	}
+
+	for in, want := range cases {
+		got, err := parseOFXDate(in)
+		if want == "" {
+			if !errors.Is(err, errInvalidDate) {
+				t.Errorf("parseOFXDate(%q): err = %v, want %v", in, err, errInvalidDate)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(date(want)) {
+			t.Errorf("parseOFXDate(%q) = %v, %v, want %s", in, got, err, want)
+		}
+	}
+}
+
+func TestDecodeOFX(t *testing.T) {
+	if got := decodeOFX([]byte("<NAME>Caf\xe9")); !strings.HasSuffix(got, "Café") {
+		t.Errorf("decodeOFX(windows-1252) = %q", got)
+	}
+	if got := decodeOFX([]byte("<NAME>Café")); !strings.HasSuffix(got, "Café") {
+		t.Errorf("decodeOFX(utf-8) = %q", got)
+	}
+}
@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * QIF文件的日期没有统一的格式, 由导入选项指定日期的顺序
+ */
+const (
+	DateOrderMDY = "mdy"
+	DateOrderDMY = "dmy"
+	DateOrderYMD = "ymd"
+)
+
+var (
+	errNoQIFTransactions = errors.New("no transactions found in qif file")
+)
+
+/**
+ * 日期的分隔符可能是/, -或., 年份前的撇号表示2000年以后的两位年份, 如1/ 5'24
+ */
+func parseQIFDate(value string, order string) (time.Time, error) {
+	apostrophe := strings.Contains(value, "'")
+	value = strings.NewReplacer("'", "/", " ", "", "-", "/", ".", "/").Replace(value)
+
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 {
+		return time.Time{}, errInvalidDate
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, errInvalidDate
+		}
+		numbers[i] = n
+	}
+
+	var year, month, day int
+	switch order {
+	case DateOrderDMY:
+		day, month, year = numbers[0], numbers[1], numbers[2]
+	case DateOrderYMD:
+		year, month, day = numbers[0], numbers[1], numbers[2]
+	default:
+		month, day, year = numbers[0], numbers[1], numbers[2]
+	}
+
+	if year < 100 {
+		if apostrophe || year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day || int(t.Month()) != month {
+		return t, errInvalidDate
+	}
+	return t, nil
+}
+
+/**
+ * 解析QIF文件中的银行, 信用卡和现金交易
+ * 1. 每笔交易以^结束, 拆分交易只使用总金额, 金额以收入为正, 导入时取反
+ * 2. 以收款方(P)作为记录名称, 没有收款方时使用备注(M)
+ * 3. 分类(L)先按导入选项映射, 再按记录类型名称匹配, 多级分类没有映射时只取第一级
+ * 4. 方括号包围的分类表示账户间转账, 跳过
+ * 5. QIF没有交易号, 依靠日期, 金额和名称检测重复
+ */
+func ParseQIF(data []byte, opts StatementOptions) ([]Row, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []Row{}
+	values := map[byte]string{}
+	start := 0
+	skip := false
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		content := strings.TrimRight(scanner.Text(), "\r")
+		if content == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(content, "!"):
+			// 只导入交易, 跳过账户, 分类等列表
+			kind := strings.ToLower(content)
+			skip = !strings.HasPrefix(kind, "!type:") || strings.Contains(kind, ":cat") || strings.Contains(kind, ":class") ||
+				strings.Contains(kind, ":memorized") || strings.Contains(kind, ":invst")
+			continue
+		case content[0] == '^':
+			if !skip && len(values) > 0 {
+				if opts.MaxRows > 0 && len(res) == opts.MaxRows {
+					return nil, errTooManyRows
+				}
+				if row, ok := qifRow(values, start, opts); ok {
+					res = append(res, row)
+				}
+			}
+			values = map[byte]string{}
+			continue
+		}
+
+		if len(values) == 0 {
+			start = line
+		}
+		// 拆分交易的S, E, $行可以重复出现, 只保留第一次出现的字段
+		if _, ok := values[content[0]]; !ok {
+			values[content[0]] = strings.TrimSpace(content[1:])
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, errNoQIFTransactions
+	}
+
+	return res, nil
+}
+
+func qifRow(values map[byte]string, line int, opts StatementOptions) (Row, bool) {
+	category := values['L']
+	if strings.HasPrefix(category, "[") {
+		return Row{}, false
+	}
+	if _, ok := opts.Categories[category]; !ok {
+		category, _, _ = strings.Cut(category, ":")
+	}
+
+	row := Row{
+		Line:       line,
+		Name:       TruncateName(values['P']),
+		RecordType: opts.recordType(category),
+	}
+	if row.Name == "" {
+		row.Name = TruncateName(values['M'])
+	}
+
+	row.Date, row.Err = parseQIFDate(values['D'], opts.DateOrder)
+	if row.Err == nil {
+		amount := values['T']
+		if amount == "" {
+			amount = values['U']
+		}
+		row.Amount, row.Err = NormalizeAmount(amount, opts.DecimalComma)
+	}
+	if row.Err == nil {
+		row.Amount = negateAmount(row.Amount)
+	}
+
+	return row, true
+}
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+func TestParseQIF(t *testing.T) {
+	cases := []struct {
+		file string
+		opts StatementOptions
+		want []Row
+	}{
+		{
+			// 分类列表被跳过, 转账被跳过, 拆分交易只使用总金额
+			file: "bank_mdy.qif",
+			opts: StatementOptions{DateOrder: DateOrderMDY},
+			want: []Row{
+				{Line: 7, Name: "Coffee Shop", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "12.5"},
+				{Line: 12, Name: "Payroll", RecordType: util.RecordTypeGift, Date: date("2024-03-15"), Amount: "-1500"},
+				{Line: 22, Name: "Grocery", RecordType: util.RecordTypeShopping, Date: date("2024-03-21"), Amount: "60"},
+				{Line: 31, Name: "Old memo", RecordType: util.RecordTypeShopping, Date: date("1999-12-31"), Amount: "1"},
+				{Line: 35, Name: "New century", RecordType: util.RecordTypeShopping, Date: date("2000-01-05"), Amount: "2"},
+				{Line: 39, Err: errInvalidDate},
+			},
+		},
+		{
+			file: "bank_dmy.qif",
+			opts: StatementOptions{DateOrder: DateOrderDMY, DecimalComma: true},
+			want: []Row{
+				{Line: 2, Name: "Miete", RecordType: util.RecordTypeAmuse, Date: date("2024-03-05"), Amount: "1234.56"},
+				{Line: 7, Name: "Gehalt", RecordType: util.RecordTypeShopping, Date: date("2024-03-31"), Amount: "-2000"},
+			},
+		},
+		{
+			file: "bank_ymd.qif",
+			opts: StatementOptions{DateOrder: DateOrderYMD},
+			want: []Row{
+				{Line: 2, Name: "午餐", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "25.5"},
+				{Line: 7, Name: "地铁", RecordType: util.RecordTypeCommuting, Date: date("2024-03-06"), Amount: "4"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			rows, err := ParseQIF(readTestdata(t, c.file), c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRows(t, rows, c.want)
+		})
+	}
+}
+
+func TestParseQIFCategoryMapping(t *testing.T) {
+	rows, err := ParseQIF(readTestdata(t, "bank_mdy.qif"), StatementOptions{
+		Categories: map[string]util.RecordType{"Gift:Salary": util.RecordTypeOffice},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[1].RecordType != util.RecordTypeOffice {
+		t.Errorf("record type = %d, want %d", rows[1].RecordType, util.RecordTypeOffice)
+	}
+}
+
+func TestParseQIFErrors(t *testing.T) {
+	_, err := ParseQIF([]byte("!Type:Cat\nNFood\n^\n"), StatementOptions{})
+	if !errors.Is(err, errNoQIFTransactions) {
+		t.Errorf("err = %v, want %v", err, errNoQIFTransactions)
+	}
+
+	_, err = ParseQIF(readTestdata(t, "bank_mdy.qif"), StatementOptions{MaxRows: 2})
+	if !errors.Is(err, errTooManyRows) {
+		t.Errorf("err = %v, want %v", err, errTooManyRows)
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	cases := []struct {
+		value string
+		order string
+		want  string
+	}{
+		{"3/ 5'24", DateOrderMDY, "2024-03-05"},
+		{"3/5/2024", DateOrderMDY, "2024-03-05"},
+		{"03-05-24", "", "2024-03-05"},
+		{"12/31/99", DateOrderMDY, "1999-12-31"},
+		{"12/31/69", DateOrderMDY, "2069-12-31"},
+		{"12/31/70", DateOrderMDY, "1970-12-31"},
+		{"1/ 5'00", DateOrderMDY, "2000-01-05"},
+		{"05/03/2024", DateOrderDMY, "2024-03-05"},
+		{"5.3'24", DateOrderDMY, "2024-03-05"},
+		{"2024-03-05", DateOrderYMD, "2024-03-05"},
+		{"24.3.5", DateOrderYMD, "2024-03-05"},
+		{"3/15/24", DateOrderDMY, ""},
+		{"2/30'24", DateOrderMDY, ""},
+		{"2024-03-05", DateOrderMDY, ""},
+		{"3/5", DateOrderMDY, ""},
+		{"", DateOrderMDY, ""},
+	}
+
+	for _, c := range cases {
+		got, err := parseQIFDate(c.value, c.order)
+		if c.want == "" {
+			if !errors.Is(err, errInvalidDate) {
+				t.Errorf("parseQIFDate(%q, %q) = %v, %v, want %v", c.value, c.order, got, err, errInvalidDate)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(date(c.want)) {
+			t.Errorf("parseQIFDate(%q, %q) = %v, %v, want %s", c.value, c.order, got, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+var (
+	errStatementHeaderNotFound = errors.New("statement header not found, check the file format")
+)
+
+/**
+ * 支付宝的交易分类到记录类型的默认映射
+ * 微信支付只有交易类型, 除转账和红包外都使用默认类型
+ */
+var walletCategories = map[string]util.RecordType{
+	"餐饮美食": util.RecordTypeFood,
+	"服饰装扮": util.RecordTypeShopping,
+	"日用百货": util.RecordTypeShopping,
+	"数码电器": util.RecordTypeShopping,
+	"美容美发": util.RecordTypeShopping,
+	"母婴亲子": util.RecordTypeShopping,
+	"家居家装": util.RecordTypeShopping,
+	"宠物":   util.RecordTypeShopping,
+	"交通出行": util.RecordTypeCommuting,
+	"爱车养车": util.RecordTypeCommuting,
+	"文化休闲": util.RecordTypeAmuse,
+	"运动户外": util.RecordTypeAmuse,
+	"酒店旅游": util.RecordTypeAmuse,
+	"教育培训": util.RecordTypeStudying,
+	"商业服务": util.RecordTypeOffice,
+	"通讯物流": util.RecordTypeOffice,
+	"充值缴费": util.RecordTypeOffice,
+	"转账红包": util.RecordTypeGift,
+	"亲友代付": util.RecordTypeGift,
+	"转账":   util.RecordTypeGift,
+	"微信红包": util.RecordTypeGift,
+	"群收款":  util.RecordTypeGift,
+}
+
+/**
+ * 导出文件中的一行, 按表头中的列名取值
+ */
+type statementRow struct {
+	line    int
+	columns map[string]int
+	values  []string
+}
+
+/**
+ * 依次尝试多个列名, 兼容不同版本的导出格式
+ */
+func (row statementRow) get(names ...string) string {
+	for _, name := range names {
+		i, ok := row.columns[name]
+		if ok && i < len(row.values) {
+			return strings.TrimSpace(strings.Trim(row.values[i], "\t"))
+		}
+	}
+	return ""
+}
+
+/**
+ * 读取CSV或XLSX格式的账单, 跳过表头之前的说明文字
+ * 第一个同时包含所有required列的行视为表头, 表头之后的空行和说明文字被忽略
+ */
+func readStatement(data []byte, required ...string) ([]statementRow, error) {
+	var table [][]string
+	var err error
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		table, err = readXLSX(data)
+	} else {
+		table, err = readCSVTable(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, values := range table {
+		columns := map[string]int{}
+		for j, name := range values {
+			columns[strings.TrimSpace(name)] = j
+		}
+
+		found := true
+		for _, name := range required {
+			if _, ok := columns[name]; !ok {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		rows := []statementRow{}
+		for j, values := range table[i+1:] {
+			// 数据行的列数至少与必需的列一样多, 过滤掉结尾的说明文字
+			if isBlank(values) || len(values) < len(required) {
+				continue
+			}
+			rows = append(rows, statementRow{line: i + j + 2, columns: columns, values: values})
+		}
+		return rows, nil
+	}
+
+	return nil, errStatementHeaderNotFound
+}
+
+func readCSVTable(data []byte) ([][]string, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	return reader.ReadAll()
+}
+
+/**
+ * 账单中的时间可能是文本, 也可能是XLSX的日期序列号
+ */
+func parseStatementDate(value string) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		t := time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial))
+		return t, nil
+	}
+
+	value, _, _ = strings.Cut(value, " ")
+	for _, layout := range []string{"2006-01-02", "2006/1/2", "2006-1-2"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errInvalidDate
+}
+
+/**
+ * 解析金额并按收支方向确定符号, 支出为正, 收入为负
+ */
+func signedAmount(value string, expense bool) (string, error) {
+	amount, err := NormalizeAmount(value, false)
+	if err != nil {
+		return "", err
+	}
+
+	rat, _ := new(big.Rat).SetString(amount)
+	rat.Abs(rat)
+	if !expense {
+		rat.Neg(rat)
+	}
+	return FormatAmount(rat), nil
+}
+
+func statementName(counterparty string, product string) string {
+	if counterparty == "" || counterparty == "/" {
+		return TruncateName(product)
+	}
+	return TruncateName(counterparty)
+}
+
+/**
+ * 解析支付宝账单, 兼容手机端导出的新格式和网页端导出的旧格式
+ * 1. 交易关闭或失败的交易没有资金变动, 跳过
+ * 2. 退款记为收入, 分类与原交易一致, 从而抵消原来的支出
+ * 3. 不计收支的交易(余额宝转入转出, 信用卡还款, 充值提现等)是内部转账, 跳过
+ * 4. 全额退款后原交易显示为交易关闭, 退款的交易号以原交易号开头, 两者都跳过
+ * 5. 旧格式中部分退款的交易, 金额扣除已退款的部分
+ */
+func ParseAlipay(data []byte, opts StatementOptions) ([]Row, error) {
+	rows, err := readStatement(data, "交易对方", "收/支", "交易状态")
+	if err != nil {
+		return nil, err
+	}
+
+	closed := []string{}
+	for _, row := range rows {
+		id := row.get("交易订单号", "交易号")
+		if strings.Contains(row.get("交易状态"), "关闭") && id != "" {
+			closed = append(closed, id)
+		}
+	}
+
+	res := []Row{}
+	for _, row := range rows {
+		status := row.get("交易状态")
+		direction := row.get("收/支")
+		product := row.get("商品说明", "商品名称")
+		id := row.get("交易订单号", "交易号")
+		refund := strings.Contains(status, "退款") && direction != "支出" || strings.HasPrefix(product, "退款")
+
+		if strings.Contains(status, "关闭") || strings.Contains(status, "失败") {
+			continue
+		}
+		if direction != "支出" && direction != "收入" && !refund {
+			continue
+		}
+		if refund && hasClosedPrefix(id, closed) {
+			continue
+		}
+
+		if opts.MaxRows > 0 && len(res) == opts.MaxRows {
+			return nil, errTooManyRows
+		}
+
+		result := Row{
+			Line:       row.line,
+			Name:       statementName(row.get("交易对方"), product),
+			RecordType: opts.recordType(row.get("交易分类", "类型")),
+		}
+
+		if id != "" {
+			result.ExternalId = "alipay:" + id
+		}
+
+		result.Date, result.Err = parseStatementDate(row.get("交易时间", "交易创建时间", "付款时间"))
+		if result.Err == nil {
+			result.Amount, result.Err = signedAmount(row.get("金额", "金额（元）"), direction == "支出" && !refund)
+		}
+		if result.Err == nil && direction == "支出" {
+			result.Amount, result.Err = deductRefund(result.Amount, row.get("成功退款（元）"))
+		}
+		if result.Err == nil && result.Amount == "0" {
+			continue
+		}
+
+		res = append(res, result)
+	}
+
+	return res, nil
+}
+
+func hasClosedPrefix(id string, closed []string) bool {
+	for _, prefix := range closed {
+		if id != prefix && strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * expense为正数, 扣除已退款的金额后仍然是正数或0
+ */
+func deductRefund(expense string, refunded string) (string, error) {
+	if refunded == "" {
+		return expense, nil
+	}
+
+	refundedAmount, err := NormalizeAmount(refunded, false)
+	if err != nil {
+		return "", err
+	}
+
+	amount, _ := new(big.Rat).SetString(expense)
+	refundedRat, _ := new(big.Rat).SetString(refundedAmount)
+	amount.Sub(amount, refundedRat.Abs(refundedRat))
+	if amount.Sign() < 0 {
+		amount.SetInt64(0)
+	}
+
+	return FormatAmount(amount), nil
+}
+
+/**
+ * 解析微信支付账单
+ * 1. 收/支为"/"的交易(零钱充值提现, 转入零钱通, 信用卡还款等)是内部转账, 跳过
+ * 2. 退款单独成行并记为收入, 原交易保留为支出
+ * 3. 已退还的转账和红包没有资金变动, 跳过
+ */
+func ParseWeChat(data []byte, opts StatementOptions) ([]Row, error) {
+	rows, err := readStatement(data, "交易类型", "交易对方", "收/支")
+	if err != nil {
+		return nil, err
+	}
+
+	res := []Row{}
+	for _, row := range rows {
+		status := row.get("当前状态")
+		direction := row.get("收/支")
+		kind := row.get("交易类型")
+
+		if direction != "支出" && direction != "收入" {
+			continue
+		}
+		if strings.Contains(status, "已退还") || strings.Contains(status, "失败") {
+			continue
+		}
+
+		if opts.MaxRows > 0 && len(res) == opts.MaxRows {
+			return nil, errTooManyRows
+		}
+
+		category, _, _ := strings.Cut(kind, "-")
+		result := Row{
+			Line:       row.line,
+			Name:       statementName(row.get("交易对方"), row.get("商品")),
+			RecordType: opts.recordType(category),
+		}
+
+		id := row.get("交易单号")
+		if id != "" {
+			result.ExternalId = "wechat:" + id
+		}
+
+		result.Date, result.Err = parseStatementDate(row.get("交易时间"))
+		if result.Err == nil {
+			result.Amount, result.Err = signedAmount(row.get("金额(元)", "金额（元）", "金额"), direction == "支出")
+		}
+		if result.Err != nil {
+			result.Err = fmt.Errorf("%w: %s", result.Err, kind)
+		}
+
+		res = append(res, result)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/timelyrain/star-account/util"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+/**
+ * 两种格式的支付宝账单都包含全额退款, 部分退款和余额宝转入
+ * 全额退款的原交易和退款都跳过, 部分退款在新格式中单独成行, 在旧格式中从原交易扣除
+ */
+func TestParseAlipay(t *testing.T) {
+	cases := []struct {
+		file string
+		want []Row
+	}{
+		{
+			file: "alipay_mobile.csv",
+			want: []Row{
+				{Line: 10, Name: "肯德基", RecordType: util.RecordTypeFood, Date: date("2024-03-05"), Amount: "35.5", ExternalId: "alipay:2024030522001100001"},
+				{Line: 13, Name: "超市", RecordType: util.RecordTypeShopping, Date: date("2024-03-08"), Amount: "120", ExternalId: "alipay:2024030822001100003"},
+				{Line: 14, Name: "超市", RecordType: util.RecordTypeShopping, Date: date("2024-03-09"), Amount: "-20", ExternalId: "alipay:2024030822001100003_R1"},
+				{Line: 16, Name: "李四", RecordType: util.RecordTypeGift, Date: date("2024-03-11"), Amount: "-200", ExternalId: "alipay:2024031120001100005"},
+				{Line: 18, Err: errInvalidDate},
+			},
+		},
+		{
+			file: "alipay_web.csv",
+			want: []Row{
+				{Line: 6, Name: "肯德基", RecordType: util.RecordTypeShopping, Date: date("2024-03-05"), Amount: "35.5", ExternalId: "alipay:2024030522001100001"},
+				{Line: 8, Name: "超市", RecordType: util.RecordTypeShopping, Date: date("2024-03-08"), Amount: "100", ExternalId: "alipay:2024030822001100003"},
+				{Line: 11, Name: "李四", RecordType: util.RecordTypeShopping, Date: date("2024-03-11"), Amount: "-200", ExternalId: "alipay:2024031120001100005"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			rows, err := ParseAlipay(readTestdata(t, c.file), StatementOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRows(t, rows, c.want)
+		})
+	}
+}
+
+/**
+ * 微信支付的退款单独成行, 原交易保留为支出; 已退还的转账和零钱提现充值跳过
+ */
+func TestParseWeChat(t *testing.T) {
+	cases := []struct {
+		file string
+		want []Row
+	}{
+		{
+			file: "wechat.csv",
+			want: []Row{
+				{Line: 10, Name: "肯德基", RecordType: util.RecordTypeShopping, Date: date("2024-03-05"), Amount: "35.5", ExternalId: "wechat:4200000001202403050001"},
+				{Line: 11, Name: "李四", RecordType: util.RecordTypeGift, Date: date("2024-03-06"), Amount: "-88", ExternalId: "wechat:1000050001202403060001"},
+				{Line: 14, Name: "超市", RecordType: util.RecordTypeShopping, Date: date("2024-03-09"), Amount: "120", ExternalId: "wechat:4200000001202403090001"},
+				{Line: 15, Name: "超市", RecordType: util.RecordTypeShopping, Date: date("2024-03-10"), Amount: "-120", ExternalId: "wechat:50300001202403100001"},
+				{Line: 16, Name: "电影票", RecordType: util.RecordTypeShopping, Date: date("2024-03-11"), Amount: "60", ExternalId: "wechat:4200000001202403110001"},
+				{Line: 17, Name: "电影票", RecordType: util.RecordTypeShopping, Date: date("2024-03-12"), Amount: "-20", ExternalId: "wechat:50300001202403120001"},
+			},
+		},
+		{
+			file: "wechat.xlsx",
+			want: []Row{
+				{Line: 7, Name: "肯德基", RecordType: util.RecordTypeShopping, Date: date("2024-03-05"), Amount: "35.5", ExternalId: "wechat:4200000001202403050001"},
+				{Line: 8, Name: "超市", RecordType: util.RecordTypeShopping, Date: date("2024-03-09"), Amount: "120", ExternalId: "wechat:4200000001202403090001"},
+				{Line: 9, Name: "超市", RecordType: util.RecordTypeShopping, Date: date("2024-03-10"), Amount: "-20", ExternalId: "wechat:50300001202403100001"},
+				{Line: 12, Name: "早餐店", RecordType: util.RecordTypeShopping, Date: date("2024-03-13"), Amount: "8", ExternalId: "wechat:1000050001202403130001"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			rows, err := ParseWeChat(readTestdata(t, c.file), StatementOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRows(t, rows, c.want)
+		})
+	}
+}
+
+func TestParseStatementOptions(t *testing.T) {
+	data := readTestdata(t, "wechat.csv")
+
+	rows, err := ParseWeChat(data, StatementOptions{
+		Categories:        map[string]util.RecordType{"微信红包": util.RecordTypeAmuse},
+		DefaultRecordType: util.RecordTypeFood,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].RecordType != util.RecordTypeFood || rows[1].RecordType != util.RecordTypeAmuse {
+		t.Errorf("record types = %d, %d", rows[0].RecordType, rows[1].RecordType)
+	}
+
+	_, err = ParseWeChat(data, StatementOptions{MaxRows: 5})
+	if !errors.Is(err, errTooManyRows) {
+		t.Errorf("err = %v, want %v", err, errTooManyRows)
+	}
+}
+
+func TestParseStatementWrongFormat(t *testing.T) {
+	_, err := ParseWeChat(readTestdata(t, "alipay_mobile.csv"), StatementOptions{})
+	if !errors.Is(err, errStatementHeaderNotFound) {
+		t.Errorf("wechat: err = %v, want %v", err, errStatementHeaderNotFound)
+	}
+
+	_, err = ParseAlipay(readTestdata(t, "wechat.csv"), StatementOptions{})
+	if !errors.Is(err, errStatementHeaderNotFound) {
+		t.Errorf("alipay: err = %v, want %v", err, errStatementHeaderNotFound)
+	}
+}
+
+func TestHasClosedPrefix(t *testing.T) {
+	closed := []string{"2024030622001100002", "2024030722001100009"}
+	cases := map[string]bool{
+		"2024030622001100002_R1": true,
+		"2024030722001100009001": true,
+		"2024030622001100002":    false,
+		"2024030822001100003_R1": false,
+		"":                       false,
+	}
+
+	for id, want := range cases {
+		if got := hasClosedPrefix(id, closed); got != want {
+			t.Errorf("hasClosedPrefix(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestDeductRefund(t *testing.T) {
+	cases := []struct {
+		expense  string
+		refunded string
+		want     string
+		err      error
+	}{
+		{"120", "", "120", nil},
+		{"120", "0.00", "120", nil},
+		{"120", "20.00", "100", nil},
+		{"120", "-20", "100", nil},
+		{"99.9", "99.90", "0", nil},
+		{"50", "60", "0", nil},
+		{"50", "abc", "", errInvalidAmount},
+	}
+
+	for _, c := range cases {
+		got, err := deductRefund(c.expense, c.refunded)
+		if !errors.Is(err, c.err) || got != c.want {
+			t.Errorf("deductRefund(%q, %q) = %q, %v, want %q, %v", c.expense, c.refunded, got, err, c.want, c.err)
+		}
+	}
+}
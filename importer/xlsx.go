@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/**
+ * xlsx最多有16384列(XFD)
+ */
+const maxXlsxColumns = 16384
+
+var (
+	errNoWorksheet       = errors.New("xlsx file has no worksheet")
+	errInvalidCellColumn = errors.New("invalid cell column")
+)
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				Text string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+/**
+ * 读取xlsx文件第一个工作表中的所有单元格, 只支持文本和数字
+ * 日期单元格返回Excel的序列号, 由调用方转换
+ */
+func readXLSX(data []byte) ([][]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*zip.File{}
+	sheets := []string{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+		if strings.HasPrefix(file.Name, "xl/worksheets/") && strings.HasSuffix(file.Name, ".xml") {
+			sheets = append(sheets, file.Name)
+		}
+	}
+	if len(sheets) == 0 {
+		return nil, errNoWorksheet
+	}
+	sort.Strings(sheets)
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		err = decodeZipXML(file, &shared)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sharedText := make([]string, len(shared.Items))
+	for i, item := range shared.Items {
+		sharedText[i] = item.Text
+		for _, run := range item.Runs {
+			sharedText[i] += run.Text
+		}
+	}
+
+	var sheet xlsxWorksheet
+	err = decodeZipXML(files[sheets[0]], &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := [][]string{}
+	for _, row := range sheet.Rows {
+		values := []string{}
+		for i, cell := range row.Cells {
+			column := i
+			if cell.Ref != "" {
+				column, err = columnIndex(cell.Ref)
+				if err != nil {
+					return nil, err
+				}
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				n, err := strconv.Atoi(cell.Value)
+				if err == nil && n >= 0 && n < len(sharedText) {
+					values[column] = sharedText[n]
+				}
+			case "inlineStr":
+				values[column] = cell.Inline.Text
+			default:
+				values[column] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+func decodeZipXML(file *zip.File, v any) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(v)
+}
+
+/**
+ * 把单元格引用(如AB12)中的列名转换为从0开始的列号
+ * 没有列名或超过XFD时返回errInvalidCellColumn
+ */
+func columnIndex(ref string) (int, error) {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+		if n > maxXlsxColumns {
+			return 0, errInvalidCellColumn
+		}
+	}
+	if n == 0 {
+		return 0, errInvalidCellColumn
+	}
+	return n - 1, nil
+}
@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/**
+ * 把邮件写入本地目录的.eml文件, 用于开发环境和没有邮件服务的部署
+ */
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir string, from string) (*FileSender, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+func (sender *FileSender) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := msg.Bytes(sender.from, now)
+	if err != nil {
+		return err
+	}
+
+	name := now.Format("20060102-150405.000000") + "-" + randomId()[:8] + ".eml"
+	return os.WriteFile(filepath.Join(sender.dir, name), data, 0o640)
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+/**
+ * 通过SMTP服务发送邮件, username为空时不认证
+ * 服务器支持STARTTLS时自动启用
+ */
+type SMTPSender struct {
+	address string
+	from    string
+	auth    smtp.Auth
+}
+
+func NewSMTPSender(address string, from string, username string, password string) (*SMTPSender, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	sender := &SMTPSender{address: address, from: from}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return sender, nil
+}
+
+/**
+ * net/smtp不支持ctx, 取消ctx不会中断正在进行的发送
+ */
+func (sender *SMTPSender) Send(ctx context.Context, msg Message) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	data, err = msg.Bytes(sender.from, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(sender.address, sender.auth, sender.from, []string{msg.To}, data)
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"io/fs"
 	"log"
 	"time"
+	_ "time/tzdata"
 
 	_ "github.com/lib/pq"
 
 	"github.com/timelyrain/star-account/api"
+	"github.com/timelyrain/star-account/blob"
 	"github.com/timelyrain/star-account/db"
+	"github.com/timelyrain/star-account/exporter"
+	"github.com/timelyrain/star-account/mail"
 )
 
 const (
@@ -17,6 +24,23 @@ const (
 	serverAddress     = "0.0.0.0:20714"
 	tokenSymmetricKey = "11451419198101145141919810aaaaaa"
 	tokenDuration     = time.Hour
+	idempotencyKeyTTL = 24 * time.Hour
+	// 未配置s3Endpoint时附件保存在本地目录
+	attachmentDir    = "./data/attachments"
+	s3Endpoint       = ""
+	s3Region         = "us-east-1"
+	s3Bucket         = "star-account"
+	s3AccessKeyId    = ""
+	s3SecretKey      = ""
+	s3RequestTimeout = time.Minute
+	// 未配置smtpAddress时邮件写入本地目录
+	mailDir      = "./data/mail"
+	mailFrom     = "STAR-ACCOUNT <noreply@star-account.local>"
+	smtpAddress  = ""
+	smtpUsername = ""
+	smtpPassword = ""
+	// 对账单PDF中嵌入的TrueType中文字体, 文件不存在时使用阅读器提供的STSong-Light
+	statementFontFile = "./data/fonts/statement.ttf"
 )
 
 func main() {
@@ -27,11 +51,66 @@ func main() {
 
 	db := db.NewDB(conn)
 
+	var blobStore blob.BlobStore
+	blobStore, err = newBlobStore()
+	if err != nil {
+		log.Fatal("cannot create blob store: ", err)
+	}
+
+	var mailSender mail.Sender
+	mailSender, err = newMailSender()
+	if err != nil {
+		log.Fatal("cannot create mail sender: ", err)
+	}
+
+	var statementFont *exporter.Font
+	statementFont, err = newStatementFont()
+	if err != nil {
+		log.Fatal("cannot load statement font: ", err)
+	}
+
 	var server *api.Server
-	server, err = api.NewServer(db, tokenSymmetricKey, tokenDuration)
+	server, err = api.NewServer(db, blobStore, mailSender, statementFont, tokenSymmetricKey, tokenDuration, idempotencyKeyTTL)
+	if err != nil {
+		log.Fatal("cannot create server: ", err)
+	}
+
+	server.StartWorkers(context.Background(), dbSource)
 
 	err = server.Start(serverAddress)
 	if err != nil {
 		log.Fatal("cannot start server: ", err)
 	}
 }
+
+func newBlobStore() (blob.BlobStore, error) {
+	if s3Endpoint == "" {
+		return blob.NewLocalStore(attachmentDir)
+	}
+
+	return blob.NewS3Store(blob.S3Config{
+		Endpoint:        s3Endpoint,
+		Region:          s3Region,
+		Bucket:          s3Bucket,
+		AccessKeyId:     s3AccessKeyId,
+		SecretAccessKey: s3SecretKey,
+	}, s3RequestTimeout)
+}
+
+func newMailSender() (mail.Sender, error) {
+	if smtpAddress == "" {
+		return mail.NewFileSender(mailDir, mailFrom)
+	}
+
+	return mail.NewSMTPSender(smtpAddress, mailFrom, smtpUsername, smtpPassword)
+}
+
+func newStatementFont() (*exporter.Font, error) {
+	font, err := exporter.LoadFont(statementFontFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Println("statement font not found, fall back to STSong-Light: ", statementFontFile)
+		return nil, nil
+	}
+
+	return font, err
+}
@@ -12,6 +12,7 @@ const (
 
 /**
  * 账单记录的类型
+ * 记录的金额为正表示支出, 为负表示退款等收入
  */
 type RecordType = int32
 
@@ -24,3 +25,134 @@ const (
 	RecordTypeOffice
 	RecordTypeGift
 )
+
+/**
+ * 预算的周期
+ */
+type BudgetPeriod = int32
+
+const (
+	BudgetPeriodWeekly = iota + 1
+	BudgetPeriodMonthly
+	BudgetPeriodYearly
+)
+
+/**
+ * Webhook投递的状态
+ */
+type WebhookDeliveryStatus = int32
+
+const (
+	WebhookDeliveryStatusPending = iota + 1
+	WebhookDeliveryStatusDelivered
+	WebhookDeliveryStatusFailed
+)
+
+/**
+ * 通知邮件的发送状态, 不发送邮件的通知为NotificationEmailStatusNone
+ */
+type NotificationEmailStatus = int32
+
+const (
+	NotificationEmailStatusNone = iota
+	NotificationEmailStatusPending
+	NotificationEmailStatusSent
+	NotificationEmailStatusFailed
+)
+
+/**
+ * 摘要邮件的发送频率
+ */
+type DigestFrequency = int32
+
+const (
+	DigestFrequencyWeekly = iota + 1
+	DigestFrequencyMonthly
+)
+
+/**
+ * 记录的审批状态, 只有已通过的记录计入金额统计
+ */
+type ApprovalStatus = int32
+
+const (
+	ApprovalStatusPending = iota + 1
+	ApprovalStatusApproved
+	ApprovalStatusRejected
+)
+
+/**
+ * 关账审计记录的操作
+ */
+type PeriodClosingAction = int32
+
+const (
+	PeriodClosingActionClose = iota + 1
+	PeriodClosingActionReopen
+)
+
+/**
+ * 记录的核对状态, 已对账的记录不能再修改核对状态, 也不能修改金额和日期
+ */
+type ClearStatus = int32
+
+const (
+	ClearStatusUncleared = iota
+	ClearStatusCleared
+	ClearStatusReconciled
+)
+
+/**
+ * 记录类型在导入导出文件中使用的名称
+ */
+var recordTypeNames = map[RecordType]string{
+	RecordTypeFood:      "food",
+	RecordTypeShopping:  "shopping",
+	RecordTypeCommuting: "commuting",
+	RecordTypeAmuse:     "amuse",
+	RecordTypeStudying:  "studying",
+	RecordTypeOffice:    "office",
+	RecordTypeGift:      "gift",
+}
+
+func RecordTypeName(recordType RecordType) string {
+	return recordTypeNames[recordType]
+}
+
+/**
+ * 按名称查找记录类型, 不存在时返回0
+ */
+func ParseRecordType(name string) RecordType {
+	for recordType, n := range recordTypeNames {
+		if n == name {
+			return recordType
+		}
+	}
+	return 0
+}
+
+/**
+ * 审批状态在导出文件中使用的名称
+ */
+var approvalStatusNames = map[ApprovalStatus]string{
+	ApprovalStatusPending:  "pending",
+	ApprovalStatusApproved: "approved",
+	ApprovalStatusRejected: "rejected",
+}
+
+func ApprovalStatusName(status ApprovalStatus) string {
+	return approvalStatusNames[status]
+}
+
+/**
+ * 核对状态在导出文件中使用的名称
+ */
+var clearStatusNames = map[ClearStatus]string{
+	ClearStatusUncleared:  "uncleared",
+	ClearStatusCleared:    "cleared",
+	ClearStatusReconciled: "reconciled",
+}
+
+func ClearStatusName(status ClearStatus) string {
+	return clearStatusNames[status]
+}
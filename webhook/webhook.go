@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+/**
+ * 投递请求携带的请求头
+ * 签名为 "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+ */
+const (
+	HeaderEvent     = "X-Star-Account-Event"
+	HeaderDelivery  = "X-Star-Account-Delivery"
+	HeaderTimestamp = "X-Star-Account-Timestamp"
+	HeaderSignature = "X-Star-Account-Signature"
+)
+
+/**
+ * 投递失败后的重试策略: 第n次失败后等待 baseDelay * 2^(n-1), 不超过maxDelay
+ * 失败MaxAttempts次后不再重试
+ */
+const (
+	MaxAttempts = 8
+	baseDelay   = 30 * time.Second
+	maxDelay    = 12 * time.Hour
+)
+
+const secretLength = 32
+
+/**
+ * 生成随机的签名密钥
+ */
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretLength)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+/**
+ * 接收方校验签名, 调用者还应检查timestamp是否在可接受的时间窗口内
+ */
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+/**
+ * attempts次失败后到下一次投递的等待时间
+ */
+func Backoff(attempts int32) time.Duration {
+	delay := baseDelay
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+
+	return delay
+}
+
+var ErrForbiddenAddress = errors.New("webhook address is not a public address")
+
+/**
+ * net/netip没有对应判断的保留地址段
+ * 0.0.0.0/8在部分系统上会连到本机, 100.64.0.0/10是运营商级NAT, 198.18.0.0/15是基准测试网络
+ * 64:ff9b::/96和64:ff9b:1::/48是NAT64的转换前缀, 其中嵌入的IPv4地址可能是内网地址
+ */
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+/**
+ * 只允许投递到公网地址, 拒绝回环, 内网, 链路本地(包括云服务的元数据地址)和组播地址
+ */
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ * 在建立连接前检查解析后的地址, 可以防止DNS重绑定绕过对url的检查
+ */
+func checkDialAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !IsPublicAddr(addr) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+type Sender struct {
+	client *http.Client
+}
+
+/**
+ * 投递只连接公网地址, 不使用环境变量中的代理, 不跟随重定向, 3xx响应视为失败
+ */
+func NewSender(timeout time.Duration) *Sender {
+	return newSender(timeout, checkDialAddress)
+}
+
+func newSender(timeout time.Duration, control func(network string, address string, c syscall.RawConn) error) *Sender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: control,
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &Sender{client: client}
+}
+
+/**
+ * 发送一次投递, 返回响应的状态码, 非2xx的响应视为失败
+ */
+func (sender *Sender) Send(
+	ctx context.Context,
+	url string,
+	secret string,
+	deliveryId int64,
+	event string,
+	payload []byte,
+) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "star-account-webhook")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(deliveryId, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, payload))
+
+	var resp *http.Response
+	resp, err = sender.client.Do(req)
+	if err != nil {
+		// 不返回解析出的地址, 投递记录中的错误信息对账单拥有者可见
+		if errors.Is(err, ErrForbiddenAddress) {
+			return 0, ErrForbiddenAddress
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// 读完响应体以复用连接, 但不保存响应内容
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
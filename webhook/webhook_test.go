@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testTimeout = 5 * time.Second
+
+func TestSendSignsPayload(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(`{"event":"record.created"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+
+		switch {
+		case r.Method != http.MethodPost:
+			t.Errorf("method = %s, want POST", r.Method)
+		case r.Header.Get(HeaderEvent) != "record.created":
+			t.Errorf("event header = %q", r.Header.Get(HeaderEvent))
+		case r.Header.Get(HeaderDelivery) != "42":
+			t.Errorf("delivery header = %q", r.Header.Get(HeaderDelivery))
+		case !Verify(secret, timestamp, body, r.Header.Get(HeaderSignature)):
+			t.Errorf("signature does not verify")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := newSender(testTimeout, nil).Send(context.Background(), server.URL, secret, 42, "record.created", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	status, err := newSender(testTimeout, nil).Send(context.Background(), server.URL, "secret", 1, "record.created", []byte("{}"))
+	if err == nil {
+		t.Fatal("expected an error for a 503 response")
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var redirected atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	})
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+		redirected.Store(true)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	status, err := newSender(testTimeout, nil).Send(context.Background(), server.URL+"/hook", "secret", 1, "record.created", []byte("{}"))
+	if err == nil {
+		t.Fatal("expected an error for a redirect response")
+	}
+	if status != http.StatusFound {
+		t.Errorf("status = %d, want %d", status, http.StatusFound)
+	}
+	if redirected.Load() {
+		t.Error("redirect was followed")
+	}
+}
+
+func TestSendRejectsLoopback(t *testing.T) {
+	var called atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer server.Close()
+
+	_, err := NewSender(testTimeout).Send(context.Background(), server.URL, "secret", 1, "record.created", []byte("{}"))
+	if err != ErrForbiddenAddress {
+		t.Fatalf("err = %v, want %v", err, ErrForbiddenAddress)
+	}
+	if called.Load() {
+		t.Error("loopback receiver was called")
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"0.1.2.3":          false,
+		"100.127.255.254":  false,
+		"198.18.0.1":       false,
+		"198.19.255.255":   false,
+		"198.20.0.1":       true,
+		"64:ff9b::a00:1":   false,
+		"64:ff9b:1::1":     false,
+		"fc00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:10.0.0.1":  false,
+	}
+
+	for s, want := range cases {
+		if got := IsPublicAddr(netip.MustParseAddr(s)); got != want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", s, got, want)
+		}
+	}
+}